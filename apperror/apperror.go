@@ -0,0 +1,157 @@
+// Package apperror defines the typed errors returned by handlers and middleware,
+// and the Fiber ErrorHandler that renders every error as an RFC 7807
+// application/problem+json response.
+package apperror
+
+import (
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Stable, machine-readable error codes. Clients may rely on these; the
+// human-readable detail text may change at any time.
+const (
+	CodeBadRequest     = "bad_request"
+	CodeInvalidID      = "invalid_id" // ID in the URL that is not a positive integer (a UUID for products)
+	CodeValidation     = "validation_failed"
+	CodeInvalidCursor  = "invalid_cursor"
+	CodeInvalidSort    = "invalid_sort"
+	CodeInvalidFilter  = "invalid_filter"
+	CodeRouteNotFound  = "route_not_found"
+	CodeMethodNotAllow = "method_not_allowed"
+
+	CodeAccountTokenInvalid = "account_token_invalid" // Email verification or password reset link
+	CodeAccountTokenExpired = "account_token_expired"
+	CodeOIDCStateInvalid    = "oidc_state_invalid" // Callback without the cookie set when the login started, or not matching it
+
+	CodeUnauthorized        = "unauthorized"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeTokenMissing        = "token_missing"
+	CodeTokenMalformed      = "token_malformed"
+	CodeTokenInvalid        = "token_invalid"
+	CodeTokenExpired        = "token_expired"
+	CodeSessionRevoked      = "session_revoked"
+	CodeRefreshTokenInvalid = "refresh_token_invalid"
+	CodeRefreshTokenExpired = "refresh_token_expired"
+	CodeRefreshTokenReused  = "refresh_token_reused"
+
+	CodeTwoFactorChallengeInvalid = "two_factor_challenge_invalid" // Challenge token returned by a login
+	CodeTwoFactorChallengeExpired = "two_factor_challenge_expired"
+	CodeTwoFactorCodeInvalid      = "two_factor_code_invalid" // Also 403 when confirming a change to the 2FA settings
+	CodeAPIKeyInvalid             = "api_key_invalid"
+	CodeAPIKeyRevoked             = "api_key_revoked"
+	CodeAPIKeyExpired             = "api_key_expired"
+	CodeOIDCLoginFailed           = "oidc_login_failed" // The identity provider refused the login, or its ID token is invalid
+
+	CodeForbidden               = "forbidden"
+	CodePermissionDenied        = "permission_denied"
+	CodeWrongPassword           = "current_password_incorrect"
+	CodeEmailNotVerified        = "email_not_verified"
+	CodeSessionRequired         = "session_required"            // Action not allowed with an API key
+	CodeIdentityEmailUnverified = "identity_email_not_verified" // The identity provider vouches for no email address
+	CodeIdentityNotLinked       = "identity_not_linked"         // No account to log in to, and provisioning is off
+
+	CodeUserNotFound     = "user_not_found"
+	CodeProductNotFound  = "product_not_found"
+	CodeOwnerNotFound    = "owner_not_found"
+	CodeAPIKeyNotFound   = "api_key_not_found"
+	CodeProviderNotFound = "identity_provider_not_found"
+
+	CodeUsernameTaken = "username_taken"
+	CodeEmailTaken    = "email_taken"
+
+	CodeTwoFactorEnabled     = "two_factor_already_enabled"
+	CodeTwoFactorNotEnabled  = "two_factor_not_enabled"
+	CodeTwoFactorNotEnrolled = "two_factor_not_enrolled" // Confirming before enrolling
+
+	CodeRateLimited   = "rate_limited"
+	CodeAccountLocked = "account_locked"
+
+	CodeInternal            = "internal_error"
+	CodeProviderUnavailable = "identity_provider_unavailable" // 502: discovery of the identity provider failed
+)
+
+// Error is an error with an HTTP status and a stable code, safe to show to clients.
+// Cause holds the underlying error (e.g. from GORM); it is logged but never sent to clients.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []validation.FieldError // Only set for validation errors
+	Cause  error
+
+	// RetryAfter, if set, is sent in the Retry-After header (in whole seconds, rounded up).
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Detail + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+// Unwrap exposes the cause to errors.Is and errors.As.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// New creates an error with the given HTTP status, code and client-facing detail.
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest is returned for malformed requests (400).
+func BadRequest(code, detail string) *Error {
+	return New(fiber.StatusBadRequest, code, detail)
+}
+
+// Unauthorized is returned when the caller is not (or no longer) authenticated (401).
+func Unauthorized(code, detail string) *Error {
+	return New(fiber.StatusUnauthorized, code, detail)
+}
+
+// Forbidden is returned when the caller is authenticated but not allowed to do this (403).
+func Forbidden(code, detail string) *Error {
+	return New(fiber.StatusForbidden, code, detail)
+}
+
+// NotFound is returned when the addressed resource does not exist (404).
+func NotFound(code, detail string) *Error {
+	return New(fiber.StatusNotFound, code, detail)
+}
+
+// Conflict is returned when the request clashes with existing data, e.g. a duplicate username (409).
+func Conflict(code, detail string) *Error {
+	return New(fiber.StatusConflict, code, detail)
+}
+
+// TooManyRequests is returned when the caller must wait before trying again (429).
+// retryAfter tells the client how long.
+func TooManyRequests(code, detail string, retryAfter time.Duration) *Error {
+	return &Error{Status: fiber.StatusTooManyRequests, Code: code, Detail: detail, RetryAfter: retryAfter}
+}
+
+// Validation is returned when one or more request fields are invalid (422).
+func Validation(fields []validation.FieldError) *Error {
+	return &Error{
+		Status: fiber.StatusUnprocessableEntity,
+		Code:   CodeValidation,
+		Detail: "One or more fields are invalid",
+		Fields: fields,
+	}
+}
+
+// Internal wraps an unexpected error (500). The detail shown to clients is generic;
+// the cause is only logged by the error handler.
+func Internal(cause error) *Error {
+	return &Error{
+		Status: fiber.StatusInternalServerError,
+		Code:   CodeInternal,
+		Detail: "An unexpected error occurred",
+		Cause:  cause,
+	}
+}
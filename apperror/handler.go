@@ -0,0 +1,107 @@
+package apperror
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the media type of every error response (RFC 7807).
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 response body. Code and RequestID are extension members.
+type Problem struct {
+	Type      string                  `json:"type"`     // URI reference identifying the problem type
+	Title     string                  `json:"title"`    // Short summary of the HTTP status
+	Status    int                     `json:"status"`   // HTTP status code
+	Detail    string                  `json:"detail"`   // Explanation specific to this occurrence
+	Instance  string                  `json:"instance"` // Path of the request that failed
+	Code      string                  `json:"code"`     // Stable machine-readable error code
+	RequestID string                  `json:"request_id,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"` // Field errors for validation failures
+}
+
+// Handler is the Fiber ErrorHandler installed on the app in main.go.
+// Handlers and middleware just return an error; this turns it into a problem+json response.
+func Handler(c *fiber.Ctx, err error) error {
+	appErr := From(err)
+
+	if appErr.Status >= fiber.StatusInternalServerError {
+		// Log the real cause; clients only get the generic detail.
+		// The request ID comes with the context.
+		slog.ErrorContext(c.UserContext(), "request failed", "method", c.Method(), "path", c.Path(), "error", err)
+	}
+
+	problem := Problem{
+		Type:      "/problems/" + appErr.Code,
+		Title:     http.StatusText(appErr.Status),
+		Status:    appErr.Status,
+		Detail:    appErr.Detail,
+		Instance:  c.Path(),
+		Code:      appErr.Code,
+		RequestID: RequestID(c),
+		Errors:    appErr.Fields,
+	}
+
+	if appErr.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(int64((appErr.RetryAfter+time.Second-1)/time.Second), 10))
+	}
+	if err := c.Status(appErr.Status).JSON(problem); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, ContentType)
+	return nil
+}
+
+// From converts any error into an *Error:
+// *Error is returned as-is, *validation.Errors becomes a 422, *fiber.Error
+// (e.g. Fiber's own 404/405/413) keeps its status, and anything else is a 500.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErr *validation.Errors
+	if errors.As(err, &validationErr) {
+		return Validation(validationErr.Fields)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		switch fiberErr.Code {
+		case fiber.StatusNotFound:
+			return NotFound(CodeRouteNotFound, fiberErr.Message)
+		case fiber.StatusMethodNotAllowed:
+			return New(fiberErr.Code, CodeMethodNotAllow, fiberErr.Message)
+		}
+		if fiberErr.Code >= fiber.StatusInternalServerError {
+			return Internal(err)
+		}
+		// Derive a code from the status text, e.g. 413 -> "request_entity_too_large".
+		return New(fiberErr.Code, strings.ToLower(strings.ReplaceAll(http.StatusText(fiberErr.Code), " ", "_")), fiberErr.Message)
+	}
+
+	return Internal(err)
+}
+
+// RouteNotMatched reports whether err is the 404 or 405 Fiber's router returns when no
+// route matches the request. In that case c.Route() is whatever middleware ran last, so
+// its path must not be used to describe the request (e.g. as a metric label).
+func RouteNotMatched(err error) bool {
+	var fiberErr *fiber.Error
+	return errors.As(err, &fiberErr) && (fiberErr.Code == fiber.StatusNotFound || fiberErr.Code == fiber.StatusMethodNotAllowed)
+}
+
+// RequestID returns the ID assigned to the request by middlewares.RequestID.
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestid").(string)
+	return id
+}
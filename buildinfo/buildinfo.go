@@ -0,0 +1,63 @@
+// Package buildinfo describes the running binary: its version, the git commit it
+// was built from, when it was built and with which Go toolchain.
+//
+// Version, Commit and BuildTime are injected at build time with -ldflags, e.g.:
+//
+//	go build -ldflags "\
+//	  -X github.com/anpsniper/test3-bayu-be/buildinfo.Version=v1.4.0 \
+//	  -X github.com/anpsniper/test3-bayu-be/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/anpsniper/test3-bayu-be/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When they are not injected, the commit and build time fall back to the VCS
+// information the Go toolchain records for builds made inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X ..." (see the package documentation).
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info is the build information returned by GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary.
+// Values that are unknown are reported as "unknown".
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/database"
+	"github.com/anpsniper/test3-bayu-be/migrations"
+	"github.com/anpsniper/test3-bayu-be/models"
+)
+
+// runCommand executes a one-off administrative command given on the command line.
+// Supported commands:
+//
+//	migrate up                 Apply every pending migration
+//	migrate down [steps]       Roll back the last migration (or the last <steps> migrations)
+//	migrate status             List migrations and whether they have been applied
+//	migrate create <name>      Add empty migration files for every database driver to ./migrations
+//	promote-admin <username>   Give an existing user the admin role
+func runCommand(cfg *config.Config, args []string) {
+	switch args[0] {
+	case "migrate":
+		migrate(cfg, args[1:])
+	case "promote-admin":
+		if len(args) != 2 {
+			fatal("Usage: promote-admin <username>")
+		}
+		connect(cfg)
+		promoteAdmin(args[1])
+	default:
+		fatal("Unknown command", "command", args[0])
+	}
+}
+
+// connect validates the database settings and connects to the database.
+// The commands don't need the rest of the configuration (e.g. the JWT keys).
+func connect(cfg *config.Config) {
+	if err := cfg.Database.Validate(); err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	if err := database.ConnectDB(cfg.Database); err != nil {
+		fatal("Failed to connect to the database", "error", err)
+	}
+}
+
+// promoteAdmin gives the user with the given username the admin role.
+// This is how an admin is seeded when the first registered account is not the right one.
+func promoteAdmin(username string) {
+	result := database.DB.Model(&models.User{}).Where("username = ?", username).Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		fatal("Failed to promote user", "username", username, "error", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		fatal("User not found", "username", username)
+	}
+	slog.Info("User is now an admin", "username", username)
+}
+
+// migrate implements the "migrate" subcommands.
+func migrate(cfg *config.Config, args []string) {
+	const usage = "Usage: migrate up | down [steps] | status | create <name>"
+	if len(args) == 0 {
+		fatal(usage)
+	}
+
+	// Creating migration files only touches the source tree, so it doesn't need a database.
+	if args[0] == "create" {
+		if len(args) != 2 {
+			fatal("Usage: migrate create <name>")
+		}
+		paths, err := migrations.Create("migrations", args[1])
+		if err != nil {
+			fatal("Failed to create migration", "error", err)
+		}
+		for _, path := range paths {
+			slog.Info("Created migration file", "path", path)
+		}
+		return
+	}
+
+	connect(cfg)
+	migrator, err := migrations.New(database.DB)
+	if err != nil {
+		fatal("Failed to load migrations", "error", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			fatal("Migration failed", "error", err)
+		}
+		slog.Info("Database is up to date", "applied", len(applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fatal("Usage: migrate down [steps]")
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, m := range rolledBack {
+			slog.Info("Rolled back migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			fatal("Rollback failed", "error", err)
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fatal("Failed to read the migration status", "error", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		fatal(usage)
+	}
+}
+
+// checkMigrations compares the database schema with the embedded migrations at startup.
+// mode is the value of DB_MIGRATE_ON_START: "warn", "fail" or "apply" (checked by config.Validate).
+func checkMigrations(mode string) {
+	migrator, err := migrations.New(database.DB)
+	if err != nil {
+		fatal("Failed to load migrations", "error", err)
+	}
+
+	if mode == "apply" {
+		applied, err := migrator.Up()
+		if err != nil {
+			fatal("Failed to run database migrations", "error", err)
+		}
+		slog.Info("Database migrations completed", "applied", len(applied))
+		return
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		fatal("Failed to check database migrations", "error", err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if mode == "fail" {
+		fatal("Database schema is behind, run `migrate up` first", "pending", len(pending))
+	}
+	slog.Warn("Database schema is behind, run `migrate up`", "pending", len(pending))
+}
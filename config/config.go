@@ -0,0 +1,334 @@
+// Package config holds every setting of the application in one typed Config value.
+//
+// Settings are read, from lowest to highest precedence, from:
+//
+//  1. the built-in defaults, adjusted by the profile of the environment (APP_ENV:
+//     development, test or production),
+//  2. an optional config file: CONFIG_FILE if set, otherwise config.yaml (or .yml / .toml)
+//     followed by config.<env>.yaml (or .yml / .toml) in the working directory,
+//  3. environment variables, including the ones from .env and .env.<env> files
+//     (which never override variables that are already set).
+//
+// Load does not validate anything: call Validate (or the Validate method of a single
+// section, as the one-off commands do) before using the values, so a misconfigured
+// server refuses to start instead of failing on the first request.
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/validation"
+)
+
+// Supported values of APP_ENV.
+const (
+	EnvDevelopment = "development" // Default
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// MinSecretLength is the minimum length, in bytes, of the HS256 secret (JWT_SECRET).
+// HMAC-SHA256 keys shorter than the 256-bit hash output are easier to brute-force.
+const MinSecretLength = 32
+
+// Config is the complete application configuration.
+// The env tag of each field is the environment variable that overrides it.
+type Config struct {
+	Env       string    `yaml:"-" toml:"-" env:"APP_ENV"`
+	App       App       `yaml:"app" toml:"app"`
+	Database  Database  `yaml:"database" toml:"database"`
+	JWT       JWT       `yaml:"jwt" toml:"jwt"`
+	Password  Password  `yaml:"password" toml:"password"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Log       Log       `yaml:"log" toml:"log"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Lockout   Lockout   `yaml:"lockout" toml:"lockout"`
+	Mail      Mail      `yaml:"mail" toml:"mail"`
+	Account   Account   `yaml:"account" toml:"account"`
+	TwoFactor TwoFactor `yaml:"two_factor" toml:"two_factor"`
+	OIDC      OIDC      `yaml:"oidc" toml:"oidc"`
+}
+
+// App holds the settings of the HTTP server.
+// Durations are written like "15s" or "1m30s"; a timeout of 0 means no timeout.
+type App struct {
+	Port int `yaml:"port" toml:"port" env:"APP_PORT"`
+
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"APP_READ_TIMEOUT"`             // Reading a whole request, body included
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"APP_WRITE_TIMEOUT"`          // Writing the response
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"APP_IDLE_TIMEOUT"`             // Keep-alive connections waiting for the next request
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"APP_SHUTDOWN_TIMEOUT"` // Draining in-flight requests on SIGINT/SIGTERM
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"APP_SHUTDOWN_DELAY"`       // Failing /readyz before draining, so load balancers stop sending traffic
+	BodyLimit       int           `yaml:"body_limit" toml:"body_limit" env:"APP_BODY_LIMIT"`                   // Maximum request body size in bytes
+
+	// Serve HTTPS instead of plain HTTP when both are set (PEM files).
+	TLSCertFile string `yaml:"tls_cert_file" toml:"tls_cert_file" env:"APP_TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" toml:"tls_key_file" env:"APP_TLS_KEY_FILE"`
+}
+
+// Database holds the connection settings (see database.ConnectDB).
+type Database struct {
+	Driver   string `yaml:"driver" toml:"driver" env:"DB_DRIVER"` // mysql, postgres or sqlite
+	URL      string `yaml:"url" toml:"url" env:"DATABASE_URL"`    // Passed to the driver as-is; the fields below are ignored if set
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"` // 0 means the driver's default port
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`          // For SQLite, the file name without the ".db" extension
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"` // PostgreSQL only
+
+	// MigrateOnStart decides what happens at startup when some migrations have not been
+	// applied yet: "warn" logs and serves anyway, "fail" refuses to start, "apply" runs them.
+	MigrateOnStart string `yaml:"migrate_on_start" toml:"migrate_on_start" env:"DB_MIGRATE_ON_START"`
+
+	// Statements taking longer than this are logged as warnings; 0 disables the check.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+}
+
+// JWT holds the keys used to sign and verify access tokens (see jwtkeys.LoadKeys).
+type JWT struct {
+	Algorithm      string   `yaml:"algorithm" toml:"algorithm" env:"JWT_ALGORITHM"` // HS256, RS256 or EdDSA
+	Secret         string   `yaml:"secret" toml:"secret" env:"JWT_SECRET"`          // Required for HS256
+	KeyID          string   `yaml:"key_id" toml:"key_id" env:"JWT_KEY_ID"`
+	PrivateKeyFile string   `yaml:"private_key_file" toml:"private_key_file" env:"JWT_PRIVATE_KEY_FILE"` // Required for RS256 and EdDSA
+	PublicKeyFiles []string `yaml:"public_key_files" toml:"public_key_files" env:"JWT_PUBLIC_KEY_FILES"` // "kid=path.pem" or "path.pem"; comma-separated in the environment
+}
+
+// Password holds the rules new passwords must follow.
+type Password struct {
+	MinLength      int  `yaml:"min_length" toml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	RequireUpper   bool `yaml:"require_upper" toml:"require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	RequireLower   bool `yaml:"require_lower" toml:"require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	RequireDigit   bool `yaml:"require_digit" toml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSpecial bool `yaml:"require_special" toml:"require_special" env:"PASSWORD_REQUIRE_SPECIAL"`
+}
+
+// Tracing holds the OpenTelemetry settings (see tracing.Setup).
+type Tracing struct {
+	// Exporter is where finished spans are sent: "none" disables tracing, "otlp" sends them
+	// over OTLP/HTTP to a collector and "stdout" prints them as JSON, for local debugging.
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"` // host:port of the collector's OTLP/HTTP receiver
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"` // Plain HTTP instead of HTTPS, e.g. for a collector on localhost
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"` // Share of new traces recorded, from 0 to 1; an incoming traceparent's decision is kept
+}
+
+// Log holds the logging settings (see logging.Setup).
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`    // debug, info, warn or error; debug also logs every SQL statement
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"` // json (one object per line) or text (key=value pairs)
+}
+
+// RateLimit holds the request throttling settings (see ratelimit.New).
+// A rate of "off" disables that limit.
+type RateLimit struct {
+	Algorithm string `yaml:"algorithm" toml:"algorithm" env:"RATE_LIMIT_ALGORITHM"` // sliding_window or token_bucket
+	Store     string `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE"`             // memory (per instance) or redis (shared by every instance)
+	RedisURL  string `yaml:"redis_url" toml:"redis_url" env:"RATE_LIMIT_REDIS_URL"` // e.g. redis://:password@localhost:6379/0; any Redis-compatible server
+
+	AuthPerIP        Rate `yaml:"auth_per_ip" toml:"auth_per_ip" env:"RATE_LIMIT_AUTH_PER_IP"`                      // Requests to /auth/* from one client IP
+	LoginPerUsername Rate `yaml:"login_per_username" toml:"login_per_username" env:"RATE_LIMIT_LOGIN_PER_USERNAME"` // Login attempts for one username, from any IP
+	APIPerUser       Rate `yaml:"api_per_user" toml:"api_per_user" env:"RATE_LIMIT_API_PER_USER"`                   // Authenticated requests by one user
+}
+
+// Lockout holds the settings of the login lockout (see ratelimit.Lockout): after Threshold
+// failed logins in a row, the account is locked for Duration, doubled for every further
+// failure up to MaxDuration. A successful login, or ResetAfter without failures, starts over.
+type Lockout struct {
+	Threshold   int           `yaml:"threshold" toml:"threshold" env:"LOGIN_LOCKOUT_THRESHOLD"` // 0 disables the lockout
+	Duration    time.Duration `yaml:"duration" toml:"duration" env:"LOGIN_LOCKOUT_DURATION"`
+	MaxDuration time.Duration `yaml:"max_duration" toml:"max_duration" env:"LOGIN_LOCKOUT_MAX_DURATION"`
+	ResetAfter  time.Duration `yaml:"reset_after" toml:"reset_after" env:"LOGIN_LOCKOUT_RESET_AFTER"`
+}
+
+// Mail holds the settings of outgoing email (see mail.New).
+type Mail struct {
+	// Transport is how messages leave: "smtp" sends them to an SMTP server, "file" writes
+	// each one as an .eml file into Dir and "log" only logs them, links included.
+	Transport string `yaml:"transport" toml:"transport" env:"MAIL_TRANSPORT"`
+	From      string `yaml:"from" toml:"from" env:"MAIL_FROM"` // e.g. "Example <no-reply@example.com>"
+
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host" env:"MAIL_SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port" env:"MAIL_SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"MAIL_SMTP_USERNAME"` // Leave empty for servers without authentication
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"MAIL_SMTP_PASSWORD"`
+	// SMTPTLS is starttls (upgrade the connection, required), tls (TLS from the start, usually
+	// on port 465) or none, e.g. for a local SMTP stand-in such as Mailpit or MailHog.
+	SMTPTLS string `yaml:"smtp_tls" toml:"smtp_tls" env:"MAIL_SMTP_TLS"`
+
+	Dir string `yaml:"dir" toml:"dir" env:"MAIL_DIR"` // For the file transport
+}
+
+// Account holds the settings of email verification and password reset (see services.AccountService).
+// The links sent by email are the URLs below with the token appended as the "token" query parameter.
+type Account struct {
+	// Refuse to log in (and don't log in on registration) until the email address is verified.
+	RequireVerifiedEmail bool `yaml:"require_verified_email" toml:"require_verified_email" env:"ACCOUNT_REQUIRE_VERIFIED_EMAIL"`
+
+	VerifyEmailURL   string        `yaml:"verify_email_url" toml:"verify_email_url" env:"ACCOUNT_VERIFY_EMAIL_URL"`       // GET /auth/verify of this API, or a page of your frontend that posts the token to it
+	VerifyEmailTTL   time.Duration `yaml:"verify_email_ttl" toml:"verify_email_ttl" env:"ACCOUNT_VERIFY_EMAIL_TTL"`       // How long a verification link works
+	ResetPasswordURL string        `yaml:"reset_password_url" toml:"reset_password_url" env:"ACCOUNT_RESET_PASSWORD_URL"` // A page of your frontend that posts the token and the new password to /auth/reset-password
+	ResetPasswordTTL time.Duration `yaml:"reset_password_ttl" toml:"reset_password_ttl" env:"ACCOUNT_RESET_PASSWORD_TTL"` // How long a reset link works
+}
+
+// TwoFactor holds the settings of TOTP two-factor authentication (see services.TwoFactorService).
+type TwoFactor struct {
+	Issuer        string        `yaml:"issuer" toml:"issuer" env:"TWO_FACTOR_ISSUER"`                         // Shown by authenticator apps above the account name
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" toml:"challenge_ttl" env:"TWO_FACTOR_CHALLENGE_TTL"`    // How long after the password the code can be entered
+	RecoveryCodes int           `yaml:"recovery_codes" toml:"recovery_codes" env:"TWO_FACTOR_RECOVERY_CODES"` // How many single-use recovery codes are generated at a time
+}
+
+// OIDC holds the settings of logging in through OpenID Connect identity providers
+// (see services.OIDCService). No provider is configured by default.
+type OIDC struct {
+	// Providers can only be listed in a config file; the client secret of each can also
+	// come from OIDC_<NAME>_CLIENT_SECRET (see OIDCProvider.SecretEnv) to keep it out of the file.
+	Providers []OIDCProvider `yaml:"providers" toml:"providers"`
+
+	// Public URL of this API. The callback of each provider, to register at the provider,
+	// is this URL followed by /auth/oidc/<name>/callback.
+	RedirectBaseURL string        `yaml:"redirect_base_url" toml:"redirect_base_url" env:"OIDC_REDIRECT_BASE_URL"`
+	StateTTL        time.Duration `yaml:"state_ttl" toml:"state_ttl" env:"OIDC_STATE_TTL"`                // How long the user has to log in at the provider
+	AutoProvision   bool          `yaml:"auto_provision" toml:"auto_provision" env:"OIDC_AUTO_PROVISION"` // Create an account on the first login of an unknown email address
+}
+
+// OIDCProvider is an OpenID Connect identity provider users can log in with.
+// Its endpoints and signing keys are discovered from the issuer.
+type OIDCProvider struct {
+	Name         string   `yaml:"name" toml:"name"`                   // In the URLs, e.g. /auth/oidc/<name>/login; lower case letters, digits, - and _
+	Issuer       string   `yaml:"issuer" toml:"issuer"`               // Discovery document at <issuer>/.well-known/openid-configuration
+	ClientID     string   `yaml:"client_id" toml:"client_id"`         // Also the audience of the ID tokens
+	ClientSecret string   `yaml:"client_secret" toml:"client_secret"` // Empty for a public client, which relies on PKCE alone
+	Scopes       []string `yaml:"scopes" toml:"scopes"`               // Requested besides "openid"; "email" is needed to link accounts
+}
+
+// SecretEnv is the environment variable that overrides the client secret of the provider,
+// e.g. OIDC_CORP_CLIENT_SECRET for the provider "corp".
+func (p OIDCProvider) SecretEnv() string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_")) + "_CLIENT_SECRET"
+}
+
+// Policy converts the settings into the policy checked by the `password` validation tag.
+func (p Password) Policy() validation.PasswordPolicy {
+	return validation.PasswordPolicy{
+		MinLength:      p.MinLength,
+		RequireUpper:   p.RequireUpper,
+		RequireLower:   p.RequireLower,
+		RequireDigit:   p.RequireDigit,
+		RequireSpecial: p.RequireSpecial,
+	}
+}
+
+// Default returns the built-in configuration of the given environment,
+// before any config file or environment variable is applied.
+func Default(env string) (*Config, error) {
+	profile, ok := profiles[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown APP_ENV %q (expected development, test or production)", env)
+	}
+
+	policy := validation.DefaultPasswordPolicy
+	cfg := &Config{
+		Env: env,
+		App: App{
+			Port:            3000,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+			BodyLimit:       4 * 1024 * 1024, // Fiber's default
+		},
+		Database: Database{
+			Driver:             "mysql",
+			Host:               "localhost",
+			SSLMode:            "disable",
+			MigrateOnStart:     "warn",
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		JWT: JWT{Algorithm: "HS256"},
+		Password: Password{
+			MinLength:      policy.MinLength,
+			RequireUpper:   policy.RequireUpper,
+			RequireLower:   policy.RequireLower,
+			RequireDigit:   policy.RequireDigit,
+			RequireSpecial: policy.RequireSpecial,
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+			ServiceName:  "test3-bayu-be",
+			SampleRatio:  1,
+		},
+		Log: Log{Level: "info", Format: "json"},
+		RateLimit: RateLimit{
+			Algorithm:        "sliding_window",
+			Store:            "memory",
+			AuthPerIP:        Rate{Limit: 20, Window: time.Minute},
+			LoginPerUsername: Rate{Limit: 10, Window: time.Minute},
+			APIPerUser:       Rate{Limit: 600, Window: time.Minute},
+		},
+		Lockout: Lockout{
+			Threshold:   5,
+			Duration:    time.Minute,
+			MaxDuration: time.Hour,
+			ResetAfter:  15 * time.Minute,
+		},
+		Mail: Mail{
+			Transport: "log",
+			From:      "no-reply@localhost",
+			SMTPHost:  "localhost",
+			SMTPPort:  587,
+			SMTPTLS:   "starttls",
+			Dir:       "mail",
+		},
+		Account: Account{
+			VerifyEmailURL:   "http://localhost:3000/auth/verify",
+			VerifyEmailTTL:   48 * time.Hour,
+			ResetPasswordURL: "http://localhost:3000/reset-password",
+			ResetPasswordTTL: time.Hour,
+		},
+		TwoFactor: TwoFactor{
+			Issuer:        "test3-bayu-be",
+			ChallengeTTL:  5 * time.Minute,
+			RecoveryCodes: 10,
+		},
+		OIDC: OIDC{
+			RedirectBaseURL: "http://localhost:3000",
+			StateTTL:        10 * time.Minute,
+			AutoProvision:   true,
+		},
+	}
+	profile(cfg)
+	return cfg, nil
+}
+
+// profiles adjust the defaults for each environment.
+// A config file or environment variable still overrides anything set here.
+var profiles = map[string]func(*Config){
+	// Logs are read by a person in a terminal rather than by a log collector.
+	EnvDevelopment: func(cfg *Config) {
+		cfg.Log.Format = "text"
+	},
+
+	// Tests run against a throwaway SQLite file that is brought up to date on start.
+	EnvTest: func(cfg *Config) {
+		cfg.Database.Driver = "sqlite"
+		cfg.Database.Name = "test"
+		cfg.Database.MigrateOnStart = "apply"
+	},
+
+	// Production never serves on an outdated schema, only talks to PostgreSQL over TLS
+	// and gives the load balancer time to take the instance out of rotation on shutdown.
+	// Traces only leave the instance over TLS, and emails are sent, never logged with their links.
+	EnvProduction: func(cfg *Config) {
+		cfg.Mail.Transport = "smtp"
+		cfg.App.ShutdownDelay = 5 * time.Second
+		cfg.Database.MigrateOnStart = "fail"
+		cfg.Database.SSLMode = "require"
+		cfg.Tracing.OTLPInsecure = false
+	},
+}
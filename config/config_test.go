@@ -0,0 +1,242 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// writeFile writes a config file to a temporary directory and points CONFIG_FILE at it.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("APP_ENV", config.EnvProduction)
+	writeFile(t, "config.yaml", `
+app:
+  port: 8080
+  read_timeout: 5s
+database:
+  driver: postgres
+  host: db.internal
+  name: shop
+jwt:
+  secret: from-the-config-file-from-the-config-file
+  public_key_files: [old.pem]
+rate_limit:
+  auth_per_ip: 5/10s
+  login_per_username: "off"
+`)
+	t.Setenv("DB_HOST", "db.override")
+	t.Setenv("APP_SHUTDOWN_TIMEOUT", "1m30s")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "v1=a.pem, b.pem")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("RATE_LIMIT_API_PER_USER", "100/m")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.App.Port != 8080 || cfg.App.ReadTimeout != 5*time.Second || cfg.Database.Name != "shop" {
+		t.Errorf("config file values not applied: %+v", cfg)
+	}
+	if cfg.Database.Host != "db.override" {
+		t.Errorf("DB_HOST = %q, want the environment to win over the file", cfg.Database.Host)
+	}
+	if cfg.App.ShutdownTimeout != 90*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want 1m30s", cfg.App.ShutdownTimeout)
+	}
+	if got := strings.Join(cfg.JWT.PublicKeyFiles, "|"); got != "v1=a.pem|b.pem" {
+		t.Errorf("PublicKeyFiles = %q", got)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("SampleRatio = %g, want 0.25", cfg.Tracing.SampleRatio)
+	}
+	if cfg.RateLimit.APIPerUser != (config.Rate{Limit: 100, Window: time.Minute}) || cfg.RateLimit.AuthPerIP.String() != "5/10s" || cfg.RateLimit.LoginPerUsername.Enabled() {
+		t.Errorf("rates not applied: %+v", cfg.RateLimit)
+	}
+	// Set by the production profile, not overridden anywhere.
+	if cfg.Database.MigrateOnStart != "fail" || cfg.Database.SSLMode != "require" {
+		t.Errorf("production profile not applied: %+v", cfg.Database)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	writeFile(t, "config.toml", `
+[app]
+idle_timeout = "2m"
+
+[database]
+driver = "sqlite"
+name = "local"
+
+[password]
+min_length = 12
+require_special = true
+
+[rate_limit]
+api_per_user = "30/1s"
+`)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != config.EnvDevelopment || cfg.App.IdleTimeout != 2*time.Minute || cfg.Database.Driver != "sqlite" || cfg.Password.MinLength != 12 || !cfg.Password.RequireSpecial || cfg.RateLimit.APIPerUser.String() != "30/1s" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	writeFile(t, "config.yaml", `
+oidc:
+  redirect_base_url: https://api.example.com
+  providers:
+    - name: corp-sso
+      issuer: https://login.example.com
+      client_id: shop
+      client_secret: from-the-file
+      scopes: [email, profile]
+    - name: partner
+      issuer: https://partner.example.com
+      client_id: shop
+`)
+	t.Setenv("OIDC_CORP_SSO_CLIENT_SECRET", "from-the-environment")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := cfg.OIDC.Providers
+	if len(providers) != 2 || providers[0].Issuer != "https://login.example.com" || strings.Join(providers[0].Scopes, " ") != "email profile" {
+		t.Fatalf("providers = %+v", providers)
+	}
+	if providers[0].ClientSecret != "from-the-environment" || providers[1].ClientSecret != "" {
+		t.Errorf("client secrets = %q and %q, want the environment to win over the file", providers[0].ClientSecret, providers[1].ClientSecret)
+	}
+	if err := cfg.OIDC.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, file, env, value string
+	}{
+		{name: "unknown yaml key", file: "database:\n  hostname: x\n"},
+		{name: "unknown toml key", file: "[jwt]\nsecrets = \"x\"\n"},
+		{name: "unknown environment", env: "APP_ENV", value: "staging"},
+		{name: "malformed number", env: "APP_PORT", value: "eighty"},
+		{name: "malformed duration", env: "APP_READ_TIMEOUT", value: "15"},
+		{name: "malformed boolean", env: "PASSWORD_REQUIRE_DIGIT", value: "sometimes"},
+		{name: "malformed ratio", env: "TRACING_SAMPLE_RATIO", value: "half"},
+		{name: "malformed rate", env: "RATE_LIMIT_AUTH_PER_IP", value: "20 per minute"},
+		{name: "malformed rate in a file", file: "rate_limit:\n  api_per_user: 0/1m\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch {
+			case strings.HasPrefix(tt.file, "["):
+				writeFile(t, "config.toml", tt.file)
+			case tt.file != "":
+				writeFile(t, "config.yaml", tt.file)
+			}
+			if tt.env != "" {
+				t.Setenv(tt.env, tt.value)
+			}
+			if _, err := config.Load(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *config.Config {
+		cfg, err := config.Default(config.EnvDevelopment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Database.User = "app"
+		cfg.Database.Name = "shop"
+		cfg.JWT.Secret = strings.Repeat("s", config.MinSecretLength)
+		return cfg
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config)
+		want   string
+	}{
+		{"short secret", func(cfg *config.Config) { cfg.JWT.Secret = "too-short" }, "JWT_SECRET must be at least 32 bytes"},
+		{"missing secret", func(cfg *config.Config) { cfg.JWT.Secret = "" }, "JWT_SECRET is required"},
+		{"missing private key", func(cfg *config.Config) { cfg.JWT.Algorithm = "RS256" }, "JWT_PRIVATE_KEY_FILE is required"},
+		{"missing database name", func(cfg *config.Config) { cfg.Database.Name = "" }, "DB_NAME is required"},
+		{"URL replaces the parts", func(cfg *config.Config) { cfg.Database.Name, cfg.Database.URL = "", "app@tcp(db)/shop" }, ""},
+		{"unknown driver", func(cfg *config.Config) { cfg.Database.Driver = "oracle" }, "DB_DRIVER must be"},
+		{"bad migrate mode", func(cfg *config.Config) { cfg.Database.MigrateOnStart = "later" }, "DB_MIGRATE_ON_START must be"},
+		{"bad port", func(cfg *config.Config) { cfg.App.Port = 0 }, "APP_PORT must be"},
+		{"negative timeout", func(cfg *config.Config) { cfg.App.WriteTimeout = -time.Second }, "APP_WRITE_TIMEOUT must not be negative"},
+		{"certificate without key", func(cfg *config.Config) { cfg.App.TLSCertFile = "cert.pem" }, "must be set together"},
+		{"unknown exporter", func(cfg *config.Config) { cfg.Tracing.Exporter = "zipkin" }, "TRACING_EXPORTER must be"},
+		{"sample ratio above 1", func(cfg *config.Config) { cfg.Tracing.SampleRatio = 1.5 }, "TRACING_SAMPLE_RATIO must be between 0 and 1"},
+		{"unknown log level", func(cfg *config.Config) { cfg.Log.Level = "verbose" }, "LOG_LEVEL must be"},
+		{"upper-case log level", func(cfg *config.Config) { cfg.Log.Level = "WARN" }, ""},
+		{"unknown log format", func(cfg *config.Config) { cfg.Log.Format = "xml" }, "LOG_FORMAT must be"},
+		{"redis store without URL", func(cfg *config.Config) { cfg.RateLimit.Store = "redis" }, "RATE_LIMIT_REDIS_URL is required"},
+		{"unknown algorithm", func(cfg *config.Config) { cfg.RateLimit.Algorithm = "leaky_bucket" }, "RATE_LIMIT_ALGORITHM must be"},
+		{"lockout shorter than its start", func(cfg *config.Config) { cfg.Lockout.MaxDuration = time.Second }, "LOGIN_LOCKOUT_MAX_DURATION must be at least"},
+		{"lockout disabled", func(cfg *config.Config) { cfg.Lockout = config.Lockout{} }, ""},
+		{"password minimum over bcrypt's limit", func(cfg *config.Config) { cfg.Password.MinLength = 73 }, "PASSWORD_MIN_LENGTH must be at most 72"},
+		{"unknown mail transport", func(cfg *config.Config) { cfg.Mail.Transport = "pigeon" }, "MAIL_TRANSPORT must be"},
+		{"sender with a name", func(cfg *config.Config) { cfg.Mail.From = "Shop <no-reply@example.com>" }, ""},
+		{"sender without an address", func(cfg *config.Config) { cfg.Mail.From = "Shop" }, "MAIL_FROM must be an email address"},
+		{"smtp without TLS", func(cfg *config.Config) { cfg.Mail.Transport, cfg.Mail.SMTPTLS = "smtp", "none" }, ""},
+		{"unknown smtp TLS mode", func(cfg *config.Config) { cfg.Mail.Transport, cfg.Mail.SMTPTLS = "smtp", "ssl" }, "MAIL_SMTP_TLS must be"},
+		{"relative reset link", func(cfg *config.Config) { cfg.Account.ResetPasswordURL = "/reset-password" }, "ACCOUNT_RESET_PASSWORD_URL must be an absolute"},
+		{"expired verification links", func(cfg *config.Config) { cfg.Account.VerifyEmailTTL = 0 }, "ACCOUNT_VERIFY_EMAIL_TTL must be positive"},
+		{"issuer with a colon", func(cfg *config.Config) { cfg.TwoFactor.Issuer = "Shop: admin" }, "TWO_FACTOR_ISSUER must be set"},
+		{"no recovery codes", func(cfg *config.Config) { cfg.TwoFactor.RecoveryCodes = 0 }, "TWO_FACTOR_RECOVERY_CODES must be between"},
+		{"provider without client ID", func(cfg *config.Config) {
+			cfg.OIDC.Providers = []config.OIDCProvider{{Name: "corp", Issuer: "https://login.example.com"}}
+		}, "oidc.providers[0].client_id is required"},
+		{"provider name in upper case", func(cfg *config.Config) {
+			cfg.OIDC.Providers = []config.OIDCProvider{{Name: "Corp", Issuer: "https://login.example.com", ClientID: "shop"}}
+		}, "oidc.providers[0].name must be"},
+		{"duplicate provider", func(cfg *config.Config) {
+			corp := config.OIDCProvider{Name: "corp", Issuer: "https://login.example.com", ClientID: "shop"}
+			cfg.OIDC.Providers = []config.OIDCProvider{corp, corp}
+		}, `oidc.providers[1].name "corp" is used`},
+		{"relative redirect base", func(cfg *config.Config) {
+			cfg.OIDC.RedirectBaseURL = "/api"
+			cfg.OIDC.Providers = []config.OIDCProvider{{Name: "corp", Issuer: "https://login.example.com", ClientID: "shop"}}
+		}, "OIDC_REDIRECT_BASE_URL must be an absolute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(cfg)
+			err := cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
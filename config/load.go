@@ -0,0 +1,197 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration from the defaults, the config files and the environment
+// (see the package documentation for the precedence). It fails on unreadable files,
+// unknown keys in a config file and environment variables of the wrong type.
+func Load() (*Config, error) {
+	// .env is optional, and never overrides variables that are already set.
+	if err := loadDotEnv(".env"); err != nil {
+		return nil, err
+	}
+
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = EnvDevelopment
+	}
+	if err := loadDotEnv(".env." + env); err != nil {
+		return nil, err
+	}
+
+	cfg, err := Default(env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range configFiles(env) {
+		if err := cfg.decodeFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+	// The providers are a list, which applyEnv can't set, but their secrets may come from the environment.
+	for i, provider := range cfg.OIDC.Providers {
+		if secret := os.Getenv(provider.SecretEnv()); secret != "" {
+			cfg.OIDC.Providers[i].ClientSecret = secret
+		}
+	}
+	return cfg, nil
+}
+
+// loadDotEnv loads a .env file into the process environment if it exists.
+func loadDotEnv(path string) error {
+	if err := godotenv.Load(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// configFiles returns the config files to read, in order: CONFIG_FILE alone if set
+// (it must exist), otherwise the shared config file and then the one of the environment,
+// whichever of them exist.
+func configFiles(env string) []string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return []string{path}
+	}
+
+	var paths []string
+	for _, base := range []string{"config", "config." + env} {
+		for _, ext := range []string{".yaml", ".yml", ".toml"} {
+			if _, err := os.Stat(base + ext); err == nil {
+				paths = append(paths, base+ext)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// decodeFile applies a YAML or TOML config file (picked by its extension) on top of cfg.
+// Keys missing from the file keep their current value.
+func (cfg *Config) decodeFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true) // A misspelled key is an error rather than a silently ignored setting
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config file format %q (expected .yaml, .yml or .toml)", path, filepath.Ext(path))
+	}
+	return nil
+}
+
+// applyEnv overrides every field of the struct v that has an env tag with the value of
+// that environment variable, recursing into nested structs. Empty variables are ignored.
+func applyEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && !isTextUnmarshaler(field) {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := v.Type().Field(i).Tag.Get("env")
+		value := os.Getenv(key)
+		if key == "" || value == "" {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	return nil
+}
+
+// setField parses an environment variable into a field of a supported kind.
+// Durations use the time.ParseDuration syntax ("15s") and lists are comma-separated.
+func setField(field reflect.Value, value string) error {
+	if isTextUnmarshaler(field) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("not a duration (e.g. 15s or 1m30s)")
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		field.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// isTextUnmarshaler reports whether the field parses itself from a string (e.g. Rate).
+func isTextUnmarshaler(field reflect.Value) bool {
+	_, ok := field.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is a number of requests allowed per time window, written "<limit>/<window>",
+// e.g. "20/1m" or "5/10s" ("20/m" is short for "20/1m"). "off" (the zero Rate) means
+// no limit. It is parsed the same way from config files and environment variables.
+type Rate struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled reports whether the rate limits anything.
+func (r Rate) Enabled() bool {
+	return r.Limit > 0
+}
+
+// String formats the rate the way UnmarshalText reads it.
+func (r Rate) String() string {
+	if !r.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", r.Limit, r.Window)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Rate) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "off" {
+		*r = Rate{}
+		return nil
+	}
+
+	limit, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return errors.New(`not a rate (e.g. "20/1m" or "off")`)
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return fmt.Errorf("limit %q is not a positive integer", limit)
+	}
+	if window != "" && (window[0] < '0' || window[0] > '9') {
+		window = "1" + window // "m" -> "1m"
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Millisecond {
+		return fmt.Errorf("window %q is not a duration of at least 1ms", window)
+	}
+	*r = Rate{Limit: n, Window: d}
+	return nil
+}
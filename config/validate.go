@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Validate checks the whole configuration and reports every problem at once,
+// one per line, named after the environment variable that sets the value.
+func (cfg *Config) Validate() error {
+	return errors.Join(
+		cfg.App.Validate(),
+		cfg.Database.Validate(),
+		cfg.JWT.Validate(),
+		cfg.Password.Validate(),
+		cfg.Tracing.Validate(),
+		cfg.Log.Validate(),
+		cfg.RateLimit.Validate(),
+		cfg.Lockout.Validate(),
+		cfg.Mail.Validate(),
+		cfg.Account.Validate(),
+		cfg.TwoFactor.Validate(),
+		cfg.OIDC.Validate(),
+	)
+}
+
+// Validate checks the HTTP server settings.
+func (a App) Validate() error {
+	var errs []error
+	if a.Port < 1 || a.Port > 65535 {
+		errs = append(errs, fmt.Errorf("APP_PORT must be between 1 and 65535, got %d", a.Port))
+	}
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"APP_READ_TIMEOUT", a.ReadTimeout},
+		{"APP_WRITE_TIMEOUT", a.WriteTimeout},
+		{"APP_IDLE_TIMEOUT", a.IdleTimeout},
+		{"APP_SHUTDOWN_TIMEOUT", a.ShutdownTimeout},
+		{"APP_SHUTDOWN_DELAY", a.ShutdownDelay},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", timeout.key, timeout.value))
+		}
+	}
+	if a.BodyLimit < 1 {
+		errs = append(errs, fmt.Errorf("APP_BODY_LIMIT must be at least 1 byte, got %d", a.BodyLimit))
+	}
+	if (a.TLSCertFile == "") != (a.TLSKeyFile == "") {
+		errs = append(errs, errors.New("APP_TLS_CERT_FILE and APP_TLS_KEY_FILE must be set together"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the database settings. It is enough for the one-off commands,
+// which only need a connection.
+func (d Database) Validate() error {
+	var errs []error
+	switch d.Driver {
+	case "mysql", "postgres":
+		// A DATABASE_URL carries everything; otherwise the connection string is built from parts.
+		if d.URL == "" {
+			if d.Host == "" {
+				errs = append(errs, errors.New("DB_HOST is required (or set DATABASE_URL)"))
+			}
+			if d.User == "" {
+				errs = append(errs, errors.New("DB_USER is required (or set DATABASE_URL)"))
+			}
+			if d.Name == "" {
+				errs = append(errs, errors.New("DB_NAME is required (or set DATABASE_URL)"))
+			}
+		}
+	case "sqlite":
+		if d.URL == "" && d.Name == "" {
+			errs = append(errs, errors.New("DB_NAME is required for sqlite (or set DATABASE_URL)"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be mysql, postgres or sqlite, got %q", d.Driver))
+	}
+
+	if d.Port < 0 || d.Port > 65535 {
+		errs = append(errs, fmt.Errorf("DB_PORT must be a port number, got %d", d.Port))
+	}
+
+	if d.SlowQueryThreshold < 0 {
+		errs = append(errs, fmt.Errorf("DB_SLOW_QUERY_THRESHOLD must not be negative, got %s", d.SlowQueryThreshold))
+	}
+
+	switch d.MigrateOnStart {
+	case "warn", "fail", "apply":
+	default:
+		errs = append(errs, fmt.Errorf("DB_MIGRATE_ON_START must be warn, fail or apply, got %q", d.MigrateOnStart))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the signing key is configured for the selected algorithm.
+// The key files themselves are read by jwtkeys.LoadKeys.
+func (j JWT) Validate() error {
+	switch j.Algorithm {
+	case "HS256":
+		if j.Secret == "" {
+			return errors.New("JWT_SECRET is required for HS256")
+		}
+		if len(j.Secret) < MinSecretLength {
+			return fmt.Errorf("JWT_SECRET must be at least %d bytes long, got %d", MinSecretLength, len(j.Secret))
+		}
+	case "RS256", "EdDSA":
+		if j.PrivateKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", j.Algorithm)
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be HS256, RS256 or EdDSA, got %q", j.Algorithm)
+	}
+	return nil
+}
+
+// Validate checks the password policy.
+func (p Password) Validate() error {
+	if p.MinLength < 1 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at least 1, got %d", p.MinLength)
+	}
+	if p.MinLength > 72 {
+		// bcrypt, which hashes the passwords, takes at most 72 bytes.
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at most 72, got %d", p.MinLength)
+	}
+	return nil
+}
+
+// Validate checks the tracing settings.
+func (t Tracing) Validate() error {
+	var errs []error
+	switch t.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if t.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("TRACING_OTLP_ENDPOINT is required for the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be none, otlp or stdout, got %q", t.Exporter))
+	}
+	if t.ServiceName == "" {
+		errs = append(errs, errors.New("TRACING_SERVICE_NAME must not be empty"))
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", t.SampleRatio))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the logging settings.
+func (l Log) Validate() error {
+	var errs []error
+	switch strings.ToLower(l.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", l.Level))
+	}
+	switch l.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be json or text, got %q", l.Format))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the rate limiting settings.
+func (r RateLimit) Validate() error {
+	var errs []error
+	switch r.Algorithm {
+	case "sliding_window", "token_bucket":
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_ALGORITHM must be sliding_window or token_bucket, got %q", r.Algorithm))
+	}
+	switch r.Store {
+	case "memory":
+	case "redis":
+		if r.RedisURL == "" {
+			errs = append(errs, errors.New("RATE_LIMIT_REDIS_URL is required for the redis store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be memory or redis, got %q", r.Store))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the login lockout settings.
+func (l Lockout) Validate() error {
+	if l.Threshold < 0 {
+		return fmt.Errorf("LOGIN_LOCKOUT_THRESHOLD must not be negative, got %d", l.Threshold)
+	}
+	if l.Threshold == 0 {
+		return nil // Disabled
+	}
+	var errs []error
+	if l.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("LOGIN_LOCKOUT_DURATION must be positive, got %s", l.Duration))
+	}
+	if l.MaxDuration < l.Duration {
+		errs = append(errs, fmt.Errorf("LOGIN_LOCKOUT_MAX_DURATION must be at least LOGIN_LOCKOUT_DURATION, got %s", l.MaxDuration))
+	}
+	if l.ResetAfter <= 0 {
+		errs = append(errs, fmt.Errorf("LOGIN_LOCKOUT_RESET_AFTER must be positive, got %s", l.ResetAfter))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the outgoing email settings.
+func (m Mail) Validate() error {
+	var errs []error
+	if _, err := mail.ParseAddress(m.From); err != nil {
+		errs = append(errs, fmt.Errorf("MAIL_FROM must be an email address, got %q", m.From))
+	}
+	switch m.Transport {
+	case "log":
+	case "file":
+		if m.Dir == "" {
+			errs = append(errs, errors.New("MAIL_DIR is required for the file transport"))
+		}
+	case "smtp":
+		if m.SMTPHost == "" {
+			errs = append(errs, errors.New("MAIL_SMTP_HOST is required for the smtp transport"))
+		}
+		if m.SMTPPort < 1 || m.SMTPPort > 65535 {
+			errs = append(errs, fmt.Errorf("MAIL_SMTP_PORT must be between 1 and 65535, got %d", m.SMTPPort))
+		}
+		switch m.SMTPTLS {
+		case "starttls", "tls", "none":
+		default:
+			errs = append(errs, fmt.Errorf("MAIL_SMTP_TLS must be starttls, tls or none, got %q", m.SMTPTLS))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_TRANSPORT must be smtp, file or log, got %q", m.Transport))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the email verification and password reset settings.
+func (a Account) Validate() error {
+	var errs []error
+	for _, link := range []struct {
+		name, value string
+	}{
+		{"ACCOUNT_VERIFY_EMAIL_URL", a.VerifyEmailURL},
+		{"ACCOUNT_RESET_PASSWORD_URL", a.ResetPasswordURL},
+	} {
+		if !absoluteHTTPURL(link.value) {
+			errs = append(errs, fmt.Errorf("%s must be an absolute http(s) URL, got %q", link.name, link.value))
+		}
+	}
+	if a.VerifyEmailTTL <= 0 {
+		errs = append(errs, fmt.Errorf("ACCOUNT_VERIFY_EMAIL_TTL must be positive, got %s", a.VerifyEmailTTL))
+	}
+	if a.ResetPasswordTTL <= 0 {
+		errs = append(errs, fmt.Errorf("ACCOUNT_RESET_PASSWORD_TTL must be positive, got %s", a.ResetPasswordTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the two-factor authentication settings.
+func (t TwoFactor) Validate() error {
+	var errs []error
+	// The issuer is the prefix of the account label in the otpauth URI, which ends at a colon.
+	if t.Issuer == "" || strings.Contains(t.Issuer, ":") {
+		errs = append(errs, fmt.Errorf("TWO_FACTOR_ISSUER must be set and must not contain a colon, got %q", t.Issuer))
+	}
+	if t.ChallengeTTL <= 0 {
+		errs = append(errs, fmt.Errorf("TWO_FACTOR_CHALLENGE_TTL must be positive, got %s", t.ChallengeTTL))
+	}
+	if t.RecoveryCodes < 1 || t.RecoveryCodes > 100 {
+		errs = append(errs, fmt.Errorf("TWO_FACTOR_RECOVERY_CODES must be between 1 and 100, got %d", t.RecoveryCodes))
+	}
+	return errors.Join(errs...)
+}
+
+// providerName matches the names allowed for OIDC providers, which appear in URLs.
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Validate checks the OpenID Connect settings. Providers are named after their position
+// in the config file, since they have no environment variables.
+func (o OIDC) Validate() error {
+	var errs []error
+	if o.StateTTL <= 0 {
+		errs = append(errs, fmt.Errorf("OIDC_STATE_TTL must be positive, got %s", o.StateTTL))
+	}
+	if len(o.Providers) > 0 && !absoluteHTTPURL(o.RedirectBaseURL) {
+		errs = append(errs, fmt.Errorf("OIDC_REDIRECT_BASE_URL must be an absolute http(s) URL, got %q", o.RedirectBaseURL))
+	}
+	names := map[string]bool{}
+	for i, p := range o.Providers {
+		key := fmt.Sprintf("oidc.providers[%d]", i)
+		if !providerName.MatchString(p.Name) {
+			errs = append(errs, fmt.Errorf("%s.name must be 1 to 50 lower case letters, digits, - or _, got %q", key, p.Name))
+		} else if names[p.Name] {
+			errs = append(errs, fmt.Errorf("%s.name %q is used by another provider", key, p.Name))
+		}
+		names[p.Name] = true
+		if !absoluteHTTPURL(p.Issuer) {
+			errs = append(errs, fmt.Errorf("%s.issuer must be an absolute http(s) URL, got %q", key, p.Issuer))
+		}
+		if p.ClientID == "" {
+			errs = append(errs, fmt.Errorf("%s.client_id is required", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// absoluteHTTPURL reports whether value is an absolute http or https URL.
+func absoluteHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
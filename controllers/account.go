@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/dto"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccountHandler serves the email verification and password reset routes under /auth.
+type AccountHandler struct {
+	accounts *services.AccountService
+}
+
+// NewAccountHandler creates an AccountHandler.
+func NewAccountHandler(accounts *services.AccountService) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// VerifyEmail handles following an email verification link.
+// The token is read from the query string on GET, so the link can point straight at
+// this API, or from the JSON body on POST, for frontends that handle the link themselves.
+func (h *AccountHandler) VerifyEmail(c *fiber.Ctx) error {
+	req := new(dto.VerifyEmailRequest)
+	if c.Method() == fiber.MethodGet {
+		req.Token = c.Query("token")
+		if err := validate(req); err != nil {
+			return err
+		}
+	} else if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	if err := h.accounts.VerifyEmail(c.UserContext(), req.Token); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Email address verified"})
+}
+
+// ForgotPassword handles requesting a password reset link.
+// It answers 202 Accepted whether or not the address belongs to an account,
+// so it can't be used to find out which addresses are registered.
+func (h *AccountHandler) ForgotPassword(c *fiber.Ctx) error {
+	req := new(dto.ForgotPasswordRequest)
+	if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	if err := h.accounts.ForgotPassword(c.UserContext(), req.Email); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If an account uses this email address, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword handles choosing a new password with a reset link.
+// Every session of the account is logged out; the user logs in again with the new password.
+func (h *AccountHandler) ResetPassword(c *fiber.Ctx) error {
+	req := new(dto.ResetPasswordRequest)
+	if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	if err := h.accounts.ResetPassword(c.UserContext(), req.Token, req.Password); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password reset; please log in with the new password"})
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/dto"      // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHandler serves the /api-keys routes with which authenticated users
+// manage their own personal API keys.
+type APIKeyHandler struct {
+	apiKeys *services.APIKeyService
+}
+
+// NewAPIKeyHandler creates an APIKeyHandler.
+func NewAPIKeyHandler(apiKeys *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeys: apiKeys}
+}
+
+// CreateAPIKey issues a new key. The key itself is only in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	req := new(dto.CreateAPIKeyRequest)
+	if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	apiKey, key, err := h.apiKeys.Create(c.UserContext(), userID, req)
+	if err != nil {
+		return err
+	}
+	// The response holds the key, so it must not be cached anywhere.
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key created; copy it now, it won't be shown again",
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// GetAPIKeys lists the keys of the authenticated user, without the keys themselves.
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	apiKeys, err := h.apiKeys.List(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(apiKeys)
+}
+
+// RevokeAPIKey stops a key of the authenticated user from working. The key stays in the list.
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.apiKeys.Revoke(c.UserContext(), userID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "API key revoked"})
+}
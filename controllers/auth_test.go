@@ -0,0 +1,183 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRegisterMakesFirstUserAdmin(t *testing.T) {
+	app, _ := newTestApp()
+
+	for _, tc := range []struct {
+		username string
+		role     string
+	}{
+		{"alice", "admin"},
+		{"bob", "user"},
+	} {
+		status, object, _ := send(t, app, "POST", "/auth/register", "", map[string]string{
+			"username": tc.username, "email": tc.username + "@example.com", "password": testPassword,
+		})
+		expect(t, status, object, fiber.StatusCreated, "")
+		user := object["user"].(map[string]interface{})
+		if user["role"] != tc.role {
+			t.Errorf("%s: role = %v, want %s", tc.username, user["role"], tc.role)
+		}
+		if _, leaked := user["password"]; leaked {
+			t.Errorf("%s: password hash is part of the response", tc.username)
+		}
+	}
+}
+
+func TestJWKSOmitsSharedSecret(t *testing.T) {
+	app, _ := newTestApp()
+
+	status, object, _ := send(t, app, "GET", "/.well-known/jwks.json", "", nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	if keys, _ := object["keys"].([]interface{}); len(keys) != 0 {
+		t.Errorf("keys = %v, want none with an HS256 secret", object["keys"])
+	}
+}
+
+func TestConcurrentRegistrationsMakeOneAdmin(t *testing.T) {
+	app, repos := newTestApp()
+
+	const accounts = 8
+	statuses := make([]int, accounts)
+	var wg sync.WaitGroup
+	for i := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i], _, _ = send(t, app, "POST", "/auth/register", "", map[string]string{
+				"username": fmt.Sprintf("user%d", i), "email": fmt.Sprintf("user%d@example.com", i), "password": testPassword,
+			})
+		}()
+	}
+	wg.Wait()
+
+	admins := 0
+	for i, status := range statuses {
+		if status != fiber.StatusCreated {
+			t.Fatalf("user%d: status = %d, want %d", i, status, fiber.StatusCreated)
+		}
+		user, err := repos.Users.FindByUsername(context.Background(), fmt.Sprintf("user%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Role == "admin" {
+			admins++
+		}
+	}
+	if admins != 1 {
+		t.Errorf("%d admins, want 1", admins)
+	}
+}
+
+func TestRegisterRejectsDuplicatesAndInvalidInput(t *testing.T) {
+	app, _ := newTestApp()
+	register(t, app, "alice")
+
+	for _, tc := range []struct {
+		name       string
+		body       map[string]string
+		wantStatus int
+		wantCode   string
+	}{
+		{"taken username", map[string]string{"username": "alice", "email": "other@example.com", "password": testPassword}, fiber.StatusConflict, apperror.CodeUsernameTaken},
+		{"taken email", map[string]string{"username": "alice2", "email": "alice@example.com", "password": testPassword}, fiber.StatusConflict, apperror.CodeEmailTaken},
+		{"short password", map[string]string{"username": "carol", "email": "carol@example.com", "password": "short"}, fiber.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"password over 72 bytes", map[string]string{"username": "carol", "email": "carol@example.com", "password": strings.Repeat("pass1", 15)}, fiber.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"missing email", map[string]string{"username": "carol", "password": testPassword}, fiber.StatusUnprocessableEntity, apperror.CodeValidation},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, object, _ := send(t, app, "POST", "/auth/register", "", tc.body)
+			expect(t, status, object, tc.wantStatus, tc.wantCode)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	app, _ := newTestApp()
+	register(t, app, "alice")
+
+	status, object, _ := send(t, app, "POST", "/auth/login", "", map[string]string{"username": "alice", "password": testPassword})
+	expect(t, status, object, fiber.StatusOK, "")
+	if object["token"] == "" || object["refresh_token"] == "" {
+		t.Fatalf("login response without tokens: %v", object)
+	}
+
+	for _, body := range []map[string]string{
+		{"username": "alice", "password": "wrong-password"},
+		{"username": "nobody", "password": testPassword},
+	} {
+		status, object, _ := send(t, app, "POST", "/auth/login", "", body)
+		expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeInvalidCredentials)
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	app, _ := newTestApp()
+
+	for _, tc := range []struct {
+		header   string
+		wantCode string
+	}{
+		{"", apperror.CodeTokenMissing},
+		{"Token abc", apperror.CodeTokenMalformed},
+		{"Bearer not-a-jwt", apperror.CodeTokenInvalid},
+	} {
+		status, object, _ := sendWithHeader(t, app, "GET", "/products", tc.header, nil)
+		expect(t, status, object, fiber.StatusUnauthorized, tc.wantCode)
+	}
+}
+
+func TestRefreshRotatesTokensAndDetectsReuse(t *testing.T) {
+	app, _ := newTestApp()
+	accessToken, refreshToken := register(t, app, "alice")
+
+	status, object, _ := send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": refreshToken})
+	expect(t, status, object, fiber.StatusOK, "")
+	rotated := object["refresh_token"].(string)
+	if rotated == refreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	// Presenting the old token again revokes the whole session.
+	status, object, _ = send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": refreshToken})
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeRefreshTokenReused)
+
+	status, object, _ = send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": rotated})
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+	status, object, _ = send(t, app, "GET", "/products", accessToken, nil)
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	app, _ := newTestApp()
+	accessToken, refreshToken := register(t, app, "alice")
+
+	status, object, _ := send(t, app, "GET", "/products", accessToken, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+
+	status, object, _ = send(t, app, "POST", "/auth/logout", "", map[string]string{"refresh_token": refreshToken})
+	expect(t, status, object, fiber.StatusNoContent, "")
+	status, object, _ = send(t, app, "GET", "/products", accessToken, nil)
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+
+	// Logging out twice, or with an unknown token, is not an error.
+	for _, token := range []string{refreshToken, "unknown"} {
+		status, object, _ = send(t, app, "POST", "/auth/logout", "", map[string]string{"refresh_token": token})
+		expect(t, status, object, fiber.StatusNoContent, "")
+	}
+
+	status, object, _ = send(t, app, "POST", "/auth/logout", "", map[string]string{})
+	expect(t, status, object, fiber.StatusBadRequest, apperror.CodeBadRequest)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/buildinfo"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler serves the probe and build information routes.
+// None of them require authentication, and none are cached.
+type HealthHandler struct {
+	health *services.HealthService
+}
+
+// NewHealthHandler creates a HealthHandler.
+func NewHealthHandler(health *services.HealthService) *HealthHandler {
+	return &HealthHandler{health: health}
+}
+
+// Healthz handles the liveness probe. It succeeds as long as the process can answer
+// HTTP requests; a failing database must not get the process restarted.
+func (h *HealthHandler) Healthz(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz handles the readiness probe: 200 when the instance should receive traffic,
+// 503 with the failed checks otherwise (database unreachable, pending migrations or shutting down).
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	readiness := h.health.Ready(c.UserContext())
+	if !readiness.Ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(readiness)
+	}
+	return c.Status(fiber.StatusOK).JSON(readiness)
+}
+
+// Version returns the version, git commit, build time and Go version of the running binary.
+func (h *HealthHandler) Version(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(buildinfo.Get())
+}
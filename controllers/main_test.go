@@ -0,0 +1,120 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/mail"
+	"github.com/anpsniper/test3-bayu-be/ratelimit"
+	"github.com/anpsniper/test3-bayu-be/repository"
+	"github.com/anpsniper/test3-bayu-be/routes"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The handler tests run the real routes, middlewares and services against the
+// in-memory repositories, so they need neither MySQL nor any other database.
+
+const testPassword = "password123"
+
+func TestMain(m *testing.M) {
+	if err := jwtkeys.LoadKeys(config.JWT{Algorithm: jwtkeys.AlgHS256, Secret: "test-secret-that-is-long-enough-for-hs256"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestApp returns an app wired exactly like main.go, but backed by fresh in-memory repositories.
+func newTestApp() (*fiber.App, *repository.Repositories) {
+	repos := repository.NewMemory()
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	cfg, err := config.Default(config.EnvTest)
+	if err != nil {
+		panic(err)
+	}
+	accounts := services.NewAccountService(repos.Users, repos.Sessions, repos.Tokens, mail.NewLogMailer(), cfg.Account)
+	twoFactor := services.NewTwoFactorService(repos.Users, repos.RecoveryCodes, cfg.TwoFactor)
+	oidc := services.NewOIDCService(repos.Users, repos.Identities, cfg.OIDC)
+	routes.SetupRoutes(app, repos, services.NewHealthService(repos.Health), &ratelimit.Limits{}, accounts, twoFactor, oidc)
+	return app, repos
+}
+
+// send performs a request with an optional bearer token and JSON body and decodes the JSON response.
+// Responses that are not JSON objects (e.g. 204 No Content or a list) are returned in raw.
+func send(t *testing.T, app *fiber.App, method, path, token string, body interface{}) (status int, object map[string]interface{}, raw []byte) {
+	t.Helper()
+	authorization := ""
+	if token != "" {
+		authorization = "Bearer " + token
+	}
+	return sendWithHeader(t, app, method, path, authorization, body)
+}
+
+// sendWithHeader is send with a verbatim Authorization header (none if empty).
+func sendWithHeader(t *testing.T, app *fiber.App, method, path, authorization string, body interface{}) (status int, object map[string]interface{}, raw []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if authorization != "" {
+		req.Header.Set(fiber.HeaderAuthorization, authorization)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, err = io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object = map[string]interface{}{}
+	_ = json.Unmarshal(raw, &object)
+	return resp.StatusCode, object, raw
+}
+
+// expect fails the test unless the response has the wanted status and, for errors, problem code.
+func expect(t *testing.T, status int, object map[string]interface{}, wantStatus int, wantCode string) {
+	t.Helper()
+	if status != wantStatus {
+		t.Fatalf("status = %d, want %d (body %v)", status, wantStatus, object)
+	}
+	if wantCode != "" && object["code"] != wantCode {
+		t.Fatalf("code = %v, want %s", object["code"], wantCode)
+	}
+}
+
+// register creates an account and returns its access and refresh tokens.
+// The first account registered in an app is an admin; later ones are regular users.
+func register(t *testing.T, app *fiber.App, username string) (accessToken, refreshToken string) {
+	t.Helper()
+	status, object, _ := send(t, app, "POST", "/auth/register", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": testPassword,
+	})
+	expect(t, status, object, fiber.StatusCreated, "")
+	return object["token"].(string), object["refresh_token"].(string)
+}
+
+// userID returns the ID of the user in a response body.
+func userID(object map[string]interface{}) int {
+	return int(object["ID"].(float64))
+}
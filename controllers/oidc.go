@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"      // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// oidcStateCookie holds the state of a login through an identity provider
+// between the redirect to the provider and the callback.
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler serves the /auth/oidc routes, which log users in through
+// OpenID Connect identity providers instead of with a password.
+type OIDCHandler struct {
+	oidc *services.OIDCService
+	auth *services.AuthService
+}
+
+// NewOIDCHandler creates an OIDCHandler.
+func NewOIDCHandler(oidc *services.OIDCService, auth *services.AuthService) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc, auth: auth}
+}
+
+// Providers lists the identity providers users can log in with, for a login page.
+func (h *OIDCHandler) Providers(c *fiber.Ctx) error {
+	providers := []fiber.Map{}
+	for _, name := range h.oidc.Providers() {
+		providers = append(providers, fiber.Map{"name": name, "login_url": "/auth/oidc/" + name + "/login"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"providers": providers})
+}
+
+// Login redirects the browser to the identity provider, having stored the state
+// of the login in a cookie that only comes back to the callback.
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	provider := c.Params("provider")
+	login, err := h.oidc.Start(c.UserContext(), provider)
+	if err != nil {
+		return err
+	}
+
+	// Lax, not Strict: the callback is a navigation from the provider's site.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    login.State,
+		Path:     "/auth/oidc/" + provider,
+		MaxAge:   int(login.ExpiresIn.Seconds()),
+		Secure:   h.oidc.SecureCookies(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(login.URL, fiber.StatusFound)
+}
+
+// Callback is where the identity provider sends the browser back to. It completes
+// the login and returns the tokens of the new session, like POST /auth/login.
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	provider := c.Params("provider")
+	query := new(dto.OIDCCallbackQuery)
+	if err := c.QueryParser(query); err != nil {
+		return apperror.BadRequest(apperror.CodeBadRequest, "Invalid query parameters")
+	}
+	state := c.Cookies(oidcStateCookie)
+
+	// The state works once, whatever the outcome.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/auth/oidc/" + provider,
+		Expires:  time.Unix(0, 0),
+		Secure:   h.oidc.SecureCookies(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	user, err := h.oidc.Finish(c.UserContext(), provider, state, query)
+	if err != nil {
+		return err
+	}
+	tokens, challenge, err := h.auth.FederatedLogin(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+	return loginResponse(c, tokens, challenge)
+}
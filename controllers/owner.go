@@ -1,86 +1,108 @@
 package controllers
 
 import (
-	"github.com/anpsniper/test3-bayu-be/database" // Adjust import path to your module name
-	"github.com/anpsniper/test3-bayu-be/models"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services"   // Adjust import path to your module name
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm" // Import gorm for error checking like ErrRecordNotFound
 )
 
-// CreateOwner handles creating a new owner.
-func CreateOwner(c *fiber.Ctx) error {
-	owner := new(models.Owner)
+// OwnerHandler serves the /owners routes.
+type OwnerHandler struct {
+	owners *services.OwnerService
+}
 
-	if err := c.BodyParser(owner); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON: " + err.Error()})
-	}
+// NewOwnerHandler creates an OwnerHandler.
+func NewOwnerHandler(owners *services.OwnerService) *OwnerHandler {
+	return &OwnerHandler{owners: owners}
+}
 
-	result := database.DB.Create(&owner)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create owner: " + result.Error.Error()})
+// CreateOwner handles creating a new owner.
+func (h *OwnerHandler) CreateOwner(c *fiber.Ctx) error {
+	createRequest := new(dto.CreateOwnerRequest)
+	if err := bindAndValidate(c, createRequest); err != nil {
+		return err
 	}
 
+	owner, err := h.owners.Create(c.UserContext(), createRequest)
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusCreated).JSON(owner)
 }
 
-// GetOwners handles fetching all owners.
-func GetOwners(c *fiber.Ctx) error {
-	var owners []models.Owner
-	database.DB.Find(&owners)
-	return c.Status(fiber.StatusOK).JSON(owners)
+// ownerListOptions lists the fields GetOwners can be sorted by.
+var ownerListOptions = pagination.Options{
+	SortFields: map[string]string{
+		"id":         "id",
+		"owner_name": "owner_name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+}
+
+// GetOwners handles fetching owners, one page at a time.
+// Supports the parameters described in pagination.Request and the
+// owner_name_prefix / owner_name_contains filters.
+func (h *OwnerHandler) GetOwners(c *fiber.Ctx) error {
+	req, err := pagination.ParseRequest(c, ownerListOptions)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.OwnerFilter{
+		NamePrefix:   c.Query("owner_name_prefix"),
+		NameContains: c.Query("owner_name_contains"),
+	}
+	owners, result, err := h.owners.List(c.UserContext(), filter, req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(pagination.NewPage(c, req, owners, result))
 }
 
 // GetOwnerByID handles fetching a single owner by ID.
-func GetOwnerByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var owner models.Owner
-
-	result := database.DB.First(&owner, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Owner not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve owner: " + result.Error.Error()})
+func (h *OwnerHandler) GetOwnerByID(c *fiber.Ctx) error {
+	owner, err := h.owners.Get(c.UserContext(), c.Params("id"), false)
+	if err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(owner)
 }
 
-// UpdateOwner handles updating an existing owner.
-func UpdateOwner(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var owner models.Owner
-
-	result := database.DB.First(&owner, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Owner not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find owner: " + result.Error.Error()})
+// GetOwnerProducts handles fetching the products linked to an owner
+// through the products_owners join table.
+func (h *OwnerHandler) GetOwnerProducts(c *fiber.Ctx) error {
+	owner, err := h.owners.Get(c.UserContext(), c.Params("id"), true)
+	if err != nil {
+		return err
 	}
 
-	if err := c.BodyParser(&owner); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON: " + err.Error()})
+	return c.Status(fiber.StatusOK).JSON(owner.Products)
+}
+
+// UpdateOwner handles updating an existing owner.
+// Only the fields present in the request body are changed.
+func (h *OwnerHandler) UpdateOwner(c *fiber.Ctx) error {
+	updateRequest := new(dto.UpdateOwnerRequest)
+	if err := bindAndValidate(c, updateRequest); err != nil {
+		return err
 	}
 
-	database.DB.Save(&owner)
+	owner, err := h.owners.Update(c.UserContext(), c.Params("id"), updateRequest)
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(owner)
 }
 
 // DeleteOwner handles deleting an owner by ID.
-func DeleteOwner(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var owner models.Owner
-
-	result := database.DB.First(&owner, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Owner not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find owner: " + result.Error.Error()})
+func (h *OwnerHandler) DeleteOwner(c *fiber.Ctx) error {
+	if err := h.owners.Delete(c.UserContext(), c.Params("id")); err != nil {
+		return err
 	}
-
-	database.DB.Delete(&owner)
 	return c.SendStatus(fiber.StatusNoContent) // 204 No Content for successful deletion
 }
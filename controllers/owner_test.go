@@ -0,0 +1,70 @@
+package controllers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// createOwner creates an owner and returns its ID.
+func createOwner(t *testing.T, app *fiber.App, token, name string) int {
+	t.Helper()
+	status, object, _ := send(t, app, "POST", "/owners", token, map[string]string{"owner_name": name})
+	expect(t, status, object, fiber.StatusCreated, "")
+	return int(object["ID"].(float64))
+}
+
+func TestOwnerCRUD(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+
+	status, object, _ := send(t, app, "POST", "/owners", token, map[string]string{"owner_name": ""})
+	expect(t, status, object, fiber.StatusUnprocessableEntity, apperror.CodeValidation)
+
+	id := createOwner(t, app, token, "Ann")
+	path := fmt.Sprintf("/owners/%d", id)
+
+	status, object, _ = send(t, app, "PUT", path, token, map[string]string{"owner_name": "Annie"})
+	expect(t, status, object, fiber.StatusOK, "")
+	if object["owner_name"] != "Annie" {
+		t.Fatalf("owner_name = %v, want Annie", object["owner_name"])
+	}
+
+	status, object, _ = send(t, app, "GET", path, token, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	if object["owner_name"] != "Annie" {
+		t.Fatalf("owner_name = %v after reload, want Annie", object["owner_name"])
+	}
+
+	status, object, _ = send(t, app, "DELETE", path, token, nil)
+	expect(t, status, object, fiber.StatusNoContent, "")
+	status, object, _ = send(t, app, "GET", path, token, nil)
+	expect(t, status, object, fiber.StatusNotFound, apperror.CodeOwnerNotFound)
+}
+
+func TestGetOwnersFilters(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+	for _, name := range []string{"Ann", "Anna", "Ben", "100%"} {
+		createOwner(t, app, token, name)
+	}
+
+	for _, tc := range []struct {
+		query     string
+		wantTotal int
+	}{
+		{"", 4},
+		{"?owner_name_prefix=ann", 2},
+		{"?owner_name_contains=n", 3},
+		{"?owner_name_contains=%25", 1}, // LIKE wildcards match literally
+	} {
+		status, object, _ := send(t, app, "GET", "/owners"+tc.query, token, nil)
+		expect(t, status, object, fiber.StatusOK, "")
+		if int(object["total"].(float64)) != tc.wantTotal {
+			t.Errorf("%q: total = %v, want %d", tc.query, object["total"], tc.wantTotal)
+		}
+	}
+}
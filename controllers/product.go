@@ -1,86 +1,118 @@
 package controllers
 
 import (
-	"github.com/anpsniper/test3-bayu-be/database" // Adjust import path to your module name
-	"github.com/anpsniper/test3-bayu-be/models"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services"   // Adjust import path to your module name
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm" // Import gorm for error checking like ErrRecordNotFound
 )
 
-// CreateProduct handles creating a new product.
-func CreateProduct(c *fiber.Ctx) error {
-	product := new(models.Product)
+// ProductHandler serves the /products routes.
+type ProductHandler struct {
+	products *services.ProductService
+}
 
-	if err := c.BodyParser(product); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON: " + err.Error()})
-	}
+// NewProductHandler creates a ProductHandler.
+func NewProductHandler(products *services.ProductService) *ProductHandler {
+	return &ProductHandler{products: products}
+}
 
-	result := database.DB.Create(&product)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create product: " + result.Error.Error()})
+// CreateProduct handles creating a new product.
+func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
+	createRequest := new(dto.CreateProductRequest)
+	if err := bindAndValidate(c, createRequest); err != nil {
+		return err
 	}
 
+	product, err := h.products.Create(c.UserContext(), createRequest)
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusCreated).JSON(product)
 }
 
-// GetProducts handles fetching all products.
-func GetProducts(c *fiber.Ctx) error {
-	var products []models.Product
-	database.DB.Find(&products)
-	return c.Status(fiber.StatusOK).JSON(products)
+// includeOwners reports whether the client asked for the owners with ?include=owners.
+func includeOwners(c *fiber.Ctx) bool {
+	return c.Query("include") == "owners"
+}
+
+// productListOptions lists the fields GetProducts can be sorted by.
+var productListOptions = pagination.Options{
+	SortFields: map[string]string{
+		"id":            "id",
+		"product_name":  "product_name",
+		"product_brand": "product_brand",
+		"created_date":  "created_date",
+	},
+	DefaultSort: "id",
 }
 
-// GetProductByID handles fetching a single product by ID.
-func GetProductByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var product models.Product
-
-	result := database.DB.First(&product, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve product: " + result.Error.Error()})
+// GetProducts handles fetching products, one page at a time.
+// Besides the pagination and sorting parameters described in pagination.Request, it supports these filters:
+//
+//	product_brand           Exact brand match
+//	product_name_prefix     Product name starts with the value
+//	product_name_contains   Product name contains the value
+//	created_from            created_date on or after (RFC 3339 or YYYY-MM-DD)
+//	created_to              created_date before (RFC 3339 or YYYY-MM-DD)
+func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
+	req, err := pagination.ParseRequest(c, productListOptions)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(product)
-}
+	filter := repository.ProductFilter{
+		Brand:         c.Query("product_brand"),
+		NamePrefix:    c.Query("product_name_prefix"),
+		NameContains:  c.Query("product_name_contains"),
+		IncludeOwners: includeOwners(c),
+	}
+	if filter.CreatedFrom, err = pagination.QueryTime(c, "created_from"); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = pagination.QueryTime(c, "created_to"); err != nil {
+		return err
+	}
 
-// UpdateProduct handles updating an existing product.
-func UpdateProduct(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var product models.Product
-
-	result := database.DB.First(&product, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find product: " + result.Error.Error()})
+	products, result, err := h.products.List(c.UserContext(), filter, req)
+	if err != nil {
+		return err
 	}
+	return c.Status(fiber.StatusOK).JSON(pagination.NewPage(c, req, products, result))
+}
 
-	if err := c.BodyParser(&product); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON: " + err.Error()})
+// GetProductByID handles fetching a single product by its UUID.
+// Owners are included only when the client asks for them with ?include=owners.
+func (h *ProductHandler) GetProductByID(c *fiber.Ctx) error {
+	product, err := h.products.Get(c.UserContext(), c.Params("uuid"), includeOwners(c))
+	if err != nil {
+		return err
 	}
 
-	database.DB.Save(&product)
 	return c.Status(fiber.StatusOK).JSON(product)
 }
 
-// DeleteProduct handles deleting a product by ID.
-func DeleteProduct(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var product models.Product
-
-	result := database.DB.First(&product, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to find product: " + result.Error.Error()})
+// UpdateProduct handles updating an existing product, identified by its UUID.
+// Only the fields present in the request body are changed; the UUID never changes.
+func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
+	updateRequest := new(dto.UpdateProductRequest)
+	if err := bindAndValidate(c, updateRequest); err != nil {
+		return err
 	}
 
-	database.DB.Delete(&product)
+	product, err := h.products.Update(c.UserContext(), c.Params("uuid"), updateRequest)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(product)
+}
+
+// DeleteProduct handles deleting a product by its UUID.
+func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
+	if err := h.products.Delete(c.UserContext(), c.Params("uuid")); err != nil {
+		return err
+	}
 	return c.SendStatus(fiber.StatusNoContent) // 204 No Content for successful deletion
 }
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/dto" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AddProductOwner handles linking an owner to a product (POST /products/:uuid/owners/:ownerId).
+// Linking an owner that is already attached is a no-op. Responds with the product and its owners.
+func (h *ProductHandler) AddProductOwner(c *fiber.Ctx) error {
+	product, err := h.products.AddOwner(c.UserContext(), c.Params("uuid"), c.Params("ownerId"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(product)
+}
+
+// RemoveProductOwner handles unlinking an owner from a product (DELETE /products/:uuid/owners/:ownerId).
+// Only the products_owners row is removed; the owner itself is kept.
+func (h *ProductHandler) RemoveProductOwner(c *fiber.Ctx) error {
+	if err := h.products.RemoveOwner(c.UserContext(), c.Params("uuid"), c.Params("ownerId")); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent) // 204 No Content for successful unlinking
+}
+
+// ReplaceProductOwners handles replacing the whole set of owners of a product (PUT /products/:uuid/owners).
+// Expects a body like {"owner_ids": [1, 2, 3]} (see dto.ReplaceProductOwnersRequest); an empty list removes every owner.
+// If any of the owner IDs does not exist, nothing is changed and 404 is returned.
+func (h *ProductHandler) ReplaceProductOwners(c *fiber.Ctx) error {
+	replaceRequest := new(dto.ReplaceProductOwnersRequest)
+	if err := bindAndValidate(c, replaceRequest); err != nil {
+		return err
+	}
+
+	product, err := h.products.ReplaceOwners(c.UserContext(), c.Params("uuid"), replaceRequest.OwnerIDs)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(product)
+}
@@ -0,0 +1,209 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// createProduct creates a product and returns its UUID.
+func createProduct(t *testing.T, app *fiber.App, token, name, brand string) string {
+	t.Helper()
+	status, object, _ := send(t, app, "POST", "/products", token, map[string]string{"product_name": name, "product_brand": brand})
+	expect(t, status, object, fiber.StatusCreated, "")
+	return object["uuid"].(string)
+}
+
+func TestProductCRUD(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+
+	publicID := createProduct(t, app, token, "Phone", "Acme")
+
+	status, object, _ := send(t, app, "GET", "/products/"+publicID, token, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	if object["product_name"] != "Phone" || object["product_brand"] != "Acme" {
+		t.Fatalf("unexpected product: %v", object)
+	}
+
+	// Only the fields in the body change; the UUID is not updatable.
+	status, object, _ = send(t, app, "PUT", "/products/"+publicID, token, map[string]string{"product_name": "Smartphone", "uuid": "changed"})
+	expect(t, status, object, fiber.StatusOK, "")
+	if object["product_name"] != "Smartphone" || object["product_brand"] != "Acme" || object["uuid"] != publicID {
+		t.Fatalf("unexpected product after update: %v", object)
+	}
+
+	status, object, _ = send(t, app, "PUT", "/products/"+publicID, token, map[string]string{"product_name": "  "})
+	expect(t, status, object, fiber.StatusUnprocessableEntity, apperror.CodeValidation)
+
+	status, object, _ = send(t, app, "DELETE", "/products/"+publicID, token, nil)
+	expect(t, status, object, fiber.StatusNoContent, "")
+	status, object, _ = send(t, app, "GET", "/products/"+publicID, token, nil)
+	expect(t, status, object, fiber.StatusNotFound, apperror.CodeProductNotFound)
+}
+
+func TestProductNotFound(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+
+	status, object, _ := send(t, app, "GET", "/products/00000000-0000-0000-0000-000000000000", token, nil)
+	expect(t, status, object, fiber.StatusNotFound, apperror.CodeProductNotFound)
+	for _, id := range []string{"1", "not-a-uuid", "1%20OR%201=1"} {
+		status, object, _ := send(t, app, "GET", "/products/"+id, token, nil)
+		expect(t, status, object, fiber.StatusBadRequest, apperror.CodeInvalidID)
+	}
+}
+
+func TestProductDeleteRequiresPermission(t *testing.T) {
+	app, _ := newTestApp()
+	adminToken, _ := register(t, app, "alice")
+	userToken, _ := register(t, app, "bob")
+	publicID := createProduct(t, app, userToken, "Phone", "Acme")
+
+	status, object, _ := send(t, app, "DELETE", "/products/"+publicID, userToken, nil)
+	expect(t, status, object, fiber.StatusForbidden, apperror.CodePermissionDenied)
+	status, object, _ = send(t, app, "DELETE", "/products/"+publicID, adminToken, nil)
+	expect(t, status, object, fiber.StatusNoContent, "")
+}
+
+func TestGetProductsFiltersAndPaginates(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+	for i, name := range []string{"Apple", "Apricot", "Banana", "Cherry", "avocado"} {
+		brand := "Fresh"
+		if i%2 == 1 {
+			brand = "Farm"
+		}
+		createProduct(t, app, token, name, brand)
+	}
+
+	for _, tc := range []struct {
+		query     string
+		wantNames []string
+		wantTotal int
+	}{
+		{"", []string{"Apple", "Apricot", "Banana", "Cherry", "avocado"}, 5},
+		{"?product_brand=Farm", []string{"Apricot", "Cherry"}, 2},
+		{"?product_name_prefix=ap", []string{"Apple", "Apricot"}, 2},
+		{"?product_name_contains=an", []string{"Banana"}, 1},
+		{"?sort=-product_name&limit=2", []string{"avocado", "Cherry"}, 5},
+		{"?sort=product_name&limit=2&page=3", []string{"avocado"}, 5},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			status, object, _ := send(t, app, "GET", "/products"+tc.query, token, nil)
+			expect(t, status, object, fiber.StatusOK, "")
+			if got := productNames(object); strings.Join(got, ",") != strings.Join(tc.wantNames, ",") {
+				t.Errorf("names = %v, want %v", got, tc.wantNames)
+			}
+			if int(object["total"].(float64)) != tc.wantTotal {
+				t.Errorf("total = %v, want %d", object["total"], tc.wantTotal)
+			}
+		})
+	}
+
+	status, object, _ := send(t, app, "GET", "/products?sort=secret", token, nil)
+	expect(t, status, object, fiber.StatusBadRequest, apperror.CodeInvalidSort)
+	status, object, _ = send(t, app, "GET", "/products?created_from=yesterday", token, nil)
+	expect(t, status, object, fiber.StatusBadRequest, apperror.CodeInvalidFilter)
+}
+
+func TestGetProductsCursorPagination(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+	for i := 1; i <= 5; i++ {
+		createProduct(t, app, token, fmt.Sprintf("Product %d", i), "Acme")
+	}
+
+	// Follow the next links to the end, then the prev links back to the start.
+	path := "/products?cursor=&limit=2&sort=-product_name"
+	pages := []string{}
+	for path != "" {
+		status, object, _ := send(t, app, "GET", path, token, nil)
+		expect(t, status, object, fiber.StatusOK, "")
+		pages = append(pages, strings.Join(productNames(object), ","))
+		path = linkPath(object, "next")
+		if len(pages) > 5 {
+			t.Fatal("next links do not end")
+		}
+	}
+	want := []string{"Product 5,Product 4", "Product 3,Product 2", "Product 1"}
+	if strings.Join(pages, " | ") != strings.Join(want, " | ") {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+
+	status, object, _ := send(t, app, "GET", "/products?cursor=garbage", token, nil)
+	expect(t, status, object, fiber.StatusBadRequest, apperror.CodeInvalidCursor)
+}
+
+func TestProductOwners(t *testing.T) {
+	app, _ := newTestApp()
+	token, _ := register(t, app, "alice")
+	publicID := createProduct(t, app, token, "Phone", "Acme")
+	first := createOwner(t, app, token, "Ann")
+	second := createOwner(t, app, token, "Ben")
+
+	status, object, _ := send(t, app, "POST", fmt.Sprintf("/products/%s/owners/%d", publicID, first), token, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	if got := ownerNames(object["owners"]); got != "Ann" {
+		t.Fatalf("owners after add = %s", got)
+	}
+
+	status, object, _ = send(t, app, "PUT", "/products/"+publicID+"/owners", token, map[string][]uint{"owner_ids": {uint(second), uint(first), uint(second)}})
+	expect(t, status, object, fiber.StatusOK, "")
+	if got := ownerNames(object["owners"]); got != "Ann,Ben" {
+		t.Fatalf("owners after replace = %s", got)
+	}
+
+	// An unknown owner leaves the links untouched.
+	status, object, _ = send(t, app, "PUT", "/products/"+publicID+"/owners", token, map[string][]uint{"owner_ids": {uint(first), 999}})
+	expect(t, status, object, fiber.StatusNotFound, apperror.CodeOwnerNotFound)
+	status, object, _ = send(t, app, "GET", "/products/"+publicID+"?include=owners", token, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	if got := ownerNames(object["owners"]); got != "Ann,Ben" {
+		t.Fatalf("owners after failed replace = %s", got)
+	}
+
+	status, object, _ = send(t, app, "DELETE", fmt.Sprintf("/products/%s/owners/%d", publicID, first), token, nil)
+	expect(t, status, object, fiber.StatusNoContent, "")
+	_, _, raw := send(t, app, "GET", fmt.Sprintf("/owners/%d/products", second), token, nil)
+	var products []map[string]interface{}
+	if err := json.Unmarshal(raw, &products); err != nil || len(products) != 1 || products[0]["uuid"] != publicID {
+		t.Fatalf("products of remaining owner = %s", raw)
+	}
+
+	status, object, _ = send(t, app, "POST", "/products/"+publicID+"/owners/abc", token, nil)
+	expect(t, status, object, fiber.StatusBadRequest, apperror.CodeInvalidID)
+}
+
+// productNames returns the product names of a list response, in order.
+func productNames(page map[string]interface{}) []string {
+	names := []string{}
+	for _, item := range page["data"].([]interface{}) {
+		names = append(names, item.(map[string]interface{})["product_name"].(string))
+	}
+	return names
+}
+
+// ownerNames joins the owner names of a product's owners field.
+func ownerNames(owners interface{}) string {
+	names := []string{}
+	list, _ := owners.([]interface{})
+	for _, item := range list {
+		names = append(names, item.(map[string]interface{})["owner_name"].(string))
+	}
+	return strings.Join(names, ",")
+}
+
+// linkPath returns the path and query of a page link, or "" if there is none.
+func linkPath(page map[string]interface{}, rel string) string {
+	link, _ := page["links"].(map[string]interface{})[rel].(string)
+	if i := strings.Index(link, "/products"); i >= 0 {
+		return link[i:]
+	}
+	return ""
+}
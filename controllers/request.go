@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/validation" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// bindAndValidate parses the JSON request body into req (a pointer to a dto struct)
+// and checks it against its validation rules.
+// It returns a 400 apperror for malformed JSON and a 422 apperror listing the invalid fields.
+func bindAndValidate(c *fiber.Ctx, req interface{}) error {
+	if err := c.BodyParser(req); err != nil {
+		return apperror.BadRequest(apperror.CodeBadRequest, "Request body is not valid JSON")
+	}
+	return validate(req)
+}
+
+// validate checks req (a pointer to a dto struct) against its validation rules,
+// returning a 422 apperror listing the invalid fields.
+func validate(req interface{}) error {
+	err := validation.Validate(req)
+	var validationErr *validation.Errors
+	if errors.As(err, &validationErr) {
+		return apperror.Validation(validationErr.Fields)
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// currentUserID returns the ID of the authenticated user, stored by JWTAuthRequired.
+func currentUserID(c *fiber.Ctx) (uint, error) {
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return 0, apperror.Unauthorized(apperror.CodeUnauthorized, "Missing authenticated user")
+	}
+	return userID, nil
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/apperror" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// refreshTokenRequest is the body of POST /auth/refresh and POST /auth/logout.
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// parseRefreshToken reads the refresh token from the request body.
+func parseRefreshToken(c *fiber.Ctx) (string, error) {
+	req := refreshTokenRequest{}
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return "", apperror.BadRequest(apperror.CodeBadRequest, "refresh_token is required")
+	}
+	return req.RefreshToken, nil
+}
+
+// Refresh handles exchanging a refresh token for a new token pair.
+// Each refresh token can be used exactly once: it is rotated on every call.
+// If an already-rotated token is presented again, the token was most likely
+// stolen, so the whole session is revoked.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	refreshToken, err := parseRefreshToken(c)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.auth.Refresh(c.UserContext(), refreshToken)
+	if err != nil {
+		return err
+	}
+
+	response := tokenResponse(tokens)
+	response["message"] = "Token refreshed successfully"
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Logout handles revoking the session (token family) that a refresh token belongs to.
+// Every access token and refresh token of that session stops working immediately.
+// Unknown tokens are ignored so that logging out is idempotent.
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	refreshToken, err := parseRefreshToken(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.auth.Logout(c.UserContext(), refreshToken); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent) // 204 No Content for successful logout
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/anpsniper/test3-bayu-be/dto"      // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TwoFactorHandler serves the routes under /auth/2fa with which authenticated users
+// manage their own two-factor authentication.
+type TwoFactorHandler struct {
+	twoFactor *services.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a TwoFactorHandler.
+func NewTwoFactorHandler(twoFactor *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor}
+}
+
+// Status reports whether two-factor authentication is enabled and how many recovery codes are left.
+func (h *TwoFactorHandler) Status(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	enabled, left, err := h.twoFactor.Status(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"enabled": enabled, "recovery_codes_left": left})
+}
+
+// Enroll starts adding an authenticator app: it returns a new secret as an otpauth:// URI
+// and as a QR code (a PNG data URI) to scan. Nothing changes for logins until Confirm.
+func (h *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := h.twoFactor.Enroll(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	// The response holds the secret, so it must not be cached anywhere.
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Scan the QR code with your authenticator app, then confirm with a code at POST /auth/2fa/confirm",
+		"secret":      enrollment.Secret,
+		"otpauth_uri": enrollment.URI,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode),
+	})
+}
+
+// Confirm enables two-factor authentication with a code from the enrolled app,
+// and returns the recovery codes, which are never shown again.
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	return h.recoveryCodes(c, h.twoFactor.Confirm, "Two-factor authentication enabled; keep these recovery codes somewhere safe")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, given a code from the app.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	return h.recoveryCodes(c, h.twoFactor.RegenerateRecoveryCodes, "New recovery codes generated; the previous ones no longer work")
+}
+
+// recoveryCodes runs generate with the code of the request and returns the recovery codes it creates.
+func (h *TwoFactorHandler) recoveryCodes(c *fiber.Ctx, generate func(ctx context.Context, userID uint, code string) ([]string, error), message string) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	req := new(dto.TwoFactorCodeRequest)
+	if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	codes, err := generate(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": message, "recovery_codes": codes})
+}
+
+// Disable turns two-factor authentication off, given a code from the app or a recovery code.
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	req := new(dto.TwoFactorCodeRequest)
+	if err := bindAndValidate(c, req); err != nil {
+		return err
+	}
+
+	if err := h.twoFactor.Disable(c.UserContext(), userID, req.Code); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
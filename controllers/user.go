@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/services"   // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserHandler serves the /users routes.
+type UserHandler struct {
+	users *services.UserService
+}
+
+// NewUserHandler creates a UserHandler.
+func NewUserHandler(users *services.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+// userListOptions lists the fields GetUsers can be sorted by.
+var userListOptions = pagination.Options{
+	SortFields: map[string]string{
+		"id":         "id",
+		"username":   "username",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+}
+
+// GetUsers handles fetching users, one page at a time.
+// Supports the parameters described in pagination.Request and the
+// username_prefix and role filters.
+func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
+	req, err := pagination.ParseRequest(c, userListOptions)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.UserFilter{
+		UsernamePrefix: c.Query("username_prefix"),
+		Role:           c.Query("role"),
+	}
+	users, result, err := h.users.List(c.UserContext(), filter, req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(pagination.NewPage(c, req, users, result))
+}
+
+// GetUserByID handles fetching a single user by ID.
+func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
+	user, err := h.users.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// UpdateUser handles updating an existing user's username, email and/or password.
+// Only the fields present in the request body are changed. Users may only update their
+// own account unless they have the PermUsersManage permission (see services.UserService.Update).
+func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
+	actorID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	// Only the fields present in the body are validated; a new password must follow the password policy.
+	updateRequest := new(dto.UpdateUserRequest)
+	if err := bindAndValidate(c, updateRequest); err != nil {
+		return err
+	}
+
+	user, err := h.users.Update(c.UserContext(), actorID, c.Params("id"), updateRequest)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// UpdateUserRole handles changing the role of a user (PUT /users/:id/role).
+// Expects a body like {"role": "admin"}. The route is guarded by the PermUsersRoles permission.
+// The new role takes effect when the user's access token is next refreshed.
+func (h *UserHandler) UpdateUserRole(c *fiber.Ctx) error {
+	roleRequest := new(dto.UpdateUserRoleRequest)
+	if err := bindAndValidate(c, roleRequest); err != nil {
+		return err
+	}
+
+	user, err := h.users.UpdateRole(c.UserContext(), c.Params("id"), roleRequest.Role)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// DeleteUser handles soft deleting a user by ID.
+// gorm.Model's DeletedAt field makes GORM set a deletion timestamp instead of removing the row.
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	actorID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.users.Delete(c.UserContext(), actorID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent) // 204 No Content for successful deletion
+}
@@ -0,0 +1,130 @@
+package controllers_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUsersManageOnlyTheirOwnAccount(t *testing.T) {
+	app, _ := newTestApp()
+	adminToken, _ := register(t, app, "alice")
+	bobToken, _ := register(t, app, "bob")
+	register(t, app, "carol")
+
+	status, object, _ := send(t, app, "GET", "/users?username_prefix=b", bobToken, nil)
+	expect(t, status, object, fiber.StatusOK, "")
+	bobID := userID(object["data"].([]interface{})[0].(map[string]interface{}))
+	carolPath := fmt.Sprintf("/users/%d", bobID+1)
+	bobPath := fmt.Sprintf("/users/%d", bobID)
+
+	for _, tc := range []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		body       map[string]string
+		wantStatus int
+		wantCode   string
+	}{
+		{"update another account", bobToken, "PUT", carolPath, map[string]string{"email": "x@example.com"}, fiber.StatusForbidden, apperror.CodePermissionDenied},
+		{"delete another account", bobToken, "DELETE", carolPath, nil, fiber.StatusForbidden, apperror.CodePermissionDenied},
+		{"change own role", bobToken, "PUT", bobPath + "/role", map[string]string{"role": "admin"}, fiber.StatusForbidden, apperror.CodePermissionDenied},
+		{"take a username", bobToken, "PUT", bobPath, map[string]string{"username": "carol"}, fiber.StatusConflict, apperror.CodeUsernameTaken},
+		{"take an email", bobToken, "PUT", bobPath, map[string]string{"email": "carol@example.com"}, fiber.StatusConflict, apperror.CodeEmailTaken},
+		{"password over 72 bytes", bobToken, "PUT", bobPath, map[string]string{"password": strings.Repeat("pass1", 15), "current_password": testPassword}, fiber.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"update own email", bobToken, "PUT", bobPath, map[string]string{"email": "bobby@example.com"}, fiber.StatusOK, ""},
+		{"admin updates another account", adminToken, "PUT", carolPath, map[string]string{"username": "caroline"}, fiber.StatusOK, ""},
+		{"unknown role", adminToken, "PUT", carolPath + "/role", map[string]string{"role": "root"}, fiber.StatusBadRequest, apperror.CodeBadRequest},
+		{"unknown user", adminToken, "GET", "/users/999", nil, fiber.StatusNotFound, apperror.CodeUserNotFound},
+		{"SQL as user ID", adminToken, "GET", "/users/1=1", nil, fiber.StatusBadRequest, apperror.CodeInvalidID},
+		{"update by SQL", adminToken, "PUT", "/users/1%20OR%201=1", map[string]string{"username": "mallory"}, fiber.StatusBadRequest, apperror.CodeInvalidID},
+		{"delete by SQL", adminToken, "DELETE", "/users/1%20OR%201=1", nil, fiber.StatusBadRequest, apperror.CodeInvalidID},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body interface{}
+			if tc.body != nil {
+				body = tc.body
+			}
+			status, object, _ := send(t, app, tc.method, tc.path, tc.token, body)
+			expect(t, status, object, tc.wantStatus, tc.wantCode)
+		})
+	}
+}
+
+func TestChangePasswordRequiresCurrentPassword(t *testing.T) {
+	app, _ := newTestApp()
+	register(t, app, "alice")
+	bobToken, bobRefresh := register(t, app, "bob")
+	bobPath := "/users/2"
+
+	status, object, _ := send(t, app, "PUT", bobPath, bobToken, map[string]string{"password": "new-password1", "current_password": "wrong"})
+	expect(t, status, object, fiber.StatusForbidden, apperror.CodeWrongPassword)
+
+	status, object, _ = send(t, app, "PUT", bobPath, bobToken, map[string]string{"password": "new-password1", "current_password": testPassword})
+	expect(t, status, object, fiber.StatusOK, "")
+
+	// Every session logged in with the old password is over.
+	status, object, _ = send(t, app, "GET", bobPath, bobToken, nil)
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+	status, object, _ = send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": bobRefresh})
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+
+	status, object, _ = send(t, app, "POST", "/auth/login", "", map[string]string{"username": "bob", "password": "new-password1"})
+	expect(t, status, object, fiber.StatusOK, "")
+}
+
+func TestAdminChangingOwnPasswordNeedsCurrentPassword(t *testing.T) {
+	app, _ := newTestApp()
+	adminToken, _ := register(t, app, "alice")
+	register(t, app, "bob")
+
+	status, object, _ := send(t, app, "PUT", "/users/1", adminToken, map[string]string{"password": "new-password1"})
+	expect(t, status, object, fiber.StatusForbidden, apperror.CodeWrongPassword)
+	status, object, _ = send(t, app, "PUT", "/users/1", adminToken, map[string]string{"password": "new-password1", "current_password": "wrong"})
+	expect(t, status, object, fiber.StatusForbidden, apperror.CodeWrongPassword)
+
+	// Resetting someone else's password still doesn't.
+	status, object, _ = send(t, app, "PUT", "/users/2", adminToken, map[string]string{"password": "new-password1"})
+	expect(t, status, object, fiber.StatusOK, "")
+
+	status, object, _ = send(t, app, "PUT", "/users/1", adminToken, map[string]string{"password": "new-password1", "current_password": testPassword})
+	expect(t, status, object, fiber.StatusOK, "")
+}
+
+func TestRoleChangeAppliesOnRefresh(t *testing.T) {
+	app, _ := newTestApp()
+	adminToken, _ := register(t, app, "alice")
+	bobToken, bobRefresh := register(t, app, "bob")
+
+	status, object, _ := send(t, app, "PUT", "/users/2/role", adminToken, map[string]string{"role": "admin"})
+	expect(t, status, object, fiber.StatusOK, "")
+
+	// The old access token still carries the old permissions.
+	status, object, _ = send(t, app, "PUT", "/users/1/role", bobToken, map[string]string{"role": "user"})
+	expect(t, status, object, fiber.StatusForbidden, apperror.CodePermissionDenied)
+
+	status, object, _ = send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": bobRefresh})
+	expect(t, status, object, fiber.StatusOK, "")
+	status, object, _ = send(t, app, "PUT", "/users/1/role", object["token"].(string), map[string]string{"role": "user"})
+	expect(t, status, object, fiber.StatusOK, "")
+}
+
+func TestDeletedUserCannotRefresh(t *testing.T) {
+	app, _ := newTestApp()
+	register(t, app, "alice")
+	bobToken, bobRefresh := register(t, app, "bob")
+
+	status, object, _ := send(t, app, "DELETE", "/users/2", bobToken, nil)
+	expect(t, status, object, fiber.StatusNoContent, "")
+
+	status, object, _ = send(t, app, "GET", "/users", bobToken, nil)
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+
+	status, object, _ = send(t, app, "POST", "/auth/refresh", "", map[string]string{"refresh_token": bobRefresh})
+	expect(t, status, object, fiber.StatusUnauthorized, apperror.CodeSessionRevoked)
+}
@@ -2,55 +2,149 @@ package database
 
 import (
 	"fmt"
-	"log"
-	"os" // Used to get environment variables
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings" // Used to inspect the SQLite DSN
 
-	"github.com/joho/godotenv" // For loading .env files if called independently
-	"gorm.io/driver/mysql"     // MySQL driver for GORM
-	"gorm.io/gorm"             // GORM ORM library
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/logging"
+	"github.com/anpsniper/test3-bayu-be/metrics"
+	"github.com/anpsniper/test3-bayu-be/tracing"
+
+	"gorm.io/driver/mysql"    // MySQL driver for GORM
+	"gorm.io/driver/postgres" // PostgreSQL driver for GORM
+	"gorm.io/driver/sqlite"   // SQLite driver for GORM (local development and tests)
+	"gorm.io/gorm"            // GORM ORM library
+)
+
+// Supported values of DB_DRIVER.
+const (
+	DriverMySQL    = "mysql" // Default
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 // DB is the global database connection instance that other packages can use.
 var DB *gorm.DB
 
-// ConnectDB establishes the connection to the MySQL database.
-func ConnectDB() {
-	// Attempt to load .env file if environment variables aren't already set.
-	// This makes the ConnectDB function more robust if called directly for testing
-	// or in scenarios where main.go might not have loaded them yet.
-	if os.Getenv("DB_USER") == "" { // Simple check to see if DB_USER is set
-		if err := godotenv.Load(); err != nil {
-			log.Println("Warning: Could not load .env from database package, assuming already loaded by main.")
-		}
+// ConnectDB establishes the connection to the database described by cfg
+// (mysql, postgres or sqlite, see config.Database).
+//
+// cfg.URL, if set, is passed to the driver as-is. Otherwise the connection string
+// is built from the host, port, user, password and database name (see DSN).
+//
+// GORM logs through slog (see logging.GormLogger): failed statements as errors and
+// statements slower than cfg.SlowQueryThreshold as warnings.
+func ConnectDB(cfg config.Database) error {
+	dialector, err := Dialector(cfg.Driver, DSN(cfg))
+	if err != nil {
+		return err
 	}
 
-	// Retrieve database credentials from environment variables.
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME") // This should be "db_sniper" as per your request
-
-	// Construct the Data Source Name (DSN) string for MySQL.
-	// `parseTime=True` is essential for GORM to correctly handle `time.Time` fields.
-	// `loc=Local` ensures time values are interpreted in the local timezone.
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser,
-		dbPassword,
-		dbHost,
-		dbPort,
-		dbName,
-	)
-
-	var err error
 	// Open a connection to the database using GORM.
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(dialector, &gorm.Config{Logger: logging.NewGormLogger(cfg.SlowQueryThreshold)})
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+
+	// Time every statement and export the connection pool statistics on /metrics.
+	// The pool is labelled with the database name (the driver when only DATABASE_URL is set).
+	dbName := cfg.Name
+	if cfg.URL != "" || dbName == "" {
+		dbName = cfg.Driver
+	}
+	if err := DB.Use(metrics.NewGormPlugin(dbName)); err != nil {
+		return fmt.Errorf("registering database metrics: %w", err)
+	}
+	// Record a span for every statement, under the span of the request that ran it.
+	if err := DB.Use(tracing.NewGormPlugin()); err != nil {
+		return fmt.Errorf("registering database tracing: %w", err)
+	}
+
+	slog.Info("Connected to the database", "driver", cfg.Driver)
+	return nil
+}
+
+// Close closes the connection pool of DB. It is called on shutdown, after the
+// HTTP server has finished serving in-flight requests.
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		// If the connection fails, log a fatal error and exit the application.
-		log.Fatalf("❌ Failed to connect to database: %v", err)
+		return err
 	}
+	return sqlDB.Close()
+}
 
-	// Log a success message if the connection is established.
-	fmt.Println("Connected to the database successfully! ✅")
+// DSN returns the driver-specific connection string for cfg: cfg.URL if set,
+// otherwise one built from the individual settings.
+func DSN(cfg config.Database) string {
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+
+	switch cfg.Driver {
+	case DriverMySQL:
+		// `parseTime=True` is essential for GORM to correctly handle `time.Time` fields.
+		// `loc=Local` ensures time values are interpreted in the local timezone.
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			cfg.User, cfg.Password, cfg.Host, portOr(cfg.Port, 3306), cfg.Name,
+		)
+	case DriverPostgres:
+		// A URL rather than key=value pairs, so that spaces, quotes and the like in the
+		// password or any other setting are escaped instead of breaking up the DSN.
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(cfg.User, cfg.Password),
+			Host:   net.JoinHostPort(cfg.Host, portOr(cfg.Port, 5432)),
+			Path:   "/" + cfg.Name,
+		}
+		if cfg.SSLMode != "" {
+			dsn.RawQuery = url.Values{"sslmode": {cfg.SSLMode}}.Encode()
+		}
+		return dsn.String()
+	case DriverSQLite:
+		return cfg.Name + ".db"
+	}
+	return ""
+}
+
+// Dialector returns the GORM dialector for the given driver and connection string.
+func Dialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(withForeignKeys(dsn)), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected mysql, postgres or sqlite)", driver)
+	}
+}
+
+// withForeignKeys turns on foreign key enforcement for a SQLite DSN.
+// SQLite ignores foreign keys unless asked to on every connection, so without this
+// the products_owners constraints would only be enforced on MySQL and PostgreSQL.
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
+
+// portOr returns port as a string, or fallback if it is 0 (not configured).
+func portOr(port, fallback int) string {
+	if port == 0 {
+		port = fallback
+	}
+	return strconv.Itoa(port)
 }
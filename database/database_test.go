@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/database"
+)
+
+func TestPostgresDSNEscapesSettings(t *testing.T) {
+	cfg := config.Database{
+		Driver:   database.DriverPostgres,
+		Host:     "db.internal",
+		User:     "app user",
+		Password: `p@ss word' sslmode=disable\/?#`,
+		Name:     "shop data",
+		SSLMode:  "verify-full",
+	}
+
+	dsn, err := url.Parse(database.DSN(cfg))
+	if err != nil {
+		t.Fatalf("DSN %q: %v", database.DSN(cfg), err)
+	}
+	password, _ := dsn.User.Password()
+	for _, tc := range []struct{ name, got, want string }{
+		{"scheme", dsn.Scheme, "postgres"},
+		{"user", dsn.User.Username(), cfg.User},
+		{"password", password, cfg.Password},
+		{"host", dsn.Hostname(), cfg.Host},
+		{"port", dsn.Port(), "5432"},
+		{"database", dsn.Path, "/" + cfg.Name},
+		{"sslmode", dsn.Query().Get("sslmode"), cfg.SSLMode},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+	if len(dsn.Query()) != 1 {
+		t.Errorf("query = %q, want sslmode only", dsn.RawQuery)
+	}
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest is the body of POST /api-keys.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,max=20,dive,required"` // Permissions such as "products:read"
+	ExpiresAt *time.Time `json:"expires_at"`                                            // RFC 3339; omit for a key that never expires
+}
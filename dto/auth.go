@@ -0,0 +1,69 @@
+// Package dto contains the request bodies accepted by the API.
+// They are kept separate from the GORM models so that clients can only set the
+// fields a handler expects, and so that each field can carry validation rules
+// (see the validation package) without affecting the database schema.
+package dto
+
+import (
+	"github.com/anpsniper/test3-bayu-be/models"
+)
+
+// RegisterRequest is the body of POST /auth/register.
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,alphanum"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,password"` // Checked against the configured password policy
+}
+
+// ToModel converts the request into a new user. The password is copied as-is
+// and must be hashed by the caller before saving.
+func (r *RegisterRequest) ToModel() *models.User {
+	return &models.User{
+		Username: r.Username,
+		Email:    r.Email,
+		Password: r.Password,
+	}
+}
+
+// LoginRequest is the body of POST /auth/login.
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// VerifyEmailRequest is the body of POST /auth/verify (GET /auth/verify takes the token from the query string).
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ForgotPasswordRequest is the body of POST /auth/forgot-password.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ResetPasswordRequest is the body of POST /auth/reset-password.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"` // Checked against the configured password policy
+}
+
+// TwoFactorCodeRequest is the body of the /auth/2fa routes that change the two-factor settings:
+// a code from the authenticator app (or, to disable it, a recovery code).
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// TwoFactorVerifyRequest is the body of POST /auth/2fa/verify.
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"` // Returned by POST /auth/login
+	Code           string `json:"code" validate:"required,max=32"`     // From the authenticator app, or a recovery code
+}
+
+// OIDCCallbackQuery holds the query parameters an identity provider redirects back to
+// GET /auth/oidc/:provider/callback with: a code on success, an error otherwise.
+type OIDCCallbackQuery struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
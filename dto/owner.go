@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"github.com/anpsniper/test3-bayu-be/models"
+)
+
+// CreateOwnerRequest is the body of POST /owners.
+type CreateOwnerRequest struct {
+	OwnerName string `json:"owner_name" validate:"notblank,max=255"`
+}
+
+// ToModel converts the request into a new owner.
+func (r *CreateOwnerRequest) ToModel() *models.Owner {
+	return &models.Owner{OwnerName: r.OwnerName}
+}
+
+// UpdateOwnerRequest is the body of PUT /owners/:id. Only provided fields are changed.
+type UpdateOwnerRequest struct {
+	OwnerName *string `json:"owner_name" validate:"omitnil,notblank,max=255"`
+}
+
+// Apply copies the provided fields onto owner.
+func (r *UpdateOwnerRequest) Apply(owner *models.Owner) {
+	if r.OwnerName != nil {
+		owner.OwnerName = *r.OwnerName
+	}
+}
@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models"
+)
+
+// CreateProductRequest is the body of POST /products.
+type CreateProductRequest struct {
+	ProductName  string     `json:"product_name" validate:"notblank,max=255"`
+	ProductBrand string     `json:"product_brand" validate:"max=255"`
+	CreatedDate  *time.Time `json:"created_date"` // Optional; the current time is used when omitted
+}
+
+// ToModel converts the request into a new product.
+func (r *CreateProductRequest) ToModel() *models.Product {
+	product := &models.Product{
+		ProductName:  r.ProductName,
+		ProductBrand: r.ProductBrand,
+	}
+	if r.CreatedDate != nil {
+		product.CreatedDate = *r.CreatedDate
+	}
+	return product
+}
+
+// UpdateProductRequest is the body of PUT /products/:uuid.
+// Pointer fields tell "not provided" apart from "set to empty"; only provided fields are changed.
+type UpdateProductRequest struct {
+	ProductName  *string    `json:"product_name" validate:"omitnil,notblank,max=255"`
+	ProductBrand *string    `json:"product_brand" validate:"omitnil,max=255"`
+	CreatedDate  *time.Time `json:"created_date"`
+}
+
+// Apply copies the provided fields onto product.
+func (r *UpdateProductRequest) Apply(product *models.Product) {
+	if r.ProductName != nil {
+		product.ProductName = *r.ProductName
+	}
+	if r.ProductBrand != nil {
+		product.ProductBrand = *r.ProductBrand
+	}
+	if r.CreatedDate != nil {
+		product.CreatedDate = *r.CreatedDate
+	}
+}
+
+// ReplaceProductOwnersRequest is the body of PUT /products/:uuid/owners.
+type ReplaceProductOwnersRequest struct {
+	OwnerIDs []uint `json:"owner_ids" validate:"required"` // An empty list removes every owner
+}
@@ -0,0 +1,15 @@
+package dto
+
+// UpdateUserRequest is the body of PUT /users/:id.
+// Pointer fields tell "not provided" apart from "set to empty"; only provided fields are changed.
+type UpdateUserRequest struct {
+	Username        *string `json:"username" validate:"omitnil,min=3,max=50,alphanum"`
+	Email           *string `json:"email" validate:"omitnil,email,max=255"`
+	Password        *string `json:"password" validate:"omitnil,password"`
+	CurrentPassword string  `json:"current_password"` // Required to change your own password without PermUsersManage
+}
+
+// UpdateUserRoleRequest is the body of PUT /users/:id/role.
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
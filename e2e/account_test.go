@@ -0,0 +1,131 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestEmailVerification(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+
+	h.run([]apiCase{
+		{
+			name: "registration", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "dave", "email": "dave@example.com", "password": "password123"},
+			wantStatus: fiber.StatusCreated,
+			check: func(t *testing.T, r response) {
+				if r.JSON["token"] == nil {
+					t.Errorf("no token, although verification is not required")
+				}
+				if verified := r.JSON["user"].(map[string]interface{})["email_verified_at"]; verified != nil {
+					t.Errorf("email_verified_at = %v, want null", verified)
+				}
+			},
+		},
+	})
+	token := h.outbox.token("dave@example.com")
+	if token == "" {
+		t.Fatal("no verification link sent to dave@example.com")
+	}
+
+	h.run([]apiCase{
+		{name: "missing token", method: "GET", path: "/auth/verify", wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "altered token", method: "GET", path: "/auth/verify?token=" + token + "x", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenInvalid, check: field("instance", "/auth/verify")},
+		{name: "access token", method: "POST", path: "/auth/verify", body: map[string]string{"token": h.token("bob")}, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenInvalid},
+		{name: "link followed", method: "GET", path: "/auth/verify?token=" + token, wantStatus: fiber.StatusOK},
+		{name: "link followed again", method: "POST", path: "/auth/verify", body: map[string]string{"token": token}, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenInvalid},
+	})
+
+	dave, err := h.repos.Users.FindByEmail(context.Background(), "dave@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dave.EmailVerifiedAt == nil {
+		t.Fatal("dave's address is not verified")
+	}
+
+	// A correctly signed link that has already expired.
+	expired, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+		"purpose": models.PurposeVerifyEmail, "user_id": dave.ID, "email": dave.Email, "jti": "expired",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.run([]apiCase{
+		{name: "expired link", method: "GET", path: "/auth/verify?token=" + expired, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenExpired},
+	})
+}
+
+func TestRequiredEmailVerification(t *testing.T) {
+	h := newHarnessWith(t, func(h *harness) {
+		h.account.RequireVerifiedEmail = true
+	}, "users.yaml")
+
+	daveLogin := map[string]string{"username": "dave", "password": "password123"}
+	h.run([]apiCase{
+		{
+			name: "registration", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "dave", "email": "dave@example.com", "password": "password123"},
+			wantStatus: fiber.StatusCreated,
+			check: func(t *testing.T, r response) {
+				if r.JSON["token"] != nil {
+					t.Errorf("token issued before the address is verified")
+				}
+			},
+		},
+		{name: "login before verifying", method: "POST", path: "/auth/login", body: daveLogin, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeEmailNotVerified},
+		{name: "verified fixture accounts", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusOK},
+	})
+	if n := h.outbox.count("dave@example.com"); n != 2 {
+		t.Fatalf("%d emails sent to dave@example.com, want 2 (registration and login)", n)
+	}
+
+	// The login sent a new link, which replaced the one sent on registration.
+	h.run([]apiCase{
+		{name: "link followed", method: "GET", path: "/auth/verify?token=" + h.outbox.token("dave@example.com"), wantStatus: fiber.StatusOK},
+		{name: "login after verifying", method: "POST", path: "/auth/login", body: daveLogin, wantStatus: fiber.StatusOK},
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+	bobSession := "Bearer " + h.token("bob")
+
+	h.run([]apiCase{
+		{name: "unknown address", method: "POST", path: "/auth/forgot-password", body: map[string]string{"email": "mallory@example.com"}, wantStatus: fiber.StatusAccepted},
+		{name: "invalid address", method: "POST", path: "/auth/forgot-password", body: map[string]string{"email": "bob"}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "first request", method: "POST", path: "/auth/forgot-password", body: map[string]string{"email": "bob@example.com"}, wantStatus: fiber.StatusAccepted},
+	})
+	if n := h.outbox.count("mallory@example.com"); n != 0 {
+		t.Fatalf("%d emails sent to an address without an account", n)
+	}
+	first := h.outbox.token("bob@example.com")
+
+	h.run([]apiCase{
+		{name: "second request", method: "POST", path: "/auth/forgot-password", body: map[string]string{"email": "bob@example.com"}, wantStatus: fiber.StatusAccepted},
+	})
+	second := h.outbox.token("bob@example.com")
+	if first == "" || second == "" || first == second {
+		t.Fatalf("reset links %q and %q, want two different ones", first, second)
+	}
+
+	newPassword := map[string]string{"token": second, "password": "new-password1"}
+	h.run([]apiCase{
+		{name: "replaced link", method: "POST", path: "/auth/reset-password", body: map[string]string{"token": first, "password": "new-password1"}, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenInvalid},
+		{name: "weak password", method: "POST", path: "/auth/reset-password", body: map[string]string{"token": second, "password": "short"}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "new password", method: "POST", path: "/auth/reset-password", body: newPassword, wantStatus: fiber.StatusOK},
+		{name: "link used again", method: "POST", path: "/auth/reset-password", body: newPassword, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeAccountTokenInvalid},
+		{name: "sessions logged out", header: bobSession, method: "GET", path: "/users/{user:bob}", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeSessionRevoked},
+		{name: "old password", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "login with the new password", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "new-password1"}, wantStatus: fiber.StatusOK},
+	})
+}
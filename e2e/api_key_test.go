@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// createAPIKey creates an API key for a fixture user and returns it.
+func (h *harness) createAPIKey(username string, scopes ...string) string {
+	h.t.Helper()
+	r := h.do("POST", "/api-keys", "Bearer "+h.token(username), map[string]interface{}{"name": "script", "scopes": scopes})
+	key, _ := r.JSON["key"].(string)
+	if r.Status != fiber.StatusCreated || key == "" {
+		h.t.Fatalf("create API key = %d %s", r.Status, r.Body)
+	}
+	return key
+}
+
+func TestAPIKeys(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+	bob := "Bearer " + h.token("bob")
+
+	var key string
+	h.run([]apiCase{
+		{name: "unknown scope", header: bob, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "ci", "scopes": []string{"products:fly"}}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "no scopes", header: bob, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "ci", "scopes": []string{}}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "scope beyond the role", header: bob, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "ci", "scopes": []string{models.PermProductsDelete}}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodePermissionDenied},
+		{name: "expiry in the past", header: bob, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "ci", "scopes": []string{models.PermProductsRead}, "expires_at": time.Now().Add(-time.Hour)}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{
+			name: "create", header: bob, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "ci", "scopes": []string{models.PermProductsRead}, "expires_at": time.Now().Add(time.Hour)}, wantStatus: fiber.StatusCreated,
+			check: func(t *testing.T, r response) {
+				key, _ = r.JSON["key"].(string)
+				apiKey, _ := r.JSON["api_key"].(map[string]interface{})
+				if !strings.HasPrefix(key, "ak_") || apiKey["prefix"] != key[:11] {
+					t.Errorf("key = %q, api_key = %v", key, apiKey)
+				}
+				if _, ok := apiKey["key_hash"]; ok {
+					t.Errorf("key hash in the response")
+				}
+				if r.Header.Get(fiber.HeaderCacheControl) != "no-store" {
+					t.Errorf("Cache-Control = %q, want no-store", r.Header.Get(fiber.HeaderCacheControl))
+				}
+			},
+		},
+	})
+
+	apiKey := "ApiKey " + key
+	h.run([]apiCase{
+		{name: "scope granted", header: apiKey, method: "GET", path: "/products", wantStatus: fiber.StatusOK},
+		{name: "scope not granted", header: apiKey, method: "POST", path: "/products", body: map[string]string{"product_name": "x"}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodePermissionDenied},
+		{name: "unknown key", header: apiKey + "x", method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeAPIKeyInvalid},
+		{name: "keys can't create keys", header: apiKey, method: "POST", path: "/api-keys", body: map[string]interface{}{"name": "more", "scopes": []string{models.PermProductsRead}}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeSessionRequired},
+		{name: "keys can't change the account", header: apiKey, method: "DELETE", path: "/users/{user:bob}", wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeSessionRequired},
+		{
+			name: "list", header: bob, method: "GET", path: "/api-keys", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if !strings.Contains(string(r.Body), `"name":"ci"`) || strings.Contains(string(r.Body), key) || strings.Contains(string(r.Body), `"last_used_at":null`) {
+					t.Errorf("list = %s, want the key used, without its value", r.Body)
+				}
+			},
+		},
+		{name: "list of another user", header: "Bearer " + h.token("carol"), method: "GET", path: "/api-keys", wantStatus: fiber.StatusOK, check: func(t *testing.T, r response) {
+			if string(r.Body) != "[]" {
+				t.Errorf("list = %s, want []", r.Body)
+			}
+		}},
+	})
+
+	// Revoking.
+	alice := h.createAPIKey("alice", models.PermUsersRead)
+	var aliceKeys []models.APIKey
+	if err := json.Unmarshal(h.do("GET", "/api-keys", "Bearer "+h.token("alice"), nil).Body, &aliceKeys); err != nil || len(aliceKeys) != 1 {
+		t.Fatalf("alice's keys = %v, %v", aliceKeys, err)
+	}
+	aliceKeyID := strconv.Itoa(int(aliceKeys[0].ID))
+	h.run([]apiCase{
+		{name: "malformed key ID", header: bob, method: "DELETE", path: "/api-keys/1=1", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "revoke another user's key", header: bob, method: "DELETE", path: "/api-keys/" + aliceKeyID, wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeAPIKeyNotFound},
+		{name: "other key still works", header: "ApiKey " + alice, method: "GET", path: "/users", wantStatus: fiber.StatusOK},
+		{name: "revoke", header: "Bearer " + h.token("alice"), method: "DELETE", path: "/api-keys/" + aliceKeyID, wantStatus: fiber.StatusOK},
+		{name: "revoked key", header: "ApiKey " + alice, method: "GET", path: "/users", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeAPIKeyRevoked},
+	})
+}
+
+func TestExpiredAPIKey(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+	bob, err := h.repos.Users.FindByUsername(context.Background(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "ak_expired"
+	hash := sha256.Sum256([]byte(key))
+	expired := time.Now().Add(-time.Minute)
+	err = h.repos.APIKeys.Create(context.Background(), &models.APIKey{
+		UserID: bob.ID, Name: "old", Prefix: key[:8], KeyHash: hex.EncodeToString(hash[:]),
+		Scopes: []string{models.PermProductsRead}, ExpiresAt: &expired,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.run([]apiCase{
+		{name: "expired key", header: "ApiKey " + key, method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeAPIKeyExpired},
+	})
+}
+
+func TestDeletingAnAccountRevokesItsKeys(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+	key := h.createAPIKey("carol", models.PermUsersRead)
+	h.run([]apiCase{
+		{name: "delete carol", as: "alice", method: "DELETE", path: "/users/{user:carol}", wantStatus: fiber.StatusNoContent},
+		{name: "key of the deleted account", header: "ApiKey " + key, method: "GET", path: "/users", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeAPIKeyInvalid},
+	})
+
+	keys, err := h.repos.APIKeys.ListByUser(context.Background(), h.users["carol"].ID)
+	if err != nil || len(keys) != 1 || !keys[0].Revoked() {
+		t.Errorf("carol's keys = %+v, %v, want one revoked key", keys, err)
+	}
+}
@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthFailures(t *testing.T) {
+	h := newHarness(t, "users.yaml", "catalog.json")
+
+	// A token signed with a different secret.
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": h.users["alice"].ID, "sid": 1, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("not-the-server-secret-not-the-server-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A correctly signed token that has already expired.
+	expired, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+		"user_id": h.users["alice"].ID, "sid": 1, "exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token whose session has been revoked (as after a logout).
+	session := &models.Session{UserID: h.users["alice"].ID}
+	if err := h.repos.Sessions.CreateSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := services.GenerateJWTToken(h.users["alice"], session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.repos.Sessions.RevokeSession(context.Background(), session.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	h.run([]apiCase{
+		{name: "missing header", method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenMissing},
+		{name: "not a bearer token", header: "Basic YWxpY2U6c2VjcmV0", method: "GET", path: "/owners", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenMalformed},
+		{name: "garbage token", header: "Bearer abc.def.ghi", method: "GET", path: "/users", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenInvalid, check: field("detail", "Invalid token")},
+		{name: "forged signature", header: "Bearer " + forged, method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenInvalid},
+		{name: "expired token", header: "Bearer " + expired, method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenExpired},
+		{name: "revoked session", header: "Bearer " + revoked, method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeSessionRevoked},
+		{name: "missing permission", as: "bob", method: "DELETE", path: "/products/{product:Phone}", wantStatus: fiber.StatusForbidden, wantCode: apperror.CodePermissionDenied},
+		{name: "managing another account", as: "bob", method: "DELETE", path: "/users/{user:carol}", wantStatus: fiber.StatusForbidden, wantCode: apperror.CodePermissionDenied},
+		{name: "wrong password", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "alice-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "unknown user", method: "POST", path: "/auth/login", body: map[string]string{"username": "mallory", "password": "bob-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "invalid refresh token", method: "POST", path: "/auth/refresh", body: map[string]string{"refresh_token": "nope"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeRefreshTokenInvalid},
+		{name: "valid token", as: "bob", method: "GET", path: "/products", wantStatus: fiber.StatusOK},
+	})
+}
+
+func TestRegistration(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+
+	h.run([]apiCase{
+		{
+			name: "duplicate username", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "bob", "email": "robert@example.com", "password": "password123"},
+			wantStatus: fiber.StatusConflict, wantCode: apperror.CodeUsernameTaken,
+		},
+		{
+			name: "duplicate email", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "robert", "email": "bob@example.com", "password": "password123"},
+			wantStatus: fiber.StatusConflict, wantCode: apperror.CodeEmailTaken,
+		},
+		{
+			name: "invalid fields", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "r", "email": "not-an-email", "password": "short"},
+			wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation,
+			check: func(t *testing.T, r response) {
+				if fields, _ := r.JSON["errors"].([]interface{}); len(fields) != 3 {
+					t.Errorf("errors = %v, want one per field", r.JSON["errors"])
+				}
+			},
+		},
+		{
+			name: "new account", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "dave", "email": "dave@example.com", "password": "password123"},
+			wantStatus: fiber.StatusCreated,
+			check: func(t *testing.T, r response) {
+				// Fixture accounts already exist, so the new one is not the first-user admin.
+				if role := r.JSON["user"].(map[string]interface{})["role"]; role != models.RoleUser {
+					t.Errorf("role = %v, want %s", role, models.RoleUser)
+				}
+			},
+		},
+		{
+			name: "login with the new account", method: "POST", path: "/auth/login",
+			body:       map[string]string{"username": "dave", "password": "password123"},
+			wantStatus: fiber.StatusOK,
+		},
+	})
+}
+
+func TestFirstRegistrationBecomesAdmin(t *testing.T) {
+	h := newHarness(t)
+
+	role := func(want string) func(t *testing.T, r response) {
+		return func(t *testing.T, r response) {
+			if role := r.JSON["user"].(map[string]interface{})["role"]; role != want {
+				t.Errorf("role = %v, want %s", role, want)
+			}
+		}
+	}
+	h.run([]apiCase{
+		{
+			name: "first account", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "dave", "email": "dave@example.com", "password": "password123"},
+			wantStatus: fiber.StatusCreated, check: role(models.RoleAdmin),
+		},
+		{
+			name: "second account", method: "POST", path: "/auth/register",
+			body:       map[string]string{"username": "erin", "email": "erin@example.com", "password": "password123"},
+			wantStatus: fiber.StatusCreated, check: role(models.RoleUser),
+		},
+	})
+}
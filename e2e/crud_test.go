@@ -0,0 +1,124 @@
+package e2e
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// field checks a top-level field of a JSON object response.
+func field(name string, want interface{}) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		if r.JSON[name] != want {
+			t.Errorf("%s = %v, want %v", name, r.JSON[name], want)
+		}
+	}
+}
+
+// names checks the names of the items of a list response (a page or a plain JSON array).
+func names(key string, want ...string) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		var items []map[string]interface{}
+		if data, ok := r.JSON["data"]; ok {
+			encoded, _ := json.Marshal(data)
+			_ = json.Unmarshal(encoded, &items)
+		} else {
+			_ = json.Unmarshal(r.Body, &items)
+		}
+		got := []string{}
+		for _, item := range items {
+			got = append(got, item[key].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestProductCRUD(t *testing.T) {
+	h := newHarness(t, "users.yaml", "catalog.json")
+
+	h.run([]apiCase{
+		{name: "list", as: "bob", method: "GET", path: "/products", wantStatus: fiber.StatusOK, check: names("product_name", "Phone", "Laptop", "Kettle")},
+		{name: "filter by brand", as: "bob", method: "GET", path: "/products?product_brand=Acme&sort=product_name", wantStatus: fiber.StatusOK, check: names("product_name", "Laptop", "Phone")},
+		{name: "filter by date", as: "bob", method: "GET", path: "/products?created_from=2024-02-01&created_to=2024-12-31", wantStatus: fiber.StatusOK, check: names("product_name", "Laptop", "Kettle")},
+		{name: "get by fixture UUID", as: "bob", method: "GET", path: "/products/5b1c1c0e-4f6c-4a4b-9a49-6a1f0e3f0c01", wantStatus: fiber.StatusOK, check: field("product_name", "Phone")},
+		{
+			name: "get with owners", as: "bob", method: "GET", path: "/products/{product:Phone}?include=owners", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if owners, _ := r.JSON["owners"].([]interface{}); len(owners) != 2 {
+					t.Errorf("owners = %v, want Ann and Ben", r.JSON["owners"])
+				}
+			},
+		},
+		{name: "create", as: "bob", method: "POST", path: "/products", body: map[string]string{"product_name": "Toaster", "product_brand": "Homely"}, wantStatus: fiber.StatusCreated, check: field("product_name", "Toaster")},
+		{name: "create without name", as: "bob", method: "POST", path: "/products", body: map[string]string{"product_brand": "Homely"}, wantStatus: fiber.StatusUnprocessableEntity, wantCode: apperror.CodeValidation},
+		{name: "update", as: "bob", method: "PUT", path: "/products/{product:Kettle}", body: map[string]string{"product_brand": "Steamy"}, wantStatus: fiber.StatusOK, check: field("product_brand", "Steamy")},
+		{name: "update keeps other fields", as: "bob", method: "GET", path: "/products/{product:Kettle}", wantStatus: fiber.StatusOK, check: field("product_name", "Kettle")},
+		{name: "replace owners", as: "bob", method: "PUT", path: "/products/{product:Kettle}/owners", body: map[string][]uint{"owner_ids": {3}}, wantStatus: fiber.StatusOK},
+		{name: "owner sees product", as: "bob", method: "GET", path: "/owners/{owner:Cleo}/products", wantStatus: fiber.StatusOK, check: names("product_name", "Kettle")},
+		{name: "unlink owner", as: "bob", method: "DELETE", path: "/products/{product:Phone}/owners/{owner:Ann}", wantStatus: fiber.StatusNoContent},
+		{name: "owner lost product", as: "bob", method: "GET", path: "/owners/{owner:Ann}/products", wantStatus: fiber.StatusOK, check: names("product_name")},
+		{name: "delete", as: "alice", method: "DELETE", path: "/products/{product:Laptop}", wantStatus: fiber.StatusNoContent},
+		{name: "deleted product is gone", as: "bob", method: "GET", path: "/products/{product:Laptop}", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeProductNotFound},
+	})
+
+	// Deletes are soft: the row is still there, with deleted_at set.
+	var count int64
+	h.db.Unscoped().Model(&models.Product{}).Where("product_name = ? AND deleted_at IS NOT NULL", "Laptop").Count(&count)
+	if count != 1 {
+		t.Errorf("soft-deleted Laptop rows = %d, want 1", count)
+	}
+}
+
+func TestOwnerAndUserCRUD(t *testing.T) {
+	h := newHarness(t, "users.yaml", "catalog.json")
+
+	h.run([]apiCase{
+		{name: "list owners", as: "bob", method: "GET", path: "/owners?owner_name_prefix=b", wantStatus: fiber.StatusOK, check: names("owner_name", "Ben")},
+		{name: "create owner", as: "bob", method: "POST", path: "/owners", body: map[string]string{"owner_name": "Dora"}, wantStatus: fiber.StatusCreated, check: field("owner_name", "Dora")},
+		{name: "rename owner", as: "bob", method: "PUT", path: "/owners/{owner:Ben}", body: map[string]string{"owner_name": "Benjamin"}, wantStatus: fiber.StatusOK, check: field("owner_name", "Benjamin")},
+		{name: "owner products", as: "bob", method: "GET", path: "/owners/{owner:Ben}/products", wantStatus: fiber.StatusOK, check: names("product_name", "Phone", "Laptop")},
+		{name: "delete owner", as: "alice", method: "DELETE", path: "/owners/{owner:Cleo}", wantStatus: fiber.StatusNoContent},
+		{name: "deleted owner is gone", as: "bob", method: "GET", path: "/owners/{owner:Cleo}", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeOwnerNotFound},
+
+		{name: "list users by role", as: "bob", method: "GET", path: "/users?role=admin", wantStatus: fiber.StatusOK, check: names("username", "alice")},
+		{name: "update own email", as: "bob", method: "PUT", path: "/users/{user:bob}", body: map[string]string{"email": "bobby@example.com"}, wantStatus: fiber.StatusOK, check: field("email", "bobby@example.com")},
+		{name: "admin changes role", as: "alice", method: "PUT", path: "/users/{user:carol}/role", body: map[string]string{"role": "admin"}, wantStatus: fiber.StatusOK, check: field("role", "admin")},
+		{name: "delete own account", as: "bob", method: "DELETE", path: "/users/{user:bob}", wantStatus: fiber.StatusNoContent},
+		{name: "deleted account can't log in", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "deleted account keeps its username", method: "POST", path: "/auth/register", body: map[string]string{"username": "bob", "email": "new-bob@example.com", "password": "password123"}, wantStatus: fiber.StatusConflict, wantCode: apperror.CodeUsernameTaken},
+		{name: "deleted account keeps its email", method: "POST", path: "/auth/register", body: map[string]string{"username": "newbob", "email": "bobby@example.com", "password": "password123"}, wantStatus: fiber.StatusConflict, wantCode: apperror.CodeEmailTaken},
+		{name: "rename to a deleted account's username", as: "carol", method: "PUT", path: "/users/{user:carol}", body: map[string]string{"username": "bob"}, wantStatus: fiber.StatusConflict, wantCode: apperror.CodeUsernameTaken},
+	})
+}
+
+func TestNotFound(t *testing.T) {
+	h := newHarness(t, "users.yaml", "catalog.json")
+
+	h.run([]apiCase{
+		{name: "unknown product", as: "bob", method: "GET", path: "/products/00000000-0000-0000-0000-000000000000", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeProductNotFound},
+		{name: "product by numeric ID", as: "bob", method: "GET", path: "/products/1", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "link to a malformed product ID", as: "bob", method: "POST", path: "/products/1=1/owners/{owner:Ben}", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "link a malformed owner ID", as: "bob", method: "POST", path: "/products/{product:Phone}/owners/1=1", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "unlink a malformed owner ID", as: "bob", method: "DELETE", path: "/products/{product:Phone}/owners/1%20OR%201=1", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "replace owners of a malformed product ID", as: "bob", method: "PUT", path: "/products/1/owners", body: map[string][]uint{"owner_ids": {1}}, wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "update unknown product", as: "bob", method: "PUT", path: "/products/00000000-0000-0000-0000-000000000000", body: map[string]string{"product_name": "x"}, wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeProductNotFound},
+		{name: "delete unknown product", as: "alice", method: "DELETE", path: "/products/00000000-0000-0000-0000-000000000000", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeProductNotFound},
+		{name: "link unknown owner", as: "bob", method: "POST", path: "/products/{product:Phone}/owners/999", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeOwnerNotFound},
+		{name: "replace with unknown owner", as: "bob", method: "PUT", path: "/products/{product:Phone}/owners", body: map[string][]uint{"owner_ids": {1, 999}}, wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeOwnerNotFound},
+		{name: "unknown owner", as: "bob", method: "GET", path: "/owners/999", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeOwnerNotFound},
+		{name: "non-numeric owner ID", as: "bob", method: "GET", path: "/owners/1%20OR%201=1", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "products of a non-numeric owner ID", as: "bob", method: "GET", path: "/owners/1=1/products", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "zero owner ID", as: "bob", method: "GET", path: "/owners/0/products", wantStatus: fiber.StatusBadRequest, wantCode: apperror.CodeInvalidID},
+		{name: "unknown user", as: "alice", method: "GET", path: "/users/999", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeUserNotFound},
+		{name: "unknown route", method: "GET", path: "/nope", wantStatus: fiber.StatusNotFound},
+	})
+}
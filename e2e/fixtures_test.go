@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures is the content of a fixture file in testdata, written as YAML or JSON
+// (picked by the file extension). Records refer to each other by name.
+type Fixtures struct {
+	Users    []UserFixture    `json:"users" yaml:"users"`
+	Owners   []OwnerFixture   `json:"owners" yaml:"owners"`
+	Products []ProductFixture `json:"products" yaml:"products"`
+}
+
+// UserFixture is an account. The password is given in plain text and hashed on load.
+// Its email address counts as verified.
+type UserFixture struct {
+	Username string `json:"username" yaml:"username"`
+	Email    string `json:"email" yaml:"email"`
+	Password string `json:"password" yaml:"password"`
+	Role     string `json:"role" yaml:"role"` // "user" if empty
+}
+
+// OwnerFixture is an owner, referenced by its name.
+type OwnerFixture struct {
+	Name string `json:"owner_name" yaml:"owner_name"`
+}
+
+// ProductFixture is a product, referenced by its name, with the names of its owners.
+type ProductFixture struct {
+	UUID        string     `json:"uuid" yaml:"uuid"` // Generated if empty
+	Name        string     `json:"product_name" yaml:"product_name"`
+	Brand       string     `json:"product_brand" yaml:"product_brand"`
+	CreatedDate *time.Time `json:"created_date" yaml:"created_date"`
+	Owners      []string   `json:"owners" yaml:"owners"`
+}
+
+// loadFixtures reads testdata/<name>.
+func loadFixtures(name string) (*Fixtures, error) {
+	path := filepath.Join("testdata", name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := &Fixtures{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, fixtures)
+	case ".json":
+		err = json.Unmarshal(content, fixtures)
+	default:
+		err = fmt.Errorf("unsupported fixture format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// insert stores the fixtures through the harness's repositories and records them by name.
+func (h *harness) insert(fixtures *Fixtures) error {
+	for _, f := range fixtures.Users {
+		hashedPassword, err := services.HashPassword(f.Password)
+		if err != nil {
+			return err
+		}
+		verifiedAt := time.Now()
+		user := &models.User{Username: f.Username, Email: f.Email, Password: hashedPassword, Role: f.Role, EmailVerifiedAt: &verifiedAt}
+		if user.Role == "" {
+			user.Role = models.RoleUser
+		}
+		if err := h.repos.Users.Create(context.Background(), user); err != nil {
+			return fmt.Errorf("user %s: %w", f.Username, err)
+		}
+		h.users[f.Username] = user
+	}
+
+	for _, f := range fixtures.Owners {
+		owner := &models.Owner{OwnerName: f.Name}
+		if err := h.repos.Owners.Create(context.Background(), owner); err != nil {
+			return fmt.Errorf("owner %s: %w", f.Name, err)
+		}
+		h.owners[f.Name] = owner
+	}
+
+	for _, f := range fixtures.Products {
+		product := &models.Product{UUID: f.UUID, ProductName: f.Name, ProductBrand: f.Brand}
+		if f.CreatedDate != nil {
+			product.CreatedDate = *f.CreatedDate
+		}
+		if err := h.repos.Products.Create(context.Background(), product); err != nil {
+			return fmt.Errorf("product %s: %w", f.Name, err)
+		}
+		for _, name := range f.Owners {
+			owner, ok := h.owners[name]
+			if !ok {
+				return fmt.Errorf("product %s: unknown owner %q", f.Name, name)
+			}
+			if err := h.repos.Products.AddOwner(context.Background(), product, owner); err != nil {
+				return err
+			}
+		}
+		h.products[f.Name] = product
+	}
+	return nil
+}
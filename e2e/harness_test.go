@@ -0,0 +1,317 @@
+// Package e2e runs the whole HTTP API (routes, middlewares, controllers, services and the
+// GORM repositories) against an in-memory SQLite database migrated with the real migrations.
+// Test data comes from the YAML/JSON fixtures in testdata, and access tokens are minted
+// directly with services.GenerateJWTToken, so tests don't depend on the login endpoints.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/database"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/mail"
+	"github.com/anpsniper/test3-bayu-be/migrations"
+	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/ratelimit"
+	"github.com/anpsniper/test3-bayu-be/repository"
+	"github.com/anpsniper/test3-bayu-be/routes"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	if err := jwtkeys.LoadKeys(config.JWT{Algorithm: jwtkeys.AlgHS256, Secret: "e2e-secret-that-is-long-enough-for-hs256"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// databaseCount gives every harness its own in-memory database.
+var databaseCount atomic.Int64
+
+// harness is one running API with its own database.
+type harness struct {
+	t      *testing.T
+	app    *fiber.App
+	db     *gorm.DB
+	repos  *repository.Repositories
+	health *services.HealthService
+
+	// Set before the routes are, see newHarnessWith.
+	limits    *ratelimit.Limits
+	account   config.Account
+	twoFactor config.TwoFactor
+	oidc      config.OIDC
+	outbox    *outbox // Every email the API sends
+
+	// Fixture records by name (username, owner name, product name).
+	users    map[string]*models.User
+	owners   map[string]*models.Owner
+	products map[string]*models.Product
+}
+
+// newHarness boots the app on a fresh, migrated SQLite database and loads the given fixture files.
+// Rate limits and the login lockout are off, and the account and two-factor settings are the test profile's.
+func newHarness(t *testing.T, fixtureFiles ...string) *harness {
+	t.Helper()
+	return newHarnessWith(t, nil, fixtureFiles...)
+}
+
+// newHarnessWith is newHarness where configure, if not nil, can change the limits,
+// account, two-factor and OIDC settings before the routes are set up.
+func newHarnessWith(t *testing.T, configure func(h *harness), fixtureFiles ...string) *harness {
+	t.Helper()
+
+	// A named shared-cache database lives as long as one of its connections is open,
+	// and is visible to every connection in GORM's pool (a plain ":memory:" is not).
+	dsn := fmt.Sprintf("file:e2e%d?mode=memory&cache=shared", databaseCount.Add(1))
+	dialector, err := database.Dialector(database.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &harness{
+		t:        t,
+		db:       db,
+		repos:    repository.NewGorm(db),
+		limits:   &ratelimit.Limits{},
+		outbox:   &outbox{},
+		users:    map[string]*models.User{},
+		owners:   map[string]*models.Owner{},
+		products: map[string]*models.Product{},
+	}
+	for _, name := range fixtureFiles {
+		fixtures, err := loadFixtures(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.insert(fixtures); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	defaults, err := config.Default(config.EnvTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.account = defaults.Account
+	h.twoFactor = defaults.TwoFactor
+	h.oidc = defaults.OIDC
+	if configure != nil {
+		configure(h)
+	}
+
+	h.app = fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	h.health = services.NewHealthService(h.repos.Health)
+	accounts := services.NewAccountService(h.repos.Users, h.repos.Sessions, h.repos.Tokens, h.outbox, h.account)
+	twoFactor := services.NewTwoFactorService(h.repos.Users, h.repos.RecoveryCodes, h.twoFactor)
+	oidc := services.NewOIDCService(h.repos.Users, h.repos.Identities, h.oidc)
+	routes.SetupRoutes(h.app, h.repos, h.health, h.limits, accounts, twoFactor, oidc)
+	return h
+}
+
+// outbox is a mail.Mailer keeping the messages instead of sending them.
+type outbox struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// linkToken matches the token of the links in the emails.
+var linkToken = regexp.MustCompile(`[?&]token=([^&\s]+)`)
+
+// token returns the token of the link in the last email sent to address, or "" if there is none.
+func (o *outbox) token(address string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].To == address {
+			if match := linkToken.FindStringSubmatch(o.messages[i].Text); match != nil {
+				return match[1]
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// count returns how many emails were sent to address.
+func (o *outbox) count(address string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := 0
+	for _, msg := range o.messages {
+		if msg.To == address {
+			n++
+		}
+	}
+	return n
+}
+
+// token starts a session for a fixture user and returns an access token for it.
+func (h *harness) token(username string) string {
+	h.t.Helper()
+	user, ok := h.users[username]
+	if !ok {
+		h.t.Fatalf("unknown fixture user %q", username)
+	}
+	session := &models.Session{UserID: user.ID}
+	if err := h.repos.Sessions.CreateSession(context.Background(), session); err != nil {
+		h.t.Fatal(err)
+	}
+	token, err := services.GenerateJWTToken(user, session.ID)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return token
+}
+
+// placeholder matches references to fixtures in request paths, e.g. "{product:Phone}".
+var placeholder = regexp.MustCompile(`\{(user|owner|product):([^}]+)\}`)
+
+// path replaces fixture references with IDs: {user:name} and {owner:name} become numeric IDs,
+// {product:name} becomes the product's UUID.
+func (h *harness) path(path string) string {
+	h.t.Helper()
+	return placeholder.ReplaceAllStringFunc(path, func(ref string) string {
+		match := placeholder.FindStringSubmatch(ref)
+		switch match[1] {
+		case "user":
+			if user, ok := h.users[match[2]]; ok {
+				return fmt.Sprint(user.ID)
+			}
+		case "owner":
+			if owner, ok := h.owners[match[2]]; ok {
+				return fmt.Sprint(owner.ID)
+			}
+		case "product":
+			if product, ok := h.products[match[2]]; ok {
+				return product.UUID
+			}
+		}
+		h.t.Fatalf("unknown fixture %s", ref)
+		return ""
+	})
+}
+
+// response is a decoded API response.
+type response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	JSON   map[string]interface{} // Empty unless the body is a JSON object
+}
+
+// do sends a request with the given Authorization header (none if empty) and optional JSON body.
+func (h *harness) do(method, path, authorization string, body interface{}) response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, h.path(path), reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if authorization != "" {
+		req.Header.Set(fiber.HeaderAuthorization, authorization)
+	}
+	return h.send(req)
+}
+
+// send sends a prepared request, e.g. one with cookies, and decodes the response.
+func (h *harness) send(req *http.Request) response {
+	h.t.Helper()
+
+	resp, err := h.app.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	result := response{Status: resp.StatusCode, Header: resp.Header, JSON: map[string]interface{}{}}
+	if result.Body, err = io.ReadAll(resp.Body); err != nil {
+		h.t.Fatal(err)
+	}
+	_ = json.Unmarshal(result.Body, &result.JSON)
+	return result
+}
+
+// apiCase is one row of a table-driven API test.
+type apiCase struct {
+	name   string
+	as     string // Fixture user whose token is sent; empty for none
+	header string // Verbatim Authorization header, used instead of as
+	method string
+	path   string // May contain fixture references, see harness.path
+	body   interface{}
+
+	wantStatus int
+	wantCode   string                         // Problem code of an error response
+	check      func(t *testing.T, r response) // Further assertions, optional
+}
+
+// run executes the cases in order against one harness, so later cases see earlier changes.
+func (h *harness) run(cases []apiCase) {
+	for _, tc := range cases {
+		h.t.Run(tc.name, func(t *testing.T) {
+			sub := *h
+			sub.t = t // Report failures against the subtest
+			authorization := tc.header
+			if authorization == "" && tc.as != "" {
+				authorization = "Bearer " + sub.token(tc.as)
+			}
+			r := sub.do(tc.method, tc.path, authorization, tc.body)
+			if r.Status != tc.wantStatus {
+				t.Fatalf("%s %s: status = %d, want %d (body %s)", tc.method, tc.path, r.Status, tc.wantStatus, r.Body)
+			}
+			if tc.wantCode != "" && r.JSON["code"] != tc.wantCode {
+				t.Fatalf("%s %s: code = %v, want %s", tc.method, tc.path, r.JSON["code"], tc.wantCode)
+			}
+			if tc.check != nil {
+				tc.check(t, r)
+			}
+		})
+	}
+}
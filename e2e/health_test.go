@@ -0,0 +1,77 @@
+package e2e
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/migrations"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// check checks one entry of the "checks" object of a readiness response.
+func check(name, want string) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		checks, _ := r.JSON["checks"].(map[string]interface{})
+		if checks[name] != want {
+			t.Errorf("checks.%s = %v, want %q", name, checks[name], want)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	h := newHarness(t)
+
+	h.run([]apiCase{
+		{name: "liveness", method: "GET", path: "/healthz", wantStatus: fiber.StatusOK, check: field("status", "ok")},
+		{name: "ready", method: "GET", path: "/readyz", wantStatus: fiber.StatusOK, check: field("status", "ready")},
+		{name: "version", method: "GET", path: "/version", wantStatus: fiber.StatusOK, check: field("go_version", runtime.Version())},
+	})
+
+	// Rolling back the last migration leaves the schema behind the binary.
+	migrator, err := migrations.New(h.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	h.run([]apiCase{
+		{name: "pending migrations", method: "GET", path: "/readyz", wantStatus: fiber.StatusServiceUnavailable, check: check("migrations", "1 pending migration(s)")},
+	})
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The probe only reads: a database without schema_migrations is not ready, and stays without it.
+	if err := h.db.Migrator().RenameTable("schema_migrations", "schema_migrations_old"); err != nil {
+		t.Fatal(err)
+	}
+	h.run([]apiCase{
+		{name: "no schema_migrations", method: "GET", path: "/readyz", wantStatus: fiber.StatusServiceUnavailable, check: check("migrations", "check failed")},
+	})
+	if h.db.Migrator().HasTable("schema_migrations") {
+		t.Error("readiness probe created schema_migrations")
+	}
+	if err := h.db.Migrator().RenameTable("schema_migrations_old", "schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once shutdown has begun the instance is no longer ready, but still alive.
+	h.health.StartDraining()
+	h.run([]apiCase{
+		{name: "draining", method: "GET", path: "/readyz", wantStatus: fiber.StatusServiceUnavailable, check: check("shutdown", "server is shutting down")},
+		{name: "alive while draining", method: "GET", path: "/healthz", wantStatus: fiber.StatusOK},
+	})
+
+	// A closed connection pool fails the database check.
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	h.run([]apiCase{
+		{name: "database down", method: "GET", path: "/readyz", wantStatus: fiber.StatusServiceUnavailable, check: check("database", "unreachable")},
+	})
+}
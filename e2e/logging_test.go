@@ -0,0 +1,134 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/logging"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// captureLogs makes the default logger write JSON to the returned buffer for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, config.Log{Level: "info", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// records decodes the JSON log lines with the given message.
+func records(t *testing.T, buf *bytes.Buffer, msg string) []map[string]interface{} {
+	t.Helper()
+	var found []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if record["msg"] == msg {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+func TestLogging(t *testing.T) {
+	buf := captureLogs(t)
+	h := newHarness(t, "users.yaml", "catalog.json")
+	// Every statement is "slow", so each one is logged.
+	h.db.Logger = logging.NewGormLogger(time.Nanosecond)
+
+	// send makes a request as bob with the given X-Request-ID (none if empty),
+	// and returns the ID echoed in the response.
+	send := func(path, requestID string) string {
+		t.Helper()
+		req := httptest.NewRequest("GET", h.path(path), nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+h.token("bob"))
+		buf.Reset() // Only keep what the request logs
+		if requestID != "" {
+			req.Header.Set(fiber.HeaderXRequestID, requestID)
+		}
+		resp, err := h.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get(fiber.HeaderXRequestID)
+	}
+
+	t.Run("access log", func(t *testing.T) {
+		if id := send("/products/{product:Phone}", "req-42"); id != "req-42" {
+			t.Fatalf("X-Request-ID = %q, want the client's ID echoed", id)
+		}
+		access := records(t, buf, "request")
+		if len(access) != 1 {
+			t.Fatalf("want one access log record, got %d:\n%s", len(access), buf)
+		}
+		want := map[string]interface{}{
+			"method":     "GET",
+			"route":      "/products/:uuid",
+			"status":     float64(fiber.StatusOK),
+			"user_id":    float64(h.users["bob"].ID),
+			"request_id": "req-42",
+		}
+		for key, value := range want {
+			if access[0][key] != value {
+				t.Errorf("%s = %v, want %v", key, access[0][key], value)
+			}
+		}
+		if _, ok := access[0]["latency_ms"].(float64); !ok {
+			t.Errorf("no latency_ms in %v", access[0])
+		}
+	})
+
+	t.Run("slow queries", func(t *testing.T) {
+		send("/products/{product:Phone}", "req-43")
+		slow := records(t, buf, "slow query")
+		if len(slow) == 0 {
+			t.Fatalf("no slow query logged:\n%s", buf)
+		}
+		for _, record := range slow {
+			if record["request_id"] != "req-43" {
+				t.Errorf("request_id = %v, want the request's ID", record["request_id"])
+			}
+			// The SQL keeps its placeholders; the product's UUID must not be logged.
+			if sql, _ := record["sql"].(string); strings.Contains(sql, h.products["Phone"].UUID) {
+				t.Errorf("bound value logged: %s", sql)
+			}
+		}
+	})
+
+	t.Run("unmatched route", func(t *testing.T) {
+		send("/does/not/exist", "")
+		access := records(t, buf, "request")
+		if len(access) != 1 || access[0]["status"] != float64(fiber.StatusNotFound) {
+			t.Fatalf("want one 404 access log record, got %v", access)
+		}
+		if _, ok := access[0]["route"]; ok {
+			t.Errorf("unmatched request logged with route %v", access[0]["route"])
+		}
+	})
+
+	t.Run("request IDs", func(t *testing.T) {
+		if id := send("/products", ""); id == "" {
+			t.Error("no X-Request-ID generated")
+		}
+		// A header that could forge log lines is replaced.
+		if id := send("/products", "evil\tid \"level\":\"ERROR\""); strings.Contains(id, "evil") {
+			t.Errorf("X-Request-ID = %q, want a generated ID", id)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exposes checks that the /metrics output contains every given series.
+// The metrics are global to the test binary, so only their presence is checked, not their values.
+func exposes(series ...string) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		for _, s := range series {
+			if !strings.Contains(string(r.Body), s) {
+				t.Errorf("/metrics has no %s", s)
+			}
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	h := newHarness(t, "users.yaml", "catalog.json")
+	if err := h.db.Use(metrics.NewGormPlugin("e2e")); err != nil {
+		t.Fatal(err)
+	}
+
+	h.run([]apiCase{
+		{name: "matched route", as: "bob", method: "GET", path: "/products/{product:Phone}", wantStatus: fiber.StatusOK},
+		{name: "unmatched route", method: "GET", path: "/does/not/exist", wantStatus: fiber.StatusNotFound},
+		{name: "failed login", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "wrong-password1"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "login", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusOK},
+		{name: "missing token", method: "GET", path: "/owners", wantStatus: fiber.StatusUnauthorized},
+		{
+			name: "scrape", method: "GET", path: "/metrics", wantStatus: fiber.StatusOK,
+			check: exposes(
+				`api_http_requests_total{method="GET",route="/products/:uuid",status="200"}`,
+				`api_http_requests_total{method="GET",route="unmatched",status="404"}`,
+				`api_http_request_duration_seconds_bucket{method="POST",route="/auth/login"`,
+				`api_auth_logins_total{result="success"}`,
+				`api_auth_logins_total{result="invalid_credentials"}`,
+				`api_auth_jwt_rejections_total{reason="token_missing"}`,
+				`api_db_query_duration_seconds_count{operation="query",table="products"}`,
+				`go_sql_open_connections{db_name="e2e"}`,
+			),
+		},
+	})
+}
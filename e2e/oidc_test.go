@@ -0,0 +1,316 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newOIDCHarness is newHarnessWith the users fixtures and the provider "corp" served by idp.
+func newOIDCHarness(t *testing.T, idp *oidcServer, configure func(h *harness)) *harness {
+	t.Helper()
+	return newHarnessWith(t, func(h *harness) {
+		h.oidc.Providers = []config.OIDCProvider{{Name: "corp", Issuer: idp.URL, ClientID: idp.clientID, ClientSecret: idp.clientSecret}}
+		if configure != nil {
+			configure(h)
+		}
+	}, "users.yaml")
+}
+
+// oidcFlow is a login through an identity provider, up to the redirect back to the API.
+type oidcFlow struct {
+	cookie   string // The state cookie, as the browser sends it back ("oidc_state=...")
+	callback string // Path and query the provider redirected to
+}
+
+// startOIDC starts a login through a provider and follows the redirect to it, which
+// redirects back at once.
+func (h *harness) startOIDC(provider string) oidcFlow {
+	h.t.Helper()
+	r := h.do("GET", "/auth/oidc/"+provider+"/login", "", nil)
+	if r.Status != fiber.StatusFound {
+		h.t.Fatalf("login = %d %s, want a redirect", r.Status, r.Body)
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(r.Header.Get(fiber.HeaderLocation))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		h.t.Fatalf("authorization = %d, %v", resp.StatusCode, err)
+	}
+	return oidcFlow{cookie: strings.SplitN(r.Header.Get(fiber.HeaderSetCookie), ";", 2)[0], callback: back.RequestURI()}
+}
+
+// finishOIDC follows the redirect back to the callback, with the given Cookie header (none if empty).
+func (h *harness) finishOIDC(callback, cookie string) response {
+	h.t.Helper()
+	req := httptest.NewRequest("GET", callback, nil)
+	if cookie != "" {
+		req.Header.Set(fiber.HeaderCookie, cookie)
+	}
+	return h.send(req)
+}
+
+// oidcLogin logs in through the provider "corp" as the user with the given claims.
+func (h *harness) oidcLogin(idp *oidcServer, claims jwt.MapClaims) response {
+	h.t.Helper()
+	idp.login(claims)
+	flow := h.startOIDC("corp")
+	return h.finishOIDC(flow.callback, flow.cookie)
+}
+
+// wantProblem fails the test unless r is an error response with the given status and code.
+func wantProblem(t *testing.T, r response, status int, code string) {
+	t.Helper()
+	if r.Status != status || r.JSON["code"] != code {
+		t.Fatalf("response = %d %s, want %d %s", r.Status, r.Body, status, code)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newOIDCServer(t)
+	h := newOIDCHarness(t, idp, nil)
+
+	h.run([]apiCase{
+		{
+			name: "providers", method: "GET", path: "/auth/oidc", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if !strings.Contains(string(r.Body), `{"login_url":"/auth/oidc/corp/login","name":"corp"}`) {
+					t.Errorf("providers = %s", r.Body)
+				}
+			},
+		},
+		{name: "unknown provider", method: "GET", path: "/auth/oidc/other/login", wantStatus: fiber.StatusNotFound, wantCode: apperror.CodeProviderNotFound},
+		{
+			name: "redirect to the provider", method: "GET", path: "/auth/oidc/corp/login", wantStatus: fiber.StatusFound,
+			check: func(t *testing.T, r response) {
+				location, err := url.Parse(r.Header.Get(fiber.HeaderLocation))
+				if err != nil || !strings.HasPrefix(location.String(), idp.URL+"/authorize?") {
+					t.Fatalf("Location = %q", r.Header.Get(fiber.HeaderLocation))
+				}
+				q := location.Query()
+				if q.Get("redirect_uri") != "http://localhost:3000/auth/oidc/corp/callback" || q.Get("scope") != "openid profile email" {
+					t.Errorf("redirect_uri = %q, scope = %q", q.Get("redirect_uri"), q.Get("scope"))
+				}
+				if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("state") == "" || q.Get("nonce") == "" {
+					t.Errorf("no PKCE challenge, state or nonce in %s", location.RawQuery)
+				}
+				cookie := r.Header.Get(fiber.HeaderSetCookie)
+				for _, attribute := range []string{"oidc_state=", "path=/auth/oidc/corp", "HttpOnly", "SameSite=Lax"} {
+					if !strings.Contains(cookie, attribute) {
+						t.Errorf("Set-Cookie = %q, want %s", cookie, attribute)
+					}
+				}
+			},
+		},
+	})
+
+	// Just-in-time provisioning on the first login.
+	r := h.oidcLogin(idp, jwt.MapClaims{"sub": "u-1", "email": "dave@corp.example", "email_verified": true, "preferred_username": "dave.smith"})
+	token, _ := r.JSON["token"].(string)
+	if r.Status != fiber.StatusOK || token == "" {
+		t.Fatalf("first login = %d %s", r.Status, r.Body)
+	}
+	if cleared := r.Header.Get(fiber.HeaderSetCookie); !strings.HasPrefix(cleared, "oidc_state=;") {
+		t.Errorf("Set-Cookie = %q, want the state cookie cleared", cleared)
+	}
+	dave, err := h.repos.Users.FindByEmail(context.Background(), "dave@corp.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dave.Username != "davesmith" || dave.Role != models.RoleUser || dave.EmailVerifiedAt == nil || dave.Password != "" {
+		t.Errorf("provisioned user = %+v", dave)
+	}
+	h.run([]apiCase{
+		{name: "provisioned account", header: "Bearer " + token, method: "GET", path: fmt.Sprintf("/users/%d", dave.ID), wantStatus: fiber.StatusOK},
+		{name: "no password", method: "POST", path: "/auth/login", body: map[string]string{"username": "davesmith", "password": "anything"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+	})
+
+	// Later logins find the account by subject, even once the address changed at the provider.
+	r = h.oidcLogin(idp, jwt.MapClaims{"sub": "u-1", "email": "dave.smith@corp.example", "email_verified": true})
+	if r.Status != fiber.StatusOK {
+		t.Fatalf("second login = %d %s", r.Status, r.Body)
+	}
+	identity, err := h.repos.Identities.FindBySubject(context.Background(), "corp", "u-1")
+	if err != nil || identity.UserID != dave.ID || identity.Email != "dave.smith@corp.example" || identity.LastLoginAt == nil {
+		t.Errorf("identity = %+v, %v", identity, err)
+	}
+	if _, err := h.repos.Users.FindByEmail(context.Background(), "dave.smith@corp.example"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("second account created (%v)", err)
+	}
+
+	// A namesake gets a number.
+	r = h.oidcLogin(idp, jwt.MapClaims{"sub": "u-2", "email": "bob@corp.example", "email_verified": true, "preferred_username": "bob"})
+	if bob2, err := h.repos.Users.FindByEmail(context.Background(), "bob@corp.example"); r.Status != fiber.StatusOK || err != nil || bob2.Username != "bob2" {
+		t.Errorf("login = %d %s, user %+v (%v), want bob2", r.Status, r.Body, bob2, err)
+	}
+}
+
+func TestOIDCLinking(t *testing.T) {
+	idp := newOIDCServer(t)
+	h := newOIDCHarness(t, idp, nil)
+	h.run([]apiCase{
+		{name: "unverified local account", method: "POST", path: "/auth/register", body: map[string]string{"username": "erin", "email": "erin@example.com", "password": "password123"}, wantStatus: fiber.StatusCreated},
+	})
+	enableTwoFactor(t, h, "carol")
+
+	tests := []struct {
+		name       string
+		claims     jwt.MapClaims
+		wantStatus int
+		wantCode   string
+	}{
+		{"address not verified by the provider", jwt.MapClaims{"sub": "b-1", "email": "bob@example.com", "email_verified": false}, fiber.StatusForbidden, apperror.CodeIdentityEmailUnverified},
+		{"no address", jwt.MapClaims{"sub": "b-1"}, fiber.StatusForbidden, apperror.CodeIdentityEmailUnverified},
+		{"address not verified locally", jwt.MapClaims{"sub": "e-1", "email": "erin@example.com", "email_verified": true}, fiber.StatusForbidden, apperror.CodeEmailNotVerified},
+		{"verified as a string", jwt.MapClaims{"sub": "b-1", "email": "bob@example.com", "email_verified": "true"}, fiber.StatusOK, ""},
+		{"linked subject", jwt.MapClaims{"sub": "b-1", "email": "robert@example.net", "email_verified": true}, fiber.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := *h
+			sub.t = t
+			r := sub.oidcLogin(idp, tt.claims)
+			if tt.wantCode != "" {
+				wantProblem(t, r, tt.wantStatus, tt.wantCode)
+			} else if r.Status != tt.wantStatus || r.JSON["token"] == nil {
+				t.Fatalf("response = %d %s, want %d with a token", r.Status, r.Body, tt.wantStatus)
+			}
+		})
+	}
+
+	if identity, err := h.repos.Identities.FindBySubject(context.Background(), "corp", "b-1"); err != nil || identity.UserID != h.users["bob"].ID {
+		t.Errorf("identity b-1 = %+v, %v, want linked to bob", identity, err)
+	}
+	if _, err := h.repos.Identities.FindBySubject(context.Background(), "corp", "e-1"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("identity e-1 linked to erin's unverified account (%v)", err)
+	}
+
+	// The provider replaces the password, not the second factor.
+	r := h.oidcLogin(idp, jwt.MapClaims{"sub": "c-1", "email": "carol@example.com", "email_verified": true})
+	if r.Status != fiber.StatusOK || r.JSON["two_factor_required"] != true || r.JSON["token"] != nil {
+		t.Errorf("login = %d %s, want a two-factor challenge", r.Status, r.Body)
+	}
+}
+
+func TestOIDCRejections(t *testing.T) {
+	idp := newOIDCServer(t)
+	h := newOIDCHarness(t, idp, nil)
+	bob := jwt.MapClaims{"sub": "b-1", "email": "bob@example.com", "email_verified": true}
+
+	t.Run("no state cookie", func(t *testing.T) {
+		idp.login(bob)
+		flow := h.startOIDC("corp")
+		wantProblem(t, h.finishOIDC(flow.callback, ""), fiber.StatusBadRequest, apperror.CodeOIDCStateInvalid)
+	})
+	t.Run("cookie of another login", func(t *testing.T) {
+		idp.login(bob)
+		mine, theirs := h.startOIDC("corp"), h.startOIDC("corp")
+		wantProblem(t, h.finishOIDC(mine.callback, theirs.cookie), fiber.StatusBadRequest, apperror.CodeOIDCStateInvalid)
+	})
+	t.Run("code used twice", func(t *testing.T) {
+		idp.login(bob)
+		flow := h.startOIDC("corp")
+		if r := h.finishOIDC(flow.callback, flow.cookie); r.Status != fiber.StatusOK {
+			t.Fatalf("first use = %d %s", r.Status, r.Body)
+		}
+		wantProblem(t, h.finishOIDC(flow.callback, flow.cookie), fiber.StatusUnauthorized, apperror.CodeOIDCLoginFailed)
+	})
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		idp.login(bob)
+		flow := h.startOIDC("corp")
+		// Re-sign the state with another verifier, as if the code had been intercepted.
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(strings.TrimPrefix(flow.cookie, "oidc_state="), claims, jwtkeys.Keys.Keyfunc); err != nil {
+			t.Fatal(err)
+		}
+		claims["verifier"] = strings.Repeat("x", 43)
+		forged, err := jwtkeys.Keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantProblem(t, h.finishOIDC(flow.callback, "oidc_state="+forged), fiber.StatusUnauthorized, apperror.CodeOIDCLoginFailed)
+	})
+	t.Run("refused at the provider", func(t *testing.T) {
+		idp.login(nil)
+		flow := h.startOIDC("corp")
+		r := h.finishOIDC(flow.callback, flow.cookie)
+		wantProblem(t, r, fiber.StatusUnauthorized, apperror.CodeOIDCLoginFailed)
+		if detail, _ := r.JSON["detail"].(string); !strings.Contains(detail, "access_denied") {
+			t.Errorf("detail = %q, want the provider's error", detail)
+		}
+	})
+
+	// ID tokens that fail validation.
+	tampered := []struct {
+		name   string
+		change func(claims jwt.MapClaims)
+	}{
+		{"nonce of another login", func(claims jwt.MapClaims) { claims["nonce"] = "other" }},
+		{"issued to another client", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }},
+		{"issued by another provider", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+	}
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.login(bob)
+			idp.tamperWith(tt.change)
+			flow := h.startOIDC("corp")
+			wantProblem(t, h.finishOIDC(flow.callback, flow.cookie), fiber.StatusUnauthorized, apperror.CodeOIDCLoginFailed)
+		})
+	}
+}
+
+func TestOIDCWithoutProvisioning(t *testing.T) {
+	idp := newOIDCServer(t)
+	h := newOIDCHarness(t, idp, func(h *harness) {
+		h.oidc.AutoProvision = false
+	})
+
+	wantProblem(t, h.oidcLogin(idp, jwt.MapClaims{"sub": "u-1", "email": "dave@corp.example", "email_verified": true}), fiber.StatusForbidden, apperror.CodeIdentityNotLinked)
+	if r := h.oidcLogin(idp, jwt.MapClaims{"sub": "b-1", "email": "bob@example.com", "email_verified": true}); r.Status != fiber.StatusOK {
+		t.Errorf("existing account = %d %s", r.Status, r.Body)
+	}
+}
+
+func TestOIDCProviderUnavailable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	h := newHarnessWith(t, func(h *harness) {
+		h.oidc.Providers = []config.OIDCProvider{{Name: "down", Issuer: down.URL, ClientID: "shop"}}
+	})
+
+	h.run([]apiCase{
+		{name: "discovery fails", method: "GET", path: "/auth/oidc/down/login", wantStatus: fiber.StatusBadGateway, wantCode: apperror.CodeProviderUnavailable},
+	})
+}
+
+func TestOIDCDeletedAccount(t *testing.T) {
+	idp := newOIDCServer(t)
+	h := newOIDCHarness(t, idp, nil)
+	h.run([]apiCase{
+		{name: "delete carol", as: "alice", method: "DELETE", path: "/users/{user:carol}", wantStatus: fiber.StatusNoContent},
+	})
+
+	// The deleted account still holds the address, so no new account can take it.
+	wantProblem(t, h.oidcLogin(idp, jwt.MapClaims{"sub": "c-1", "email": "carol@example.com", "email_verified": true}), fiber.StatusConflict, apperror.CodeEmailTaken)
+}
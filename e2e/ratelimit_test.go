@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// limited checks the headers of a request rejected by a rate limit or the lockout:
+// a Retry-After of at least 1 second and at most max. A sliding window may ask to wait
+// for up to 2 windows.
+func limited(max time.Duration) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		seconds, err := strconv.Atoi(r.Header.Get(fiber.HeaderRetryAfter))
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > max {
+			t.Errorf("Retry-After = %q, want 1 to %v seconds", r.Header.Get(fiber.HeaderRetryAfter), max.Seconds())
+		}
+	}
+}
+
+// remaining checks the X-RateLimit-* headers of an allowed request.
+func remaining(limit, left int) func(t *testing.T, r response) {
+	return func(t *testing.T, r response) {
+		t.Helper()
+		if got := r.Header.Get("X-RateLimit-Limit"); got != strconv.Itoa(limit) {
+			t.Errorf("X-RateLimit-Limit = %q, want %d", got, limit)
+		}
+		if got := r.Header.Get("X-RateLimit-Remaining"); got != strconv.Itoa(left) {
+			t.Errorf("X-RateLimit-Remaining = %q, want %d", got, left)
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	for _, algorithm := range []string{ratelimit.SlidingWindow, ratelimit.TokenBucket} {
+		t.Run(algorithm, func(t *testing.T) {
+			store := ratelimit.NewMemoryStore()
+			h := newHarnessWith(t, func(h *harness) {
+				h.limits = &ratelimit.Limits{
+					AuthPerIP:        ratelimit.NewLimiter("auth_ip", store, algorithm, config.Rate{Limit: 4, Window: time.Hour}),
+					LoginPerUsername: ratelimit.NewLimiter("login_username", store, algorithm, config.Rate{Limit: 2, Window: time.Hour}),
+					APIPerUser:       ratelimit.NewLimiter("api_user", store, algorithm, config.Rate{Limit: 2, Window: time.Hour}),
+				}
+			}, "users.yaml", "catalog.json")
+
+			bobLogin := map[string]string{"username": "bob", "password": "wrong-password1"}
+			maxWait := 2 * time.Hour
+			h.run([]apiCase{
+				{name: "bob's requests", as: "bob", method: "GET", path: "/products", wantStatus: fiber.StatusOK, check: remaining(2, 1)},
+				{name: "bob's requests again", as: "bob", method: "GET", path: "/products", wantStatus: fiber.StatusOK, check: remaining(2, 0)},
+				{name: "bob over his limit", as: "bob", method: "GET", path: "/owners", wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeRateLimited, check: limited(maxWait)},
+				{name: "alice has her own limit", as: "alice", method: "GET", path: "/products", wantStatus: fiber.StatusOK},
+
+				{name: "login attempt", method: "POST", path: "/auth/login", body: bobLogin, wantStatus: fiber.StatusUnauthorized},
+				{name: "another login attempt", method: "POST", path: "/auth/login", body: bobLogin, wantStatus: fiber.StatusUnauthorized},
+				{name: "login attempts over the username's limit", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeRateLimited, check: limited(maxWait)},
+				{name: "another username", method: "POST", path: "/auth/login", body: map[string]string{"username": "carol", "password": "carol-password1"}, wantStatus: fiber.StatusOK},
+				{name: "auth requests over the IP's limit", method: "POST", path: "/auth/refresh", body: map[string]string{"refresh_token": "nope"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeRateLimited, check: limited(maxWait)},
+			})
+		})
+	}
+}
+
+func TestLoginLimitCountsEveryBodyFormat(t *testing.T) {
+	h := newHarnessWith(t, func(h *harness) {
+		h.limits = &ratelimit.Limits{
+			LoginPerUsername: ratelimit.NewLimiter("login_username", ratelimit.NewMemoryStore(), ratelimit.SlidingWindow, config.Rate{Limit: 2, Window: time.Hour}),
+		}
+	}, "users.yaml")
+
+	formLogin := func(password string) response {
+		form := url.Values{"username": {"bob"}, "password": {password}}
+		req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(form.Encode()))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		return h.send(req)
+	}
+	if r := formLogin("wrong-password1"); r.Status != fiber.StatusUnauthorized {
+		t.Fatalf("form login = %d %s", r.Status, r.Body)
+	}
+	if r := formLogin("wrong-password1"); r.Status != fiber.StatusUnauthorized {
+		t.Fatalf("form login = %d %s", r.Status, r.Body)
+	}
+	if r := formLogin("bob-password1"); r.Status != fiber.StatusTooManyRequests || r.JSON["code"] != apperror.CodeRateLimited {
+		t.Errorf("form login over the username's limit = %d %s", r.Status, r.Body)
+	}
+	h.run([]apiCase{
+		{name: "JSON login shares the limit", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeRateLimited},
+		{name: "in any letter case", method: "POST", path: "/auth/login", body: map[string]string{"username": "BOB", "password": "bob-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeRateLimited},
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	h := newHarnessWith(t, func(h *harness) {
+		h.limits = &ratelimit.Limits{
+			Lockout: ratelimit.NewLockout(store, config.Lockout{Threshold: 2, Duration: time.Minute, MaxDuration: time.Hour, ResetAfter: time.Hour}),
+		}
+	}, "users.yaml")
+
+	wrong := map[string]string{"username": "bob", "password": "wrong-password1"}
+	h.run([]apiCase{
+		{name: "first failure", method: "POST", path: "/auth/login", body: wrong, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "failure reaching the threshold", method: "POST", path: "/auth/login", body: wrong, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "locked, even with the right password", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked, check: limited(time.Minute)},
+		{name: "other accounts unaffected", method: "POST", path: "/auth/login", body: map[string]string{"username": "carol", "password": "carol-password1"}, wantStatus: fiber.StatusOK},
+		{name: "unknown username", method: "POST", path: "/auth/login", body: map[string]string{"username": "mallory", "password": "x"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "unknown username reaching the threshold", method: "POST", path: "/auth/login", body: map[string]string{"username": "mallory", "password": "x"}, wantStatus: fiber.StatusUnauthorized},
+		{name: "unknown username locked", method: "POST", path: "/auth/login", body: map[string]string{"username": "mallory", "password": "x"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+		{name: "unknown username locked in any case", method: "POST", path: "/auth/login", body: map[string]string{"username": " Mallory ", "password": "x"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+
+		// Letter case doesn't buy fresh attempts: carol's account is locked however it's typed.
+		{name: "failure as Carol", method: "POST", path: "/auth/login", body: map[string]string{"username": "Carol", "password": "wrong-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "failure as carol", method: "POST", path: "/auth/login", body: map[string]string{"username": "carol", "password": "wrong-password1"}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeInvalidCredentials},
+		{name: "CAROL locked", method: "POST", path: "/auth/login", body: map[string]string{"username": "CAROL", "password": "wrong-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+		{name: "carol locked, even with the right password", method: "POST", path: "/auth/login", body: map[string]string{"username": "carol", "password": "carol-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+	})
+}
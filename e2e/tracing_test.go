@@ -0,0 +1,132 @@
+package e2e
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attr returns the value of a span attribute, or an invalid value if it is missing.
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	// The middleware and the GORM plugin use the global provider, so it is replaced before
+	// the harness is built. Other tests may record spans too; only the traces started
+	// below are looked at.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	if _, err := tracing.Setup(context.Background(), config.Tracing{Exporter: "none"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newHarness(t, "users.yaml", "catalog.json")
+	if err := h.db.Use(tracing.NewGormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	// send makes a request as bob, continuing the trace of the given traceparent header.
+	send := func(path, traceparent string) trace.TraceID {
+		t.Helper()
+		req := httptest.NewRequest("GET", h.path(path), nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+h.token("bob"))
+		req.Header.Set("traceparent", traceparent)
+		resp, err := h.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		traceID, err := trace.TraceIDFromHex(traceparent[3:35])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return traceID
+	}
+	// spans returns the finished spans of one trace.
+	spans := func(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+		var found []sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID() == traceID {
+				found = append(found, span)
+			}
+		}
+		return found
+	}
+
+	t.Run("request and statements", func(t *testing.T) {
+		traceID := send("/products/{product:Phone}", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		var server sdktrace.ReadOnlySpan
+		var statements []sdktrace.ReadOnlySpan
+		for _, span := range spans(traceID) {
+			switch span.SpanKind() {
+			case trace.SpanKindServer:
+				server = span
+			case trace.SpanKindClient:
+				statements = append(statements, span)
+			}
+		}
+		if server == nil {
+			t.Fatal("no server span in the caller's trace")
+		}
+		if server.Name() != "GET /products/:uuid" {
+			t.Errorf("server span name = %q", server.Name())
+		}
+		if server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+			t.Errorf("server span parent = %s, want the caller's span", server.Parent().SpanID())
+		}
+		if got := attr(server, "user_id").AsInt64(); got != int64(h.users["bob"].ID) {
+			t.Errorf("user_id = %d, want %d", got, h.users["bob"].ID)
+		}
+		if got := attr(server, "http.response.status_code").AsInt64(); got != fiber.StatusOK {
+			t.Errorf("http.response.status_code = %d", got)
+		}
+
+		if len(statements) == 0 {
+			t.Fatal("no database spans under the request")
+		}
+		for _, span := range statements {
+			if span.Parent().SpanID() != server.SpanContext().SpanID() {
+				t.Errorf("%s is not a child of the request span", span.Name())
+			}
+			if attr(span, "db.system").AsString() != "sqlite" || attr(span, "db.query.text").AsString() == "" {
+				t.Errorf("%s lacks the database attributes: %v", span.Name(), span.Attributes())
+			}
+		}
+	})
+
+	t.Run("unmatched route", func(t *testing.T) {
+		traceID := send("/does/not/exist", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		found := spans(traceID)
+		if len(found) != 1 || found[0].Name() != "GET" {
+			t.Fatalf("want one span named GET, got %d", len(found))
+		}
+		if got := attr(found[0], "http.response.status_code").AsInt64(); got != fiber.StatusNotFound {
+			t.Errorf("http.response.status_code = %d, want 404", got)
+		}
+	})
+
+	t.Run("unsampled caller", func(t *testing.T) {
+		// The trace flags 00 mean the caller didn't record this trace, so neither do we.
+		traceID := send("/products/{product:Phone}", "00-11111111111111111111111111111111-2222222222222222-00")
+		if found := spans(traceID); len(found) != 0 {
+			t.Errorf("recorded %d spans of an unsampled trace", len(found))
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pquerna/otp/totp"
+)
+
+// totpCode returns the code of an authenticator app for secret, steps time steps from now.
+func totpCode(t *testing.T, secret string, steps int) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(steps)*30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// challenge logs in with a username and password and returns the two-factor challenge token.
+func (h *harness) challenge(username, password string) string {
+	h.t.Helper()
+	r := h.do("POST", "/auth/login", "", map[string]string{"username": username, "password": password})
+	token, _ := r.JSON["challenge_token"].(string)
+	if r.Status != fiber.StatusOK || r.JSON["two_factor_required"] != true || token == "" || r.JSON["token"] != nil {
+		h.t.Fatalf("login = %d %s, want a two-factor challenge", r.Status, r.Body)
+	}
+	return token
+}
+
+func TestTwoFactor(t *testing.T) {
+	h := newHarness(t, "users.yaml")
+	bob := "Bearer " + h.token("bob")
+
+	// Enrolling.
+	var secret string
+	h.run([]apiCase{
+		{name: "confirm before enrolling", header: bob, method: "POST", path: "/auth/2fa/confirm", body: map[string]string{"code": "123456"}, wantStatus: fiber.StatusConflict, wantCode: apperror.CodeTwoFactorNotEnrolled},
+		{
+			name: "enroll", header: bob, method: "POST", path: "/auth/2fa/enroll", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				secret, _ = r.JSON["secret"].(string)
+				if uri, _ := r.JSON["otpauth_uri"].(string); !strings.HasPrefix(uri, "otpauth://totp/") || !strings.Contains(uri, "secret="+secret) {
+					t.Errorf("otpauth_uri = %q", uri)
+				}
+				if qr, _ := r.JSON["qr_code"].(string); !strings.HasPrefix(qr, "data:image/png;base64,iVBORw0KGgo") {
+					t.Errorf("qr_code = %.40q, want a PNG data URI", qr)
+				}
+			},
+		},
+		{name: "not enabled yet", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusOK},
+		{name: "confirm with a wrong code", header: bob, method: "POST", path: "/auth/2fa/confirm", body: map[string]string{"code": "000000"}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeTwoFactorCodeInvalid},
+	})
+	if secret == "" {
+		t.Fatal("no secret")
+	}
+
+	first := totpCode(t, secret, 0)
+	var recoveryCodes []string
+	h.run([]apiCase{
+		{
+			name: "confirm", header: bob, method: "POST", path: "/auth/2fa/confirm", body: map[string]string{"code": first}, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				codes, _ := r.JSON["recovery_codes"].([]interface{})
+				for _, code := range codes {
+					recoveryCodes = append(recoveryCodes, code.(string))
+				}
+				if len(recoveryCodes) != h.twoFactor.RecoveryCodes {
+					t.Errorf("recovery_codes = %v, want %d codes", r.JSON["recovery_codes"], h.twoFactor.RecoveryCodes)
+				}
+			},
+		},
+		{name: "enroll again", header: bob, method: "POST", path: "/auth/2fa/enroll", wantStatus: fiber.StatusConflict, wantCode: apperror.CodeTwoFactorEnabled},
+	})
+
+	// Logging in.
+	challenge := h.challenge("bob", "bob-password1")
+	h.run([]apiCase{
+		{name: "challenge as an access token", header: "Bearer " + challenge, method: "GET", path: "/products", wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTokenInvalid},
+		{name: "access token as a challenge", method: "POST", path: "/auth/2fa/verify", body: map[string]string{"challenge_token": h.token("bob"), "code": first}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorChallengeInvalid},
+		{name: "code already used", method: "POST", path: "/auth/2fa/verify", body: map[string]string{"challenge_token": challenge, "code": first}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorCodeInvalid},
+		{
+			name: "next code", method: "POST", path: "/auth/2fa/verify", body: map[string]string{"challenge_token": challenge, "code": totpCode(t, secret, 1)}, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if r.JSON["token"] == nil || r.JSON["refresh_token"] == nil {
+					t.Errorf("no tokens in %s", r.Body)
+				}
+			},
+		},
+	})
+
+	// Recovery codes, typed in upper case.
+	recovery := map[string]string{"challenge_token": h.challenge("bob", "bob-password1"), "code": strings.ToUpper(recoveryCodes[0])}
+	h.run([]apiCase{
+		{name: "recovery code", method: "POST", path: "/auth/2fa/verify", body: recovery, wantStatus: fiber.StatusOK},
+		{name: "recovery code used again", method: "POST", path: "/auth/2fa/verify", body: recovery, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorCodeInvalid},
+		{
+			name: "status", header: bob, method: "GET", path: "/auth/2fa", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if r.JSON["enabled"] != true || r.JSON["recovery_codes_left"] != float64(len(recoveryCodes)-1) {
+					t.Errorf("status = %s, want enabled with %d recovery codes left", r.Body, len(recoveryCodes)-1)
+				}
+			},
+		},
+		{name: "regenerate with a recovery code", header: bob, method: "POST", path: "/auth/2fa/recovery-codes", body: map[string]string{"code": recoveryCodes[1]}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeTwoFactorCodeInvalid},
+	})
+
+	// Disabling.
+	h.run([]apiCase{
+		{name: "disable with a wrong code", header: bob, method: "DELETE", path: "/auth/2fa", body: map[string]string{"code": "nope"}, wantStatus: fiber.StatusForbidden, wantCode: apperror.CodeTwoFactorCodeInvalid},
+		{name: "disable", header: bob, method: "DELETE", path: "/auth/2fa", body: map[string]string{"code": recoveryCodes[2]}, wantStatus: fiber.StatusOK},
+		{
+			name: "password alone logs in", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, r response) {
+				if r.JSON["token"] == nil {
+					t.Errorf("no token in %s", r.Body)
+				}
+			},
+		},
+		{name: "challenge after disabling", method: "POST", path: "/auth/2fa/verify", body: map[string]string{"challenge_token": challenge, "code": recoveryCodes[3]}, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorChallengeInvalid},
+	})
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	h := newHarnessWith(t, func(h *harness) {
+		h.limits = &ratelimit.Limits{
+			Lockout: ratelimit.NewLockout(ratelimit.NewMemoryStore(), config.Lockout{Threshold: 3, Duration: time.Minute, MaxDuration: time.Hour, ResetAfter: time.Hour}),
+		}
+	}, "users.yaml")
+	enableTwoFactor(t, h, "bob")
+
+	wrong := map[string]string{"challenge_token": h.challenge("bob", "bob-password1"), "code": "000000"}
+	h.run([]apiCase{
+		{name: "wrong code", method: "POST", path: "/auth/2fa/verify", body: wrong, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorCodeInvalid},
+		{name: "wrong code again", method: "POST", path: "/auth/2fa/verify", body: wrong, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorCodeInvalid},
+	})
+	// The right password alone doesn't reset the count of failures.
+	wrong["challenge_token"] = h.challenge("bob", "bob-password1")
+	h.run([]apiCase{
+		{name: "wrong code reaching the threshold", method: "POST", path: "/auth/2fa/verify", body: wrong, wantStatus: fiber.StatusUnauthorized, wantCode: apperror.CodeTwoFactorCodeInvalid},
+		{name: "locked", method: "POST", path: "/auth/2fa/verify", body: wrong, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+		{name: "login locked too", method: "POST", path: "/auth/login", body: map[string]string{"username": "bob", "password": "bob-password1"}, wantStatus: fiber.StatusTooManyRequests, wantCode: apperror.CodeAccountLocked},
+	})
+}
+
+// enableTwoFactor enrolls and confirms an authenticator for a fixture user.
+func enableTwoFactor(t *testing.T, h *harness, username string) {
+	t.Helper()
+	authorization := "Bearer " + h.token(username)
+	r := h.do("POST", "/auth/2fa/enroll", authorization, nil)
+	secret, _ := r.JSON["secret"].(string)
+	if secret == "" {
+		t.Fatalf("enroll = %d %s", r.Status, r.Body)
+	}
+	if r := h.do("POST", "/auth/2fa/confirm", authorization, map[string]string{"code": totpCode(t, secret, 0)}); r.Status != fiber.StatusOK {
+		t.Fatalf("confirm = %d %s", r.Status, r.Body)
+	}
+}
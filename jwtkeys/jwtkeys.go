@@ -0,0 +1,273 @@
+// Package jwtkeys holds the keys used to sign and verify JWT access tokens.
+//
+// Tokens are signed with a single active key and carry its ID in the `kid` header.
+// Any number of additional public keys can be configured for verification, so tokens
+// signed with the previous key keep working while a new key is being rolled out.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms (the values of JWT_ALGORITHM).
+const (
+	AlgHS256 = "HS256" // Shared secret from JWT_SECRET (default, every verifier needs the secret)
+	AlgRS256 = "RS256" // RSA private key from JWT_PRIVATE_KEY_FILE
+	AlgEdDSA = "EdDSA" // Ed25519 private key from JWT_PRIVATE_KEY_FILE
+)
+
+// Keys is the global key set used by GenerateJWTToken and JWTAuthRequired.
+// It is populated by LoadKeys at startup.
+var Keys *KeySet
+
+// verificationKey is a key that can verify tokens with a given kid.
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{} // []byte for HMAC, *rsa.PublicKey or ed25519.PublicKey otherwise
+}
+
+// KeySet holds the active signing key and every key accepted for verification.
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{} // []byte for HMAC, *rsa.PrivateKey or ed25519.PrivateKey otherwise
+
+	verificationKeys map[string]verificationKey
+}
+
+// LoadKeys builds the global key set from the JWT configuration:
+//
+//	Algorithm        HS256 (default), RS256 or EdDSA
+//	Secret           Shared secret, required for HS256
+//	PrivateKeyFile   PEM file with the signing key, required for RS256 and EdDSA
+//	KeyID            kid of the signing key (derived from the public key if empty)
+//	PublicKeyFiles   Extra verification keys as "kid=path.pem" or "path.pem"; entries
+//	                 without "kid=" use the derived kid, matching a previous signing key
+func LoadKeys(cfg config.JWT) error {
+	keys, err := NewKeySet(cfg)
+	if err != nil {
+		return err
+	}
+	Keys = keys
+	return nil
+}
+
+// NewKeySet builds a key set from the JWT configuration (see LoadKeys).
+func NewKeySet(cfg config.JWT) (*KeySet, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = AlgHS256
+	}
+
+	keys := &KeySet{verificationKeys: map[string]verificationKey{}}
+
+	switch algorithm {
+	case AlgHS256:
+		secret := cfg.Secret
+		if secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET not set")
+		}
+		keys.signingMethod = jwt.SigningMethodHS256
+		keys.signingKey = []byte(secret)
+		keys.signingKID = cfg.KeyID
+		if keys.signingKID == "" {
+			keys.signingKID = "hs256"
+		}
+		keys.verificationKeys[keys.signingKID] = verificationKey{method: keys.signingMethod, key: keys.signingKey}
+
+	case AlgRS256, AlgEdDSA:
+		path := cfg.PrivateKeyFile
+		if path == "" {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE must be set for %s", algorithm)
+		}
+		if err := keys.loadSigningKey(algorithm, path, cfg.KeyID); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q (expected HS256, RS256 or EdDSA)", algorithm)
+	}
+
+	// Additional verification keys, typically the previous signing key during a rotation.
+	for _, entry := range cfg.PublicKeyFiles {
+		kid, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			kid, path = "", kid
+		}
+		if path == "" {
+			return nil, fmt.Errorf("invalid JWT_PUBLIC_KEY_FILES entry %q (expected kid=path or path)", entry)
+		}
+		if err := keys.loadVerificationKey(kid, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// loadSigningKey reads a PEM private key and registers its public half for verification.
+func (k *KeySet) loadSigningKey(algorithm, path, kid string) error {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	var public crypto.PublicKey
+	switch algorithm {
+	case AlgRS256:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse RSA private key %s: %w", path, err)
+		}
+		k.signingMethod = jwt.SigningMethodRS256
+		k.signingKey = private
+		public = &private.PublicKey
+	case AlgEdDSA:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse Ed25519 private key %s: %w", path, err)
+		}
+		edPrivate, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("%s is not an Ed25519 private key", path)
+		}
+		k.signingMethod = jwt.SigningMethodEdDSA
+		k.signingKey = edPrivate
+		public = edPrivate.Public()
+	}
+
+	if kid == "" {
+		if kid, err = thumbprint(public); err != nil {
+			return err
+		}
+	}
+	k.signingKID = kid
+	k.verificationKeys[kid] = verificationKey{method: k.signingMethod, key: public}
+	return nil
+}
+
+// loadVerificationKey reads a PEM public key (RSA or Ed25519) and accepts tokens signed with it.
+// An empty kid is derived from the key the same way loadSigningKey does.
+func (k *KeySet) loadVerificationKey(kid, path string) error {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+
+	var key verificationKey
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		key = verificationKey{method: jwt.SigningMethodRS256, key: public}
+	} else if public, err := jwt.ParseEdPublicKeyFromPEM(pemBytes); err == nil {
+		key = verificationKey{method: jwt.SigningMethodEdDSA, key: public}
+	} else {
+		return fmt.Errorf("%s is neither an RSA nor an Ed25519 public key", path)
+	}
+
+	if kid == "" {
+		if kid, err = thumbprint(key.key); err != nil {
+			return err
+		}
+	}
+	k.verificationKeys[kid] = key
+	return nil
+}
+
+// thumbprint derives a stable key ID from a public key (SHA-256 of its DER encoding).
+func thumbprint(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// Sign signs the claims with the active key and sets the `kid` header.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc is passed to jwt.Parse. It picks the verification key by the token's
+// `kid` header and makes sure the token's algorithm matches that key, so an
+// RSA public key can never be abused as an HMAC secret.
+// Tokens without a `kid` (issued before key IDs were introduced) are checked against the signing key.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = k.signingKID
+	}
+
+	key, ok := k.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA public exponent
+	Crv string `json:"crv,omitempty"` // OKP curve name
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS returns every public verification key as a JSON Web Key Set.
+// HMAC secrets are never included.
+func (k *KeySet) JWKS() map[string][]JWK {
+	keys := []JWK{}
+	for kid, key := range k.verificationKeys {
+		switch public := key.key.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(bigEndianExponent(public.E)),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return map[string][]JWK{"keys": keys}
+}
+
+// bigEndianExponent encodes an RSA exponent as the minimal big-endian byte slice required by JWK.
+func bigEndianExponent(e int) []byte {
+	var b []byte
+	for e > 0 {
+		b = append([]byte{byte(e & 0xff)}, b...)
+		e >>= 8
+	}
+	return b
+}
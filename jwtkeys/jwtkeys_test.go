@@ -0,0 +1,141 @@
+package jwtkeys_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-that-is-long-enough-for-hs256"
+
+// rsaKeyFiles writes a new RSA key pair to PEM files and returns their paths
+// and the public key in PEM form.
+func rsaKeyFiles(t *testing.T, name string) (privatePath, publicPath string, publicPEM []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	dir := t.TempDir()
+	privatePath = filepath.Join(dir, name+".pem")
+	publicPath = filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(privatePath, privatePEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, publicPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath, publicPEM
+}
+
+func newKeySet(t *testing.T, cfg config.JWT) *jwtkeys.KeySet {
+	t.Helper()
+	keys, err := jwtkeys.NewKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func sign(t *testing.T, keys *jwtkeys.KeySet) string {
+	t.Helper()
+	token, err := keys.Sign(jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func verify(keys *jwtkeys.KeySet, token string) error {
+	_, err := jwt.Parse(token, keys.Keyfunc)
+	return err
+}
+
+func TestRotatedOutKeyVerifiesWhileListed(t *testing.T) {
+	oldPrivate, oldPublic, _ := rsaKeyFiles(t, "old")
+	newPrivate, _, _ := rsaKeyFiles(t, "new")
+
+	for _, tc := range []struct {
+		name  string
+		kid   string // Of the old signing key
+		entry string // Its JWT_PUBLIC_KEY_FILES entry
+	}{
+		{"explicit kid", "2024-01", "2024-01=" + oldPublic},
+		{"derived kid", "", oldPublic},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			old := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgRS256, PrivateKeyFile: oldPrivate, KeyID: tc.kid})
+			token := sign(t, old)
+
+			rotated := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgRS256, PrivateKeyFile: newPrivate, PublicKeyFiles: []string{tc.entry}})
+			if err := verify(rotated, token); err != nil {
+				t.Errorf("token signed with the listed old key: %v", err)
+			}
+			if err := verify(rotated, sign(t, rotated)); err != nil {
+				t.Errorf("token signed with the new key: %v", err)
+			}
+
+			retired := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgRS256, PrivateKeyFile: newPrivate})
+			if err := verify(retired, token); err == nil {
+				t.Error("token signed with a key no longer listed was accepted")
+			}
+		})
+	}
+}
+
+func TestUnknownKIDIsRejected(t *testing.T) {
+	keys := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgHS256, Secret: testSecret})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	token.Header["kid"] = "unknown"
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(keys, signed); err == nil {
+		t.Error("token with an unknown kid was accepted")
+	}
+}
+
+func TestHMACTokenNamingRSAKeyIsRejected(t *testing.T) {
+	private, _, publicPEM := rsaKeyFiles(t, "signing")
+	keys := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgRS256, PrivateKeyFile: private, KeyID: "rsa"})
+
+	// The classic algorithm confusion attack: HMAC-sign with the public key, which is no secret.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	token.Header["kid"] = "rsa"
+	signed, err := token.SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(keys, signed); err == nil {
+		t.Error("HS256 token naming an RSA key was accepted")
+	}
+}
+
+func TestJWKSOmitsHMACKeys(t *testing.T) {
+	_, public, _ := rsaKeyFiles(t, "extra")
+	keys := newKeySet(t, config.JWT{Algorithm: jwtkeys.AlgHS256, Secret: testSecret, PublicKeyFiles: []string{"rsa=" + public}})
+
+	jwks := keys.JWKS()["keys"]
+	if len(jwks) != 1 || jwks[0].Kid != "rsa" || jwks[0].Kty != "RSA" {
+		t.Errorf("JWKS = %+v, want only the RSA key", jwks)
+	}
+}
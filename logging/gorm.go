@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's logs to the default slog logger:
+//   - statements that fail are logged as errors (a lookup finding nothing is not a failure),
+//   - statements slower than the threshold are logged as warnings,
+//   - every other statement is logged at debug level, or info level under db.Debug().
+//
+// The SQL is logged with its placeholders, never the bound values, which may be
+// passwords or tokens.
+type GormLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger creates a GormLogger; a slowThreshold of 0 disables the slow query warnings.
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{level: gormlogger.Warn, slowThreshold: slowThreshold}
+}
+
+// LogMode implements gorm's logger.Interface.
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// Info implements gorm's logger.Interface.
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Warn implements gorm's logger.Interface.
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Error implements gorm's logger.Interface.
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace implements gorm's logger.Interface. GORM calls it after every statement.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	var (
+		level = slog.LevelDebug
+		msg   = "query"
+		attrs []slog.Attr
+	)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		level, msg = slog.LevelError, "query failed"
+		attrs = append(attrs, slog.Any("error", err))
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+		attrs = append(attrs, slog.Float64("threshold_ms", float64(l.slowThreshold.Microseconds())/1000))
+	case l.level >= gormlogger.Info:
+		level = slog.LevelInfo
+	}
+	// Building the SQL costs more than the check, so it is only done for records that are kept.
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs = append(attrs,
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	)
+	slog.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter implements gorm.ParamsFilter. Dropping the values
+// leaves the placeholders in the SQL passed to Trace.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessLog logs one "request" record per request, with its method, route pattern,
+// path, status code, latency, client IP and, for authenticated requests, the user_id
+// set by JWTAuthRequired.
+//
+// Errors are returned unchanged, for the app's ErrorHandler to turn into the response;
+// the status logged is the one it will send.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		latency := time.Since(start)
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = apperror.From(err).Status
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if !apperror.RouteNotMatched(err) {
+			attrs = append(attrs, slog.String("route", c.Route().Path))
+		}
+		if userID, ok := c.Locals("userID").(uint); ok {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(userID)))
+		}
+		slog.LogAttrs(c.UserContext(), slog.LevelInfo, "request", attrs...)
+		return err
+	}
+}
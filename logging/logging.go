@@ -0,0 +1,86 @@
+// Package logging configures the application's structured logger (log/slog).
+//
+// Setup installs it as the default logger, so slog.InfoContext and friends, the
+// standard log package and GORM (see GormLogger) all write to the same sink in the
+// same format. Records logged with a request's context (c.UserContext()) carry its
+// request_id and, when tracing is enabled, its trace_id and span_id, which is how a
+// log line is matched to an access log entry or a trace.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/anpsniper/test3-bayu-be/config" // Adjust import path to your module name
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes a logger writing to standard output the default logger.
+func Setup(cfg config.Log) error {
+	logger, err := New(os.Stdout, cfg)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New creates a logger writing records of cfg.Level and above to w, as JSON or text.
+func New(w io.Writer, cfg config.Log) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", cfg.Level)
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// requestIDKey is the context key of the request ID (see WithRequestID).
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID, which is then added to
+// every record logged with that context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// contextHandler adds the request and trace IDs found in the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler.
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
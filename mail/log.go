@@ -0,0 +1,21 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogMailer logs messages instead of sending them, links included, so it is only
+// suitable for development (the production profile sends email over SMTP).
+type LogMailer struct{}
+
+// NewLogMailer creates a LogMailer.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send implements Mailer.
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Email not sent (MAIL_TRANSPORT=log)", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
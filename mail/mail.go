@@ -0,0 +1,104 @@
+// Package mail sends the emails of the API, such as address verification and password
+// reset links, through a Mailer chosen by MAIL_TRANSPORT:
+//   - SMTPMailer hands them to an SMTP server (a real relay, or a local stand-in such as
+//     Mailpit or MailHog during development),
+//   - FileMailer writes each one as an .eml file into a directory,
+//   - LogMailer only logs them.
+//
+// The server wraps the chosen Mailer in a Queue, which sends in the background.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string // A bare address, e.g. "alice@example.com"
+	Subject string
+	Text    string
+}
+
+// Mailer sends messages. Send returns once the message has been handed over
+// (to the SMTP server, the file system or the log), not once it has been delivered.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the Mailer selected by cfg.Transport.
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Transport {
+	case "smtp":
+		return NewSMTPMailer(cfg)
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case "log":
+		return NewLogMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+}
+
+// compose renders msg as an RFC 5322 message sent by from, with CRLF line endings.
+func compose(from string, msg Message, now time.Time) ([]byte, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	recipient, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	// A line break in the subject would let it add headers of its own.
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("line break in the subject")
+	}
+	id, err := messageID(sender.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", sender.String())
+	header("To", recipient.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", id)
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&b)
+	if _, err := body.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID in the domain of the sender's address.
+func messageID(sender string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	domain := sender[strings.LastIndex(sender, "@")+1:]
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
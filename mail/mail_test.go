@@ -0,0 +1,252 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+var testMessage = Message{
+	To:      "alice@example.com",
+	Subject: "Vérifiez votre adresse",
+	Text:    "Open this link:\nhttps://example.com/verify?token=abc.def=ghi\n",
+}
+
+// checkMessage parses a composed message and compares it with testMessage.
+func checkMessage(t *testing.T, data []byte) {
+	t.Helper()
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("From"); got != `"Shop" <no-reply@example.com>` {
+		t.Errorf("From = %q", got)
+	}
+	if got := parsed.Header.Get("To"); got != "<alice@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); err != nil || subject != testMessage.Subject {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != testMessage.Text {
+		t.Errorf("body = %q, want %q", got, testMessage.Text)
+	}
+}
+
+// fakeSMTP is a minimal SMTP server accepting one message, without TLS or authentication,
+// like the local stand-ins used during development.
+type fakeSMTP struct {
+	addr     string
+	sender   chan string
+	rcpt     chan string
+	received chan []byte
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &fakeSMTP{addr: listener.Addr().String(), sender: make(chan string, 1), rcpt: make(chan string, 1), received: make(chan []byte, 1)}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); {
+			case verb == "EHLO" || verb == "HELO":
+				reply("250 fake")
+			case strings.HasPrefix(strings.ToUpper(command), "MAIL FROM:"):
+				s.sender <- command[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(strings.ToUpper(command), "RCPT TO:"):
+				s.rcpt <- command[len("RCPT TO:"):]
+				reply("250 OK")
+			case verb == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, ".")) // Undo dot-stuffing
+				}
+				s.received <- []byte(data.String())
+				reply("250 Queued")
+			case verb == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	mailer, err := New(config.Mail{Transport: "smtp", From: "Shop <no-reply@example.com>", SMTPHost: host, SMTPPort: portNumber, SMTPTLS: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if sender := <-server.sender; sender != "<no-reply@example.com>" {
+		t.Errorf("MAIL FROM:%s", sender)
+	}
+	if rcpt := <-server.rcpt; rcpt != "<alice@example.com>" {
+		t.Errorf("RCPT TO:%s", rcpt)
+	}
+	checkMessage(t, <-server.received)
+}
+
+func TestSMTPMailerRequiresSTARTTLS(t *testing.T) {
+	server := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	mailer, err := New(config.Mail{Transport: "smtp", From: "no-reply@example.com", SMTPHost: host, SMTPPort: portNumber, SMTPTLS: "starttls"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(context.Background(), testMessage); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send = %v, want an error about STARTTLS", err)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer, err := New(config.Mail{Transport: "file", From: "Shop <no-reply@example.com>", Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files = %v (%v), want one .eml file", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMessage(t, data)
+}
+
+func TestComposeRejectsHeaderInjection(t *testing.T) {
+	for name, msg := range map[string]Message{
+		"subject":   {To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com"},
+		"recipient": {To: "alice@example.com\r\nBcc: mallory@example.com", Subject: "Hi"},
+	} {
+		if _, err := compose("no-reply@example.com", msg, time.Now()); err == nil {
+			t.Errorf("%s: line break accepted", name)
+		}
+	}
+}
+
+// blockingMailer records messages, each once release is closed.
+type blockingMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []Message
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg Message) error {
+	<-m.release
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	slow := &blockingMailer{release: make(chan struct{})}
+	queue := NewQueue(slow, 2, 1)
+
+	// The mailer blocks, yet Send doesn't wait for it.
+	queued := 0
+	for ; queued < 2; queued++ {
+		if err := queue.Send(context.Background(), testMessage); err != nil {
+			t.Fatalf("Send %d = %v", queued, err)
+		}
+	}
+	// The queue holds 2 messages, plus the one the worker may have taken.
+	err := queue.Send(context.Background(), testMessage)
+	if err == nil {
+		queued++
+		err = queue.Send(context.Background(), testMessage)
+	}
+	if err != ErrQueueFull {
+		t.Errorf("Send over the queue's size = %v, want ErrQueueFull", err)
+	}
+
+	close(slow.release)
+	if err := queue.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(slow.sent) != queued {
+		t.Errorf("sent %d messages, want the %d queued", len(slow.sent), queued)
+	}
+	if err := queue.Send(context.Background(), testMessage); err != ErrQueueClosed {
+		t.Errorf("Send after Close = %v, want ErrQueueClosed", err)
+	}
+}
+
+func TestQueueCloseGivesUpAtDeadline(t *testing.T) {
+	slow := &blockingMailer{release: make(chan struct{})}
+	defer close(slow.release)
+	queue := NewQueue(slow, 1, 1)
+	if err := queue.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := queue.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrQueueFull is returned by Queue.Send when the queue has no room left for a message.
+var ErrQueueFull = errors.New("mail queue is full")
+
+// ErrQueueClosed is returned by Queue.Send once the queue has been closed.
+var ErrQueueClosed = errors.New("mail queue is closed")
+
+// Queue sends messages through another Mailer in the background. Send returns as soon as
+// the message is queued, so a request never waits for a slow SMTP server, and how long it
+// takes doesn't tell whether a message was sent at all (e.g. whether an address has an
+// account). Failures to send are logged.
+type Queue struct {
+	next Mailer
+	jobs chan queuedMessage
+	done sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// queuedMessage is a message waiting for a worker, with the context it was sent in
+// (without its cancellation, which ends with the request) for the logs.
+type queuedMessage struct {
+	ctx context.Context
+	msg Message
+}
+
+// NewQueue creates a Queue holding up to size messages, sent through next by the given
+// number of workers.
+func NewQueue(next Mailer, size, workers int) *Queue {
+	q := &Queue{next: next, jobs: make(chan queuedMessage, size)}
+	for i := 0; i < workers; i++ {
+		q.done.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Send implements Mailer. It fails only if the queue is full or closed.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- queuedMessage{ctx: context.WithoutCancel(ctx), msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for the queued ones to be sent, or for ctx to
+// be done, whichever comes first.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		q.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work sends queued messages until the queue is closed and empty.
+func (q *Queue) work() {
+	defer q.done.Done()
+	for job := range q.jobs {
+		if err := q.next.Send(job.ctx, job.msg); err != nil {
+			slog.ErrorContext(job.ctx, "Failed to send an email", "subject", job.msg.Subject, "error", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// smtpTimeout bounds a whole SMTP conversation when the context has no earlier deadline.
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends messages to an SMTP server, one connection per message.
+type SMTPMailer struct {
+	host     string
+	addr     string // host:port
+	tls      string // starttls, tls or none (see config.Mail.SMTPTLS)
+	username string
+	password string
+	from     string // The From header
+	sender   string // The envelope sender: the bare address of from
+}
+
+// NewSMTPMailer creates an SMTPMailer from the smtp settings of cfg.
+func NewSMTPMailer(cfg config.Mail) (*SMTPMailer, error) {
+	sender, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, err
+	}
+	return &SMTPMailer{
+		host:     cfg.SMTPHost,
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		tls:      cfg.SMTPTLS,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.From,
+		sender:   sender.Address,
+	}, nil
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	recipient, _ := netmail.ParseAddress(msg.To) // Checked by compose
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if m.tls == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server does not support STARTTLS; set MAIL_SMTP_TLS=none to send in plain text")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection,
+		// unless the server is on localhost.
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.sender); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the server, over TLS from the start in tls mode.
+func (m *SMTPMailer) dial(ctx context.Context) (net.Conn, error) {
+	if m.tls == "tls" {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: m.host}}
+		return dialer.DialContext(ctx, "tcp", m.addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", m.addr)
+}
@@ -2,60 +2,158 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
 	"github.com/anpsniper/test3-bayu-be/database"
-	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/logging"
+	"github.com/anpsniper/test3-bayu-be/mail"
+	"github.com/anpsniper/test3-bayu-be/ratelimit"
+	"github.com/anpsniper/test3-bayu-be/repository"
 	"github.com/anpsniper/test3-bayu-be/routes"
+	"github.com/anpsniper/test3-bayu-be/services"
+	"github.com/anpsniper/test3-bayu-be/tracing"
+	"github.com/anpsniper/test3-bayu-be/validation"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
+)
+
+// mailQueueSize and mailWorkers size the queue of emails waiting to be sent in the background.
+const (
+	mailQueueSize = 1000
+	mailWorkers   = 2
 )
 
 func main() {
-	// 1. Load environment variables from .env file
-	// godotenv.Load() attempts to load .env from the current directory.
-	// It's good practice to check for errors, especially in development.
-	err := godotenv.Load()
+	// 1. Load the configuration
+	// config.Load() merges the defaults of the APP_ENV profile, the optional config
+	// file(s) and the environment (including .env) into one typed value, which is
+	// then passed down explicitly to everything that needs a setting.
+	cfg, err := config.Load()
 	if err != nil {
-		log.Println("⚠️ Warning: .env file not found or could not be loaded. Using system environment variables.")
-		// In a production environment, you might want to log.Fatal here
-		// if critical environment variables are missing.
+		fatal("Failed to load configuration", "error", err)
 	}
 
-	// 2. Connect to the database and run migrations
-	// This function (defined in database/database.go) establishes the connection.
-	database.ConnectDB()
+	// Everything from here on, the standard log package and GORM included, logs through
+	// slog in the LOG_FORMAT (json or text) at LOG_LEVEL and above.
+	if err := logging.Setup(cfg.Log); err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+
+	// Optional one-off commands (e.g. `go run . migrate up` or `go run . promote-admin alice`).
+	// They exit instead of starting the server, and only check the settings they use.
+	if len(os.Args) > 1 {
+		runCommand(cfg, os.Args[1:])
+		return
+	}
 
-	// AutoMigrate will automatically create or update tables in your MySQL database
-	// based on the defined structs in your models package.
-	// Ensure all your models are listed here, including the new User model.
-	log.Println("Running database migrations...")
-	err = database.DB.AutoMigrate(&models.Owner{}, &models.Product{}, &models.User{}) // Add all your models here
+	// Refuse to start with a missing or invalid setting rather than failing on the first request.
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	slog.Info("Starting", "profile", cfg.Env)
+	validation.SetPasswordPolicy(cfg.Password.Policy())
+
+	// Send request and SQL spans to the exporter selected by TRACING_EXPORTER
+	// (none by default, otlp for a collector or stdout for local debugging).
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("❌ Failed to run database migrations: %v", err)
+		fatal("Failed to set up tracing", "error", err)
+	}
+
+	// 2. Connect to the database and check its schema
+	// This function (defined in database/database.go) establishes the connection.
+	if err := database.ConnectDB(cfg.Database); err != nil {
+		fatal("Failed to connect to the database", "error", err)
+	}
+
+	// The schema is managed by the SQL migrations in the migrations package (see `migrate up`).
+	// DB_MIGRATE_ON_START decides what happens when some of them have not been applied yet:
+	// "warn" logs and serves anyway, "fail" refuses to start, "apply" runs them now.
+	checkMigrations(cfg.Database.MigrateOnStart)
+
+	// Load the keys used to sign and verify JWTs (HS256 secret or RS256/EdDSA PEM files).
+	// Failing here is better than failing on the first login.
+	if err := jwtkeys.LoadKeys(cfg.JWT); err != nil {
+		fatal("Failed to load JWT keys", "error", err)
 	}
-	log.Println("Database migrations completed successfully! ✅")
 
 	// 3. Initialize Fiber app
 	// fiber.New() creates a new Fiber application instance.
-	app := fiber.New()
+	// Every error returned by a handler or middleware is turned into an
+	// RFC 7807 "application/problem+json" response by apperror.Handler.
+	// The timeouts keep slow or idle clients from holding connections forever,
+	// and BodyLimit rejects oversized requests with 413 before they are parsed.
+	// Fiber's startup banner is replaced by a log record, which log collectors can parse.
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          apperror.Handler,
+		ReadTimeout:           cfg.App.ReadTimeout,
+		WriteTimeout:          cfg.App.WriteTimeout,
+		IdleTimeout:           cfg.App.IdleTimeout,
+		BodyLimit:             cfg.App.BodyLimit,
+		DisableStartupMessage: true,
+	})
 
 	// 4. Setup API routes
 	// This function (defined in routes/routes.go) registers all your API endpoints
 	// with the Fiber application instance, including the new auth routes.
-	routes.SetupRoutes(app)
+	// The handlers reach the database only through the GORM repositories passed in here.
+	// The health service backs /readyz, which also fails once shutdown has begun.
+	// The rate limits and login lockout count in memory, or in Redis when
+	// RATE_LIMIT_STORE=redis so that every instance shares them.
+	repos := repository.NewGorm(database.DB)
+	health := services.NewHealthService(repos.Health)
+	store, err := ratelimit.NewStore(context.Background(), cfg.RateLimit)
+	if err != nil {
+		fatal("Failed to set up the rate limit store", "error", err)
+	}
+	// Email verification and password reset links are sent through MAIL_TRANSPORT, in the
+	// background so that requests don't wait for the mail server.
+	// Two-factor authentication uses the TWO_FACTOR_* settings, and logins through
+	// identity providers the OIDC_* settings and the providers of the config file.
+	transport, err := mail.New(cfg.Mail)
+	if err != nil {
+		fatal("Failed to set up the mailer", "error", err)
+	}
+	mailer := mail.NewQueue(transport, mailQueueSize, mailWorkers)
+	accounts := services.NewAccountService(repos.Users, repos.Sessions, repos.Tokens, mailer, cfg.Account)
+	twoFactor := services.NewTwoFactorService(repos.Users, repos.RecoveryCodes, cfg.TwoFactor)
+	oidc := services.NewOIDCService(repos.Users, repos.Identities, cfg.OIDC)
+	routes.SetupRoutes(app, repos, health, ratelimit.New(store, cfg.RateLimit, cfg.Lockout), accounts, twoFactor, oidc)
 
 	// 5. Start the Fiber server
-	// Get the application port from environment variables (APP_PORT) or default to "3000".
-	appPort := os.Getenv("APP_PORT")
-	if appPort == "" {
-		appPort = "3000" // Default port if not specified in .env
+	// serve() (defined in server.go) listens on APP_PORT (3000 by default), over TLS if
+	// a certificate is configured, until SIGINT or SIGTERM. It then fails /readyz, stops
+	// accepting connections and waits up to APP_SHUTDOWN_TIMEOUT for in-flight requests to finish.
+	serve(app, cfg.App, health)
+
+	// 6. Send the emails still queued, close the database connection pool and the rate
+	// limit store once no request can use them anymore, and flush the spans that have
+	// not been exported yet.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailer.Close(ctx); err != nil {
+		slog.Warn("Failed to send the queued emails", "error", err)
+	}
+	if err := database.Close(); err != nil {
+		slog.Warn("Failed to close the database connection", "error", err)
+	}
+	if err := store.Close(); err != nil {
+		slog.Warn("Failed to close the rate limit store", "error", err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
+}
 
-	log.Printf("Server is starting on port %s... 🌐", appPort)
-	// app.Listen() starts the HTTP server. log.Fatal ensures the application exits
-	// if the server fails to start (e.g., port already in use).
-	log.Fatal(app.Listen(":" + appPort))
+// fatal logs an error and exits, for failures the server can't start or run with.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startKey is where the before callbacks store the time a statement started.
+const startKey = "metrics:start"
+
+// GormPlugin is a GORM plugin recording DBQueryDuration for every statement and
+// exporting the connection pool statistics (open, in use and idle connections,
+// waits...) as go_sql_* metrics labelled with db_name.
+//
+// Register it once per connection pool with db.Use(metrics.NewGormPlugin(name)).
+type GormPlugin struct {
+	dbName string
+}
+
+// NewGormPlugin creates a GormPlugin; dbName labels the pool statistics.
+func NewGormPlugin(dbName string) *GormPlugin {
+	return &GormPlugin{dbName: dbName}
+}
+
+// Name implements gorm.Plugin.
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin. It wraps every GORM operation with timing callbacks
+// and registers the pool statistics collector.
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	err := errors.Join(
+		callbacks.Create().Before("*").Register("metrics:before_create", before),
+		callbacks.Create().After("*").Register("metrics:after_create", after("create")),
+		callbacks.Query().Before("*").Register("metrics:before_query", before),
+		callbacks.Query().After("*").Register("metrics:after_query", after("query")),
+		callbacks.Update().Before("*").Register("metrics:before_update", before),
+		callbacks.Update().After("*").Register("metrics:after_update", after("update")),
+		callbacks.Delete().Before("*").Register("metrics:before_delete", before),
+		callbacks.Delete().After("*").Register("metrics:after_delete", after("delete")),
+		callbacks.Row().Before("*").Register("metrics:before_row", before),
+		callbacks.Row().After("*").Register("metrics:after_row", after("row")),
+		callbacks.Raw().Before("*").Register("metrics:before_raw", before),
+		callbacks.Raw().After("*").Register("metrics:after_raw", after("raw")),
+	)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	// A second pool with the same name keeps reporting through the first collector.
+	err = Registry.Register(collectors.NewDBStatsCollector(sqlDB, p.dbName))
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &alreadyRegistered) {
+		return err
+	}
+	return nil
+}
+
+// before records when the statement started.
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// after observes the statement's duration, labelled with the operation and table.
+func after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown" // e.g. db.Exec with raw SQL
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
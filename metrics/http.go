@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// unmatchedRoute labels requests that didn't match any route, so scanners probing
+// random paths can't create a new time series per path.
+const unmatchedRoute = "unmatched"
+
+// Middleware records HTTPRequests and HTTPDuration for every request.
+// Requests are labelled with the route pattern (e.g. "/products/:uuid") rather than the
+// actual path, which keeps the number of series bounded.
+//
+// Errors returned by the handlers are passed to the app's ErrorHandler here, as Fiber's
+// logger middleware does, so the recorded status is the one actually sent to the client.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+		route := c.Route().Path
+		if err != nil {
+			if apperror.RouteNotMatched(err) {
+				route = unmatchedRoute
+			}
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		// c.Method() points into a buffer fasthttp reuses for the next request, while
+		// Prometheus keeps label values forever, so it must be copied.
+		method := utils.CopyString(c.Method())
+		HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Response().StatusCode())).Inc()
+		HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
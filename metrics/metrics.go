@@ -0,0 +1,91 @@
+// Package metrics exposes Prometheus metrics about the HTTP API, authentication
+// and the database on GET /metrics.
+//
+// Every collector is registered on Registry rather than on the Prometheus default
+// registry, so the exported set is exactly what this package declares (plus the
+// standard Go runtime and process collectors).
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// namespace prefixes every metric name declared here.
+const namespace = "api"
+
+// Registry holds every collector served by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts handled requests by method, route pattern and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by method and route pattern.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// Logins counts login attempts by result: "success", "invalid_credentials", "locked",
+	// "unverified" (right password, email address not verified yet), "two_factor_required"
+	// (right password, waiting for the second factor), "invalid_two_factor", "federation_failed"
+	// (login through an OpenID Connect provider refused, see services.OIDCService) or "error".
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Login attempts, by result.",
+	}, []string{"result"})
+
+	// JWTRejections counts requests rejected by JWTAuthRequired, by error code
+	// (token_missing, token_malformed, token_invalid, token_expired, session_revoked...).
+	JWTRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_jwt_rejections_total",
+		Help:      "Requests rejected by the JWT authentication middleware, by reason.",
+	}, []string{"reason"})
+
+	// RateLimited counts requests rejected with 429 by a rate limiter, by limiter name
+	// (auth_ip, login_username, api_user; see ratelimit.New).
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by a rate limiter, by limiter.",
+	}, []string{"limiter"})
+
+	// DBQueryDuration observes GORM statement latency by operation and table (see GormPlugin).
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time spent executing database statements through GORM, by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Logins,
+		JWTRejections,
+		RateLimited,
+		DBQueryDuration,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
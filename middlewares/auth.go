@@ -1,79 +1,108 @@
 package middlewares
 
 import (
+	"context"
 	"errors" // Import the standard errors package
-	"fmt"
-	"os"
 	"strings"
 
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/metrics"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+
 	// Still useful for general time operations if needed elsewhere
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5" // Correct import for v5
 )
 
-// JWTAuthRequired is a middleware to validate JWT tokens.
+// APIKeyAuthenticator checks the personal API keys sent as "Authorization: ApiKey <key>"
+// (see services.APIKeyService). It returns the key with its user loaded, or an *apperror.Error.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// JWTAuthRequired returns a middleware that validates JWT tokens.
 // It expects an "Authorization" header with a "Bearer <token>" format.
+// An "ApiKey <key>" header is accepted too, and checked by apiKeys; requests authenticated
+// that way have no session (see SessionRequired), and carry the scopes of the key as
+// their permissions.
 // If the token is valid, it extracts the 'user_id' from the token's claims
 // and stores it in Fiber's context (c.Locals("userID")) for subsequent handlers to use.
-func JWTAuthRequired(c *fiber.Ctx) error {
+// Tokens whose session has been revoked (see services.AuthService.Logout) are rejected;
+// sessions are looked up in the given repository.
+// Every rejection is counted in metrics.JWTRejections, labelled with its error code.
+func JWTAuthRequired(sessions repository.SessionRepository, apiKeys APIKeyAuthenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := authenticate(c, sessions, apiKeys)
+		if err != nil {
+			reason := apperror.CodeInternal
+			var appErr *apperror.Error
+			if errors.As(err, &appErr) {
+				reason = appErr.Code
+			}
+			metrics.JWTRejections.WithLabelValues(reason).Inc()
+		}
+		return err
+	}
+}
+
+// authenticate implements JWTAuthRequired.
+func authenticate(c *fiber.Ctx, sessions repository.SessionRepository, apiKeys APIKeyAuthenticator) error {
 	// 1. Extract the Authorization header from the incoming request.
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		// If the header is missing, return an Unauthorized status.
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Authorization header is missing"})
+		return apperror.Unauthorized(apperror.CodeTokenMissing, "Authorization header is missing")
 	}
 
 	// 2. Validate the format of the Authorization header.
-	// It must start with "Bearer " followed by the token string.
+	// It must start with "Bearer " followed by the token string, or "ApiKey " followed by a key.
+	if strings.HasPrefix(authHeader, "ApiKey ") {
+		return authenticateAPIKey(c, apiKeys, strings.TrimPrefix(authHeader, "ApiKey "))
+	}
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid Authorization header format. Expected 'Bearer <token>'"})
+		return apperror.Unauthorized(apperror.CodeTokenMalformed, "Invalid Authorization header format. Expected 'Bearer <token>' or 'ApiKey <key>'")
 	}
 
 	// 3. Extract the actual token string by removing the "Bearer " prefix.
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-	// 4. Retrieve the JWT secret key from environment variables.
-	// This secret is crucial for verifying the token's signature.
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		// If the secret is not configured, it's a server-side issue.
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Server error: JWT_SECRET environment variable not configured"})
+	// 4. Make sure the verification keys were loaded at startup (see jwtkeys.LoadKeys in main.go).
+	if jwtkeys.Keys == nil {
+		// If the keys are not configured, it's a server-side issue.
+		return apperror.Internal(errors.New("JWT verification keys not loaded"))
 	}
 
-	// 5. Parse the token using the secret key and a custom validation function.
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the token's signing method matches the expected HMAC method (e.g., HS256).
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// Return the secret key as bytes for signature verification.
-		return []byte(jwtSecret), nil
-	})
+	// 5. Parse the token. The key set picks the verification key by the token's 'kid' header
+	// and rejects tokens whose algorithm doesn't match that key.
+	token, err := jwt.Parse(tokenString, jwtkeys.Keys.Keyfunc)
 
 	// 6. Handle any errors that occurred during token parsing or validation.
 	if err != nil {
 		// Use errors.Is to check for specific JWT errors from v5.
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Token has expired"})
+			return apperror.Unauthorized(apperror.CodeTokenExpired, "Token has expired")
 		}
 		if errors.Is(err, jwt.ErrSignatureInvalid) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid token signature"})
+			return apperror.Unauthorized(apperror.CodeTokenInvalid, "Invalid token signature")
 		}
-		// Catch other general parsing errors.
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid token: " + err.Error()})
+		// Catch other general parsing errors. The parser's message stays out of the response:
+		// it describes the internals of the verification, not something the client can fix.
+		return apperror.Unauthorized(apperror.CodeTokenInvalid, "Invalid token")
 	}
 
 	// 7. After parsing, explicitly check if the token is valid.
 	// This catches cases where parsing succeeded but the token itself is deemed invalid by the parser.
 	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Token is invalid"})
+		return apperror.Unauthorized(apperror.CodeTokenInvalid, "Token is invalid")
 	}
 
 	// 8. Extract the claims (payload) from the validated token.
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		// This case is unlikely if token.Valid is true, but adds robustness.
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to extract token claims"})
+		return apperror.Unauthorized(apperror.CodeTokenInvalid, "Failed to extract token claims")
 	}
 
 	// 9. Extract the 'user_id' from the claims.
@@ -81,12 +110,70 @@ func JWTAuthRequired(c *fiber.Ctx) error {
 	userIDFloat, ok := claims["user_id"].(float64)
 	if !ok {
 		// If 'user_id' claim is missing or not a valid number.
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "User ID claim missing or invalid in token"})
+		return apperror.Unauthorized(apperror.CodeTokenInvalid, "User ID claim missing or invalid in token")
+	}
+	// 10. Extract the 'sid' (session ID) claim and make sure the session has not been revoked.
+	// A revoked session means the user logged out or a stolen refresh token was detected,
+	// so the access token must stop working even though it has not expired yet.
+	sessionIDFloat, ok := claims["sid"].(float64)
+	if !ok {
+		return apperror.Unauthorized(apperror.CodeTokenInvalid, "Session claim missing or invalid in token")
 	}
+	session, err := sessions.FindSession(c.UserContext(), uint(sessionIDFloat))
+	if err != nil || session.Revoked() {
+		return apperror.Unauthorized(apperror.CodeSessionRevoked, "Session has been revoked")
+	}
+
 	// Store the user ID in Fiber's context. This makes the user ID accessible
 	// to subsequent route handlers without re-parsing the token.
 	c.Locals("userID", uint(userIDFloat))
+	c.Locals("sessionID", session.ID)
 
-	// 10. If all checks pass, proceed to the next handler in the Fiber chain.
+	// Store the role and permissions carried by the token for RequirePermission.
+	// JSON arrays are decoded as []interface{}, so each entry is converted back to a string.
+	role, _ := claims["role"].(string)
+	permissions := []string{}
+	if rawPermissions, ok := claims["permissions"].([]interface{}); ok {
+		for _, p := range rawPermissions {
+			if permission, ok := p.(string); ok {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	c.Locals("role", role)
+	c.Locals("permissions", permissions)
+
+	// 11. If all checks pass, proceed to the next handler in the Fiber chain.
 	return c.Next()
 }
+
+// authenticateAPIKey implements JWTAuthRequired for "ApiKey <key>" headers.
+// The request gets the permissions of the key that the user's role still grants.
+func authenticateAPIKey(c *fiber.Ctx, apiKeys APIKeyAuthenticator, key string) error {
+	if key == "" {
+		return apperror.Unauthorized(apperror.CodeTokenMalformed, "API key is missing from the Authorization header")
+	}
+	apiKey, err := apiKeys.Authenticate(c.UserContext(), key)
+	if err != nil {
+		return err
+	}
+
+	c.Locals("userID", apiKey.UserID)
+	c.Locals("apiKeyID", apiKey.ID)
+	c.Locals("role", apiKey.User.Role)
+	c.Locals("permissions", apiKey.Permissions())
+	return c.Next()
+}
+
+// SessionRequired returns a middleware that rejects requests authenticated with an API key
+// rather than with a login session. It guards the account settings (API keys, two-factor
+// authentication, password, deletion), so a leaked key can't be used to take over the account.
+// It must be registered after JWTAuthRequired.
+func SessionRequired() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("sessionID").(uint); !ok {
+			return apperror.Forbidden(apperror.CodeSessionRequired, "This action requires logging in; API keys are not accepted")
+		}
+		return c.Next()
+	}
+}
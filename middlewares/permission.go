@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/anpsniper/test3-bayu-be/apperror" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission returns a middleware that only lets the request through if the
+// authenticated user's token grants the given permission (e.g. "products:delete").
+// It must be registered after JWTAuthRequired, which stores the permissions in c.Locals("permissions").
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		permissions, _ := c.Locals("permissions").([]string)
+		for _, p := range permissions {
+			if p == permission {
+				return c.Next()
+			}
+		}
+		return apperror.Forbidden(apperror.CodePermissionDenied, "Missing required permission: "+permission)
+	}
+}
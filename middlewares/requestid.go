@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"regexp"
+
+	"github.com/anpsniper/test3-bayu-be/logging" // Adjust import path to your module name
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+)
+
+// requestIDPattern is what an X-Request-ID sent by the client must look like to be kept.
+// Anything else (too long, spaces, quotes, newlines...) could forge or break log lines.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:+=/-]{1,128}$`)
+
+// RequestID returns a middleware giving every request an ID, so an error response, its
+// log lines and its trace can be matched to each other.
+// The ID is the client's X-Request-ID header when it is well-formed (e.g. set by a proxy
+// upstream), and a new UUID otherwise. It is echoed in the X-Request-ID response header,
+// stored in c.Locals("requestid") (see apperror.RequestID) and added to the request's
+// user context, so everything logged with c.UserContext() carries it.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if requestIDPattern.MatchString(id) {
+			id = utils.CopyString(id) // Kept beyond the handler by the logs
+		} else {
+			id = uuid.NewString()
+		}
+
+		c.Set(fiber.HeaderXRequestID, id)
+		c.Locals("requestid", id)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+		return c.Next()
+	}
+}
@@ -0,0 +1,352 @@
+// Package migrations applies the versioned SQL migrations embedded in the binary.
+//
+// Each database driver has its own directory (mysql, postgres, sqlite) holding pairs of files
+// named "<version>_<name>.up.sql" and "<version>_<name>.down.sql", e.g. "0002_add_sku.up.sql".
+// Statements in a file are separated by a semicolon at the end of a line.
+// Applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// fileName matches migration files, e.g. "0001_initial.up.sql".
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL applied by Up
+	Down    string // SQL applied by Down; empty if the migration cannot be rolled back
+}
+
+// SchemaMigration is a row of the schema_migrations table.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName keeps the table name independent of GORM's naming strategy.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil if the migration is pending
+	Missing   bool       // Applied to the database but not known to this binary
+}
+
+// Migrator applies the migrations for one database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration // Sorted by version
+}
+
+// New loads the migrations matching the database's driver (db.Dialector.Name()).
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the embedded migrations for a driver (mysql, postgres or sqlite), sorted by version.
+func Load(driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s/%s", driver, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, driver+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// applied returns the rows of schema_migrations, creating the table if necessary.
+func (m *Migrator) applied() (map[int]SchemaMigration, error) {
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var rows []SchemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	result := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Status lists every known migration and every applied one, sorted by version.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	// Whatever is left was applied by a newer binary.
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: row.Version, Name: row.Name},
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// ErrNotInitialized is returned by CountPending when the schema_migrations table does not exist.
+var ErrNotInitialized = errors.New("schema_migrations table does not exist")
+
+// CountPending returns how many migrations have not been applied yet. Unlike Pending it
+// only reads: it never creates schema_migrations, so it suits frequent health checks run
+// by a database user that may not change the schema.
+func (m *Migrator) CountPending() (int, error) {
+	if !m.db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, ErrNotInitialized
+	}
+	var versions []int
+	if err := m.db.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+	pending := 0
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order and returns the ones it applied.
+// Each migration runs in its own transaction. Note that MySQL commits DDL statements
+// implicitly, so a migration that fails halfway there must be repaired by hand.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range pending {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns the ones it rolled back.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := statuses[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+		if migration.Missing {
+			return done, fmt.Errorf("migration %04d_%s is not known to this binary", migration.Version, migration.Name)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return done, fmt.Errorf("migration %04d_%s cannot be rolled back", migration.Version, migration.Name)
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration.Migration)
+	}
+	return done, nil
+}
+
+// execScript runs each statement of a migration file separately, because not every
+// driver accepts several statements in one call.
+func execScript(tx *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits SQL on semicolons at the end of a line and drops comment-only chunks.
+func splitStatements(script string) []string {
+	statements := []string{}
+	var current strings.Builder
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		current.Reset()
+		if statement != "" && !onlyComments(statement) {
+			statements = append(statements, statement)
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, ";") {
+			current.WriteString(strings.TrimSuffix(trimmed, ";"))
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+	return statements
+}
+
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// Drivers lists the drivers that have a migrations directory.
+var Drivers = []string{"mysql", "postgres", "sqlite"}
+
+// Create writes empty up/down files for a new migration into dir/<driver> for every driver
+// and returns their paths. dir is the migrations source directory (these files are embedded
+// at build time, so the binary has to be rebuilt to pick them up).
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return nil, errors.New("migration name may only contain letters, digits and underscores")
+	}
+
+	// The next version follows the highest one in any driver directory, so they stay in step.
+	next := 1
+	for _, driver := range Drivers {
+		entries, err := os.ReadDir(filepath.Join(dir, driver))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+				if version, _ := strconv.Atoi(match[1]); version >= next {
+					next = version + 1
+				}
+			}
+		}
+	}
+
+	created := []string{}
+	for _, driver := range Drivers {
+		if err := os.MkdirAll(filepath.Join(dir, driver), 0o755); err != nil {
+			return created, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, driver, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			content := fmt.Sprintf("-- %s migration %04d_%s (%s)\n", strings.ToUpper(direction[:1])+direction[1:], next, name, driver)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, path)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,146 @@
+package migrations_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/database"
+	"github.com/anpsniper/test3-bayu-be/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// uuidV4 matches the UUIDs migration 0002 generates on SQLite.
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestSQLiteUpgradeAndRollback upgrades a database that AutoMigrate set up before versioned
+// migrations existed, with data in it, then rolls every migration back.
+func TestSQLiteUpgradeAndRollback(t *testing.T) {
+	dialector, err := database.Dialector(database.DriverSQLite, "file:migrations?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// The baseline layout is exactly what 0001_initial creates, but without schema_migrations.
+	all, err := migrations.Load(database.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		all[0].Up,
+		"INSERT INTO owners (id, owner_name) VALUES (1, 'Acme'), (2, 'Globex')",
+		"INSERT INTO products (id, product_id, product_name, product_brand, created_date) VALUES " +
+			"(1, 100, 'Anvil', 'Acme', '2024-01-01'), (2, 200, 'Rocket', 'Acme', '2024-01-02'), (3, NULL, 'Widget', 'Globex', '2024-01-03')",
+		"INSERT INTO products_owners (product_id, owner_id) VALUES (1, 1), (2, 1), (2, 2), (3, 2)",
+		"INSERT INTO users (id, username, email, password, role) VALUES (1, 'alice', 'alice@example.com', 'hash', 'admin')",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(all) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(all))
+	}
+
+	var products []struct {
+		ID          uint
+		UUID        string
+		ProductName string
+	}
+	if err := db.Raw("SELECT id, uuid, product_name FROM products ORDER BY id").Scan(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 3 {
+		t.Fatalf("%d products after the upgrade, want 3", len(products))
+	}
+	seen := map[string]bool{}
+	for _, product := range products {
+		if !uuidV4.MatchString(product.UUID) {
+			t.Errorf("product %d: uuid %q is not a version 4 UUID", product.ID, product.UUID)
+		}
+		if seen[product.UUID] {
+			t.Errorf("product %d: uuid %q is used twice", product.ID, product.UUID)
+		}
+		seen[product.UUID] = true
+	}
+	if products[1].ProductName != "Rocket" {
+		t.Errorf("product 2 is named %q, want Rocket", products[1].ProductName)
+	}
+
+	var links []struct{ ProductID, OwnerID uint }
+	if err := db.Raw("SELECT product_id, owner_id FROM products_owners ORDER BY product_id, owner_id").Scan(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ ProductID, OwnerID uint }{{1, 1}, {2, 1}, {2, 2}, {3, 2}}
+	if len(links) != len(want) {
+		t.Fatalf("products_owners = %v, want %v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("products_owners = %v, want %v", links, want)
+			break
+		}
+	}
+
+	// The rebuilt join table must reference the new products table, not the renamed copy.
+	var references []string
+	if err := db.Raw("SELECT `table` FROM pragma_foreign_key_list('products_owners')").Scan(&references).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range references {
+		if table != "products" && table != "owners" {
+			t.Errorf("products_owners references %q", table)
+		}
+	}
+	var leftovers int64
+	if err := db.Raw("SELECT count(*) FROM sqlite_master WHERE name LIKE '%\\_old' ESCAPE '\\'").Scan(&leftovers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if leftovers != 0 {
+		t.Errorf("%d *_old tables left behind", leftovers)
+	}
+	if err := db.Exec("INSERT INTO products_owners (product_id, owner_id) VALUES (99, 1)").Error; err == nil {
+		t.Error("products_owners accepted a product that doesn't exist")
+	}
+
+	rolledBack, err := migrator.Down(len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != len(all) {
+		t.Fatalf("rolled back %d migrations, want %d", len(rolledBack), len(all))
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(all) {
+		t.Errorf("%d migrations pending after rolling back, want %d", len(pending), len(all))
+	}
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')").Scan(&tables).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Errorf("tables left after rolling back: %v", tables)
+	}
+}
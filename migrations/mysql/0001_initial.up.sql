@@ -0,0 +1,77 @@
+-- Initial schema, matching what AutoMigrate created before versioned migrations were introduced.
+-- IF NOT EXISTS lets databases that were set up by AutoMigrate adopt this migration as-is.
+
+CREATE TABLE IF NOT EXISTS `owners` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `owner_name` longtext NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_owners_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `products` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `product_id` bigint unsigned,
+  `product_name` longtext,
+  `product_brand` longtext,
+  `created_date` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_products_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `products_owners` (
+  `product_id` bigint unsigned,
+  `owner_id` bigint unsigned,
+  PRIMARY KEY (`product_id`, `owner_id`),
+  CONSTRAINT `fk_products_owners_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`),
+  CONSTRAINT `fk_products_owners_owner` FOREIGN KEY (`owner_id`) REFERENCES `owners` (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `username` varchar(191) NOT NULL,
+  `email` varchar(191) NOT NULL,
+  `password` longtext NOT NULL,
+  `role` varchar(191) NOT NULL DEFAULT 'user',
+  PRIMARY KEY (`id`),
+  INDEX `idx_users_deleted_at` (`deleted_at`),
+  CONSTRAINT `uni_users_username` UNIQUE (`username`),
+  CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `revoked_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_sessions_deleted_at` (`deleted_at`),
+  INDEX `idx_sessions_user_id` (`user_id`),
+  CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `session_id` bigint unsigned NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `used_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_refresh_tokens_deleted_at` (`deleted_at`),
+  INDEX `idx_refresh_tokens_session_id` (`session_id`),
+  UNIQUE INDEX `idx_refresh_tokens_token_hash` (`token_hash`),
+  CONSTRAINT `fk_sessions_refresh_tokens` FOREIGN KEY (`session_id`) REFERENCES `sessions` (`id`)
+);
@@ -0,0 +1,3 @@
+-- Back to the 0001_initial layout. The generated UUIDs are lost.
+ALTER TABLE `products` DROP INDEX `idx_products_uuid`, DROP COLUMN `uuid`;
+ALTER TABLE `products` ADD COLUMN `product_id` bigint unsigned AFTER `deleted_at`;
@@ -0,0 +1,34 @@
+-- Give products a single primary key (id) and a public UUID, and drop the unused product_id column.
+-- Databases created by AutoMigrate before this change have a composite primary key (id, product_id)
+-- and a products_owners table keyed on both columns (product_id, product_product_id);
+-- the statements below bring both the old and the new layout to the same schema.
+
+-- Rebuild products_owners keyed on (product_id, owner_id) only, keeping every link.
+ALTER TABLE `products_owners` DROP FOREIGN KEY `fk_products_owners_product`;
+ALTER TABLE `products_owners` DROP FOREIGN KEY `fk_products_owners_owner`;
+CREATE TABLE `products_owners_new` (
+  `product_id` bigint unsigned,
+  `owner_id` bigint unsigned,
+  PRIMARY KEY (`product_id`, `owner_id`)
+);
+INSERT INTO `products_owners_new` (`product_id`, `owner_id`)
+  SELECT DISTINCT `product_id`, `owner_id` FROM `products_owners`;
+DROP TABLE `products_owners`;
+RENAME TABLE `products_owners_new` TO `products_owners`;
+
+-- Single primary key on id (a no-op for databases created from 0001_initial).
+ALTER TABLE `products` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);
+ALTER TABLE `products` DROP COLUMN `product_id`;
+
+-- Products created while the column had a database default may still lack a date.
+UPDATE `products` SET `created_date` = COALESCE(`created_at`, NOW(3)) WHERE `created_date` IS NULL;
+ALTER TABLE `products` MODIFY `created_date` datetime(3) NOT NULL;
+
+-- Public identifier for existing rows; UUID() is evaluated once per row.
+ALTER TABLE `products` ADD COLUMN `uuid` varchar(36) NULL AFTER `deleted_at`;
+UPDATE `products` SET `uuid` = UUID();
+ALTER TABLE `products` MODIFY `uuid` varchar(36) NOT NULL, ADD UNIQUE INDEX `idx_products_uuid` (`uuid`);
+
+ALTER TABLE `products_owners`
+  ADD CONSTRAINT `fk_products_owners_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`),
+  ADD CONSTRAINT `fk_products_owners_owner` FOREIGN KEY (`owner_id`) REFERENCES `owners` (`id`);
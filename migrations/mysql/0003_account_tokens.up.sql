@@ -0,0 +1,21 @@
+-- Email verification and password reset links.
+-- Accounts created before verification existed keep working: their address counts as verified.
+ALTER TABLE `users` ADD COLUMN `email_verified_at` datetime(3) NULL;
+UPDATE `users` SET `email_verified_at` = COALESCE(`created_at`, NOW(3));
+
+CREATE TABLE IF NOT EXISTS `account_tokens` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `purpose` varchar(32) NOT NULL,
+  `token_id` varchar(36) NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `used_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_account_tokens_deleted_at` (`deleted_at`),
+  INDEX `idx_account_tokens_user_id` (`user_id`),
+  UNIQUE INDEX `idx_account_tokens_token_id` (`token_id`),
+  CONSTRAINT `fk_account_tokens_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
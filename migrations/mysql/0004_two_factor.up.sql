@@ -0,0 +1,19 @@
+-- TOTP two-factor authentication and its recovery codes.
+ALTER TABLE `users` ADD COLUMN `totp_secret` longtext NULL;
+ALTER TABLE `users` ADD COLUMN `totp_enabled_at` datetime(3) NULL;
+ALTER TABLE `users` ADD COLUMN `totp_last_step` bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `recovery_codes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `used_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_recovery_codes_deleted_at` (`deleted_at`),
+  INDEX `idx_recovery_codes_user_id` (`user_id`),
+  UNIQUE INDEX `idx_recovery_codes_code_hash` (`code_hash`),
+  CONSTRAINT `fk_recovery_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
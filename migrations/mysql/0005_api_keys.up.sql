@@ -0,0 +1,20 @@
+-- Personal API keys for machine clients.
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `prefix` varchar(16) NOT NULL,
+  `key_hash` varchar(64) NOT NULL,
+  `scopes` text NOT NULL,
+  `expires_at` datetime(3) NULL,
+  `last_used_at` datetime(3) NULL,
+  `revoked_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_api_keys_deleted_at` (`deleted_at`),
+  INDEX `idx_api_keys_user_id` (`user_id`),
+  UNIQUE INDEX `idx_api_keys_key_hash` (`key_hash`),
+  CONSTRAINT `fk_api_keys_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
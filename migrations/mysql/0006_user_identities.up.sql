@@ -0,0 +1,17 @@
+-- Accounts at OpenID Connect identity providers linked to users.
+CREATE TABLE IF NOT EXISTS `user_identities` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `provider` varchar(50) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `email` varchar(255),
+  `last_login_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_identities_deleted_at` (`deleted_at`),
+  INDEX `idx_user_identities_user_id` (`user_id`),
+  UNIQUE INDEX `idx_user_identities_provider_subject` (`provider`, `subject`),
+  CONSTRAINT `fk_user_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "refresh_tokens";
+DROP TABLE IF EXISTS "sessions";
+DROP TABLE IF EXISTS "users";
+DROP TABLE IF EXISTS "products_owners";
+DROP TABLE IF EXISTS "products";
+DROP TABLE IF EXISTS "owners";
@@ -0,0 +1,77 @@
+-- Initial schema, matching what AutoMigrate created before versioned migrations were introduced.
+-- IF NOT EXISTS lets databases that were set up by AutoMigrate adopt this migration as-is.
+
+CREATE TABLE IF NOT EXISTS "owners" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "owner_name" text NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_owners_deleted_at" ON "owners" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "products" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "product_id" bigint,
+  "product_name" text,
+  "product_brand" text,
+  "created_date" timestamptz NOT NULL,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_products_deleted_at" ON "products" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "products_owners" (
+  "product_id" bigint,
+  "owner_id" bigint,
+  PRIMARY KEY ("product_id", "owner_id"),
+  CONSTRAINT "fk_products_owners_product" FOREIGN KEY ("product_id") REFERENCES "products" ("id"),
+  CONSTRAINT "fk_products_owners_owner" FOREIGN KEY ("owner_id") REFERENCES "owners" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "username" text NOT NULL,
+  "email" text NOT NULL,
+  "password" text NOT NULL,
+  "role" text NOT NULL DEFAULT 'user',
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_users_username" UNIQUE ("username"),
+  CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "user_id" bigint NOT NULL,
+  "revoked_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_sessions_deleted_at" ON "sessions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "session_id" bigint NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_sessions_refresh_tokens" FOREIGN KEY ("session_id") REFERENCES "sessions" ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_session_id" ON "refresh_tokens" ("session_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_deleted_at" ON "refresh_tokens" ("deleted_at");
@@ -0,0 +1,4 @@
+-- Back to the 0001_initial layout. The generated UUIDs are lost.
+DROP INDEX IF EXISTS "idx_products_uuid";
+ALTER TABLE "products" DROP COLUMN "uuid";
+ALTER TABLE "products" ADD COLUMN "product_id" bigint;
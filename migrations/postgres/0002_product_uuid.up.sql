@@ -0,0 +1,9 @@
+-- Give products a public UUID and drop the unused product_id column.
+-- (PostgreSQL databases always had a single primary key on id, see 0001_initial.)
+
+ALTER TABLE "products" ADD COLUMN "uuid" uuid;
+UPDATE "products" SET "uuid" = gen_random_uuid();
+ALTER TABLE "products" ALTER COLUMN "uuid" SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_uuid" ON "products" ("uuid");
+
+ALTER TABLE "products" DROP COLUMN "product_id";
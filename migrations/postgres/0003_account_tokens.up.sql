@@ -0,0 +1,21 @@
+-- Email verification and password reset links.
+-- Accounts created before verification existed keep working: their address counts as verified.
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamptz;
+UPDATE "users" SET "email_verified_at" = COALESCE("created_at", NOW());
+
+CREATE TABLE IF NOT EXISTS "account_tokens" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "user_id" bigint NOT NULL,
+  "purpose" varchar(32) NOT NULL,
+  "token_id" varchar(36) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_account_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_account_tokens_token_id" ON "account_tokens" ("token_id");
+CREATE INDEX IF NOT EXISTS "idx_account_tokens_user_id" ON "account_tokens" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_account_tokens_deleted_at" ON "account_tokens" ("deleted_at");
@@ -0,0 +1,17 @@
+-- Accounts at OpenID Connect identity providers linked to users.
+CREATE TABLE IF NOT EXISTS "user_identities" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "user_id" bigint NOT NULL,
+  "provider" varchar(50) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "email" varchar(255),
+  "last_login_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identities_provider_subject" ON "user_identities" ("provider", "subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_user_id" ON "user_identities" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_deleted_at" ON "user_identities" ("deleted_at");
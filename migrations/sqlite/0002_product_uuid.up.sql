@@ -0,0 +1,42 @@
+-- Give products a public UUID and drop the unused product_id column.
+-- SQLite cannot add a NOT NULL column without a default, so products is rebuilt;
+-- products_owners is rebuilt with it so its foreign key points at the new table.
+
+ALTER TABLE `products_owners` RENAME TO `products_owners_old`;
+ALTER TABLE `products` RENAME TO `products_old`;
+DROP INDEX IF EXISTS `idx_products_deleted_at`;
+
+CREATE TABLE `products` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `uuid` text NOT NULL,
+  `product_name` text,
+  `product_brand` text,
+  `created_date` datetime NOT NULL
+);
+CREATE INDEX `idx_products_deleted_at` ON `products` (`deleted_at`);
+CREATE UNIQUE INDEX `idx_products_uuid` ON `products` (`uuid`);
+
+-- SQLite has no UUID function; this builds a random (version 4) UUID per row.
+INSERT INTO `products` (`id`, `created_at`, `updated_at`, `deleted_at`, `uuid`, `product_name`, `product_brand`, `created_date`)
+  SELECT `id`, `created_at`, `updated_at`, `deleted_at`,
+    lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))),
+    `product_name`, `product_brand`, `created_date`
+  FROM `products_old`;
+
+CREATE TABLE `products_owners` (
+  `product_id` integer,
+  `owner_id` integer,
+  PRIMARY KEY (`product_id`, `owner_id`),
+  CONSTRAINT `fk_products_owners_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`),
+  CONSTRAINT `fk_products_owners_owner` FOREIGN KEY (`owner_id`) REFERENCES `owners` (`id`)
+);
+INSERT INTO `products_owners` (`product_id`, `owner_id`)
+  SELECT DISTINCT `product_id`, `owner_id` FROM `products_owners_old`;
+
+DROP TABLE `products_owners_old`;
+DROP TABLE `products_old`;
@@ -0,0 +1,20 @@
+-- Email verification and password reset links.
+-- Accounts created before verification existed keep working: their address counts as verified.
+ALTER TABLE `users` ADD COLUMN `email_verified_at` datetime;
+UPDATE `users` SET `email_verified_at` = COALESCE(`created_at`, CURRENT_TIMESTAMP);
+
+CREATE TABLE IF NOT EXISTS `account_tokens` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL,
+  `purpose` text NOT NULL,
+  `token_id` text NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime,
+  CONSTRAINT `fk_account_tokens_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_account_tokens_token_id` ON `account_tokens` (`token_id`);
+CREATE INDEX IF NOT EXISTS `idx_account_tokens_user_id` ON `account_tokens` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_account_tokens_deleted_at` ON `account_tokens` (`deleted_at`);
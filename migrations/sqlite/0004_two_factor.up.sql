@@ -0,0 +1,18 @@
+-- TOTP two-factor authentication and its recovery codes.
+ALTER TABLE `users` ADD COLUMN `totp_secret` text;
+ALTER TABLE `users` ADD COLUMN `totp_enabled_at` datetime;
+ALTER TABLE `users` ADD COLUMN `totp_last_step` integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `recovery_codes` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL,
+  `code_hash` text NOT NULL,
+  `used_at` datetime,
+  CONSTRAINT `fk_recovery_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_recovery_codes_code_hash` ON `recovery_codes` (`code_hash`);
+CREATE INDEX IF NOT EXISTS `idx_recovery_codes_user_id` ON `recovery_codes` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_recovery_codes_deleted_at` ON `recovery_codes` (`deleted_at`);
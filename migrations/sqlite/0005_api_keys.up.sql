@@ -0,0 +1,19 @@
+-- Personal API keys for machine clients.
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL,
+  `name` text NOT NULL,
+  `prefix` text NOT NULL,
+  `key_hash` text NOT NULL,
+  `scopes` text NOT NULL,
+  `expires_at` datetime,
+  `last_used_at` datetime,
+  `revoked_at` datetime,
+  CONSTRAINT `fk_api_keys_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_keys_key_hash` ON `api_keys` (`key_hash`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_user_id` ON `api_keys` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_deleted_at` ON `api_keys` (`deleted_at`);
@@ -0,0 +1,16 @@
+-- Accounts at OpenID Connect identity providers linked to users.
+CREATE TABLE IF NOT EXISTS `user_identities` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL,
+  `provider` text NOT NULL,
+  `subject` text NOT NULL,
+  `email` text,
+  `last_login_at` datetime,
+  CONSTRAINT `fk_user_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_identities_provider_subject` ON `user_identities` (`provider`, `subject`);
+CREATE INDEX IF NOT EXISTS `idx_user_identities_user_id` ON `user_identities` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_identities_deleted_at` ON `user_identities` (`deleted_at`);
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey represents the 'api_keys' table in the database.
+// An API key lets a script act as its user without a password or a login session
+// (see services.APIKeyService). Like refresh tokens, only a SHA-256 hash of the key is
+// stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16;not null"`                   // The first characters of the key, to tell keys apart
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;size:64;not null"`            // Hex-encoded SHA-256 of the key
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:text;not null"` // Permissions the key may use, within those of the user's role
+	ExpiresAt  *time.Time `json:"expires_at"`                                       // nil if the key never expires
+	LastUsedAt *time.Time `json:"last_used_at"`                                     // Updated at most once a minute
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	User User `json:"-"`
+}
+
+// Revoked reports whether the key has been revoked.
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Expired reports whether the key has expired at the given time.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Permissions returns the scopes of the key that the current role of its user still grants,
+// so a key loses what its user loses when their role changes. The user must be loaded.
+func (k *APIKey) Permissions() []string {
+	permissions := []string{}
+	for _, scope := range k.Scopes {
+		if RoleHasPermission(k.User.Role, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	return permissions
+}
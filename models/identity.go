@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity represents the 'user_identities' table in the database.
+// It links an account at an OpenID Connect identity provider to a User (see
+// services.OIDCService), so later logins through that provider find the same user
+// even if the email address changes on either side.
+type UserIdentity struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	UserID      uint       `json:"user_id" gorm:"index;not null"`
+	Provider    string     `json:"provider" gorm:"uniqueIndex:idx_user_identities_provider_subject;size:50;not null"` // Name of the provider in the configuration
+	Subject     string     `json:"subject" gorm:"uniqueIndex:idx_user_identities_provider_subject;size:255;not null"` // The "sub" claim: the provider's ID of the account
+	Email       string     `json:"email" gorm:"size:255"`                                                             // Email address at the provider, as of the last login
+	LastLoginAt *time.Time `json:"last_login_at"`
+
+	User User `json:"-"`
+}
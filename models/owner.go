@@ -12,6 +12,6 @@ type Owner struct {
 	OwnerName string `json:"owner_name" gorm:"column:owner_name;not null"`
 
 	// Define the many-to-many relationship with Products.
-	// GORM will use the 'products_owners' table as the join table automatically.
-	Products []Product `json:"products" gorm:"many2many:products_owners;"`
+	// Uses the same 'products_owners' join table (product_id, owner_id) as models.Product.
+	Products []Product `json:"products" gorm:"many2many:products_owners;joinForeignKey:OwnerID;joinReferences:ProductID"`
 }
@@ -3,24 +3,38 @@ package models
 import (
 	"time" // Import for time.Time type
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // Product represents the 'products' table in the database.
 type Product struct {
-	gorm.Model // Provides CreatedAt, UpdatedAt, DeletedAt fields.
-	// Note: Your schema uses 'product_id' as primary key, not 'id'.
-	// We explicitly define ProductID to match your schema.
-	ProductID uint `json:"product_id" gorm:"primaryKey;column:product_id"`
+	gorm.Model // Provides ID (the internal primary key), CreatedAt, UpdatedAt, DeletedAt fields.
+
+	// UUID is the product's public identifier, used by every /products/:uuid route.
+	// It is generated when the product is created and never changes, so clients can
+	// store it safely; the numeric ID is only used inside the database (e.g. products_owners).
+	UUID string `json:"uuid" gorm:"column:uuid;size:36;uniqueIndex;not null"`
 
 	ProductName  string `json:"product_name" gorm:"column:product_name"`
 	ProductBrand string `json:"product_brand" gorm:"column:product_brand"`
 
 	// It's recommended to use time.Time for date fields for better handling.
-	// 'default:CURRENT_TIMESTAMP' will set the default value in the database.
-	CreatedDate time.Time `json:"created_date" gorm:"column:created_date;default:CURRENT_TIMESTAMP"`
+	// 'autoCreateTime' makes GORM fill in the current time on insert when no date is given.
+	// This is done in Go rather than with a database default, because CURRENT_TIMESTAMP
+	// differs between databases (MySQL rejects it for datetime(3), SQLite stores it as text).
+	CreatedDate time.Time `json:"created_date" gorm:"column:created_date;autoCreateTime;not null"`
 
 	// Define the many-to-many relationship with Owners.
-	// GORM will use the 'products_owners' table as the join table automatically.
-	Owners []Owner `json:"owners" gorm:"many2many:products_owners;"`
+	// The join table columns are named explicitly (and identically in models.Owner),
+	// so 'products_owners' is always (product_id, owner_id) whichever side migrates it first.
+	Owners []Owner `json:"owners" gorm:"many2many:products_owners;joinForeignKey:ProductID;joinReferences:OwnerID"`
+}
+
+// BeforeCreate is a GORM hook that assigns a new UUID to products created without one.
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if p.UUID == "" {
+		p.UUID = uuid.NewString()
+	}
+	return nil
 }
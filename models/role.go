@@ -0,0 +1,58 @@
+package models
+
+// Permissions that can be required on routes with middlewares.RequirePermission.
+// They follow a "<resource>:<action>" naming scheme.
+const (
+	PermProductsRead   = "products:read"
+	PermProductsWrite  = "products:write"
+	PermProductsDelete = "products:delete"
+
+	PermOwnersRead   = "owners:read"
+	PermOwnersWrite  = "owners:write"
+	PermOwnersDelete = "owners:delete"
+
+	PermUsersRead   = "users:read"
+	PermUsersManage = "users:manage" // Update or delete accounts other than your own
+	PermUsersRoles  = "users:roles"  // Change the role of any account
+)
+
+// RolePermissions maps every role to the permissions it grants.
+// A role that is not listed here grants no permissions at all.
+var RolePermissions = map[string][]string{
+	RoleUser: {
+		PermProductsRead, PermProductsWrite,
+		PermOwnersRead, PermOwnersWrite,
+		PermUsersRead,
+	},
+	RoleAdmin: {
+		PermProductsRead, PermProductsWrite, PermProductsDelete,
+		PermOwnersRead, PermOwnersWrite, PermOwnersDelete,
+		PermUsersRead, PermUsersManage, PermUsersRoles,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// ValidPermission reports whether permission is granted by at least one role.
+func ValidPermission(permission string) bool {
+	for role := range RolePermissions {
+		if RoleHasPermission(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleHasPermission reports whether the given role grants the given permission.
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time" // Import for time.Time type
+
+	"gorm.io/gorm"
+)
+
+// Session represents the 'sessions' table in the database.
+// A session is created on every login and groups all refresh tokens that were
+// rotated from the first one (a "token family"). Revoking the session logs out
+// every access and refresh token that belongs to it.
+type Session struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	RevokedAt *time.Time `json:"revoked_at"` // Set on logout or when refresh token reuse is detected
+
+	User          User           `json:"-"`
+	RefreshTokens []RefreshToken `json:"-"`
+}
+
+// Revoked reports whether the session has been revoked.
+func (s *Session) Revoked() bool {
+	return s.RevokedAt != nil
+}
+
+// RefreshToken represents the 'refresh_tokens' table in the database.
+// Only a SHA-256 hash of the opaque token is stored, never the token itself.
+type RefreshToken struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	SessionID uint       `json:"session_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"` // Hex-encoded SHA-256 of the token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"` // Set once the token has been rotated; a second use means it was stolen
+
+	Session Session `json:"-"`
+}
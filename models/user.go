@@ -1,15 +1,70 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Roles that can be assigned to a user.
+const (
+	RoleUser  = "user"  // Default role given to every newly registered account
+	RoleAdmin = "admin" // Can manage every user account, not just their own
+)
+
 // User represents the 'users' table in the database.
 type User struct {
 	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
 	// GORM's default 'ID' field will map to your primary key.
 
-	Username string `json:"username" gorm:"unique;not null"` // Unique and cannot be null
-	Email    string `json:"email" gorm:"unique;not null"`    // Unique and cannot be null
-	Password string `json:"-" gorm:"not null"`               // Stored hashed; 'json:"-"' prevents it from being serialized to JSON output
+	Username string `json:"username" gorm:"unique;not null"`     // Unique and cannot be null
+	Email    string `json:"email" gorm:"unique;not null"`        // Unique and cannot be null
+	Password string `json:"-" gorm:"not null"`                   // Stored hashed; 'json:"-"' prevents it from being serialized to JSON output
+	Role     string `json:"role" gorm:"not null;default:'user'"` // One of the roles in RolePermissions
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // Set once the owner of Email has followed a verification link
+
+	// TOTP two-factor authentication (see services.TwoFactorService).
+	TOTPSecret    string     `json:"-"`                           // Base32 secret shared with the authenticator app; set on enrollment
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`             // Set once the enrollment is confirmed: from then on, logging in needs a code
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"` // Time step of the last accepted code, so that a code works only once
+}
+
+// TwoFactorEnabled reports whether logging in needs a TOTP or recovery code.
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Purposes of an AccountToken.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// AccountToken represents the 'account_tokens' table in the database.
+// One is recorded for every link emailed to a user (see services.AccountService), so
+// that each link works only once. The link carries a signed token; only its ID is stored.
+type AccountToken struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	Purpose   string     `json:"purpose" gorm:"size:32;not null"`       // PurposeVerifyEmail or PurposeResetPassword
+	TokenID   string     `json:"-" gorm:"uniqueIndex;size:36;not null"` // The jti claim of the signed token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"` // Set once the link has been followed, or superseded
+
+	User User `json:"-"`
+}
+
+// RecoveryCode represents the 'recovery_codes' table in the database.
+// Recovery codes replace a TOTP code when the authenticator is lost; each works once.
+// Like refresh tokens, only a SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	gorm.Model // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields.
+
+	UserID   uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	UsedAt   *time.Time `json:"used_at"`
+
+	User User `json:"-"`
 }
@@ -0,0 +1,45 @@
+package pagination
+
+import (
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LikeEscape is the escape character used by Prefix and Contains.
+// Use it as "column LIKE ? ESCAPE '!'"; '!' behaves the same on every SQL dialect, unlike '\'.
+const LikeEscape = "!"
+
+// escapeLike makes LIKE wildcards in user input match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Prefix returns a LIKE pattern matching values that start with s.
+func Prefix(s string) string {
+	return escapeLike(s) + "%"
+}
+
+// Contains returns a LIKE pattern matching values that contain s.
+func Contains(s string) string {
+	return "%" + escapeLike(s) + "%"
+}
+
+// QueryTime parses a time query parameter given either as RFC 3339
+// ("2024-05-01T10:00:00Z") or as a plain date ("2024-05-01").
+// It returns nil if the parameter is absent and a 400 *apperror.Error if it is malformed.
+func QueryTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, apperror.BadRequest(apperror.CodeInvalidFilter, "Invalid "+key+": expected RFC 3339 timestamp or YYYY-MM-DD date")
+}
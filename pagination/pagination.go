@@ -0,0 +1,257 @@
+// Package pagination implements the shared list query layer used by the
+// GetProducts, GetOwners and GetUsers handlers: limit/offset and cursor
+// pagination, whitelisted sorting, total counts and next/prev links.
+//
+// A list request is parsed once with ParseRequest, run either against the
+// database (Query) or against an in-memory slice (Slice, used by the
+// repository fakes), and turned into the JSON envelope with NewPage.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Limits for the 'limit' query parameter.
+const (
+	DefaultLimit = 20  // Number of items returned when no 'limit' is given
+	MaxLimit     = 100 // Larger values are capped to this
+)
+
+// Options describes how a resource can be listed.
+type Options struct {
+	// SortFields maps the names clients may use in ?sort= to database columns.
+	// Anything not listed here is rejected, so user input never reaches ORDER BY directly.
+	SortFields map[string]string
+	// DefaultSort is used when the request has no ?sort=, e.g. "id" or "-created_date".
+	DefaultSort string
+}
+
+// Links holds absolute URLs to the neighbouring pages, if there are any.
+type Links struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Page is the JSON envelope returned by every paginated list endpoint.
+type Page struct {
+	Data  interface{} `json:"data"`
+	Total int64       `json:"total"`          // Number of items matching the filters, across all pages
+	Limit int         `json:"limit"`          // Page size actually used
+	Page  int         `json:"page,omitempty"` // Current page number (offset pagination only)
+	Links Links       `json:"links"`
+}
+
+// Sort is one parsed entry of the ?sort= parameter.
+type Sort struct {
+	Column string // Database column, taken from Options.SortFields
+	Desc   bool
+}
+
+// cursor marks a position in a cursor-paginated list: the sort value and ID of
+// the item next to which the requested page starts.
+type cursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    json.RawMessage `json:"id"`
+	Prev  bool            `json:"p,omitempty"` // Page backwards from this position
+}
+
+// Request is a parsed and validated list request.
+//
+// Supported query parameters:
+//
+//	limit    Page size, capped at MaxLimit
+//	sort     Comma-separated field names from Options.SortFields; prefix with '-' for descending
+//	page     1-based page number (offset pagination, the default)
+//	cursor   Opaque position from a previous response's links (cursor pagination).
+//	         Pass an empty ?cursor= to start cursor pagination from the beginning.
+//
+// Cursor pagination is stable under concurrent inserts but only supports a single sort field.
+type Request struct {
+	Limit      int
+	Page       int // 1-based page number (offset pagination only)
+	Sorts      []Sort
+	CursorMode bool
+	cursor     *cursor // Position to continue from; nil at the start of a cursor-paginated list
+}
+
+// Result describes where a page sits in the full list.
+type Result struct {
+	Total      int64 // Number of items matching the filters, across all pages
+	HasNext    bool
+	HasPrev    bool
+	NextCursor string // Cursor pagination only
+	PrevCursor string // Cursor pagination only
+}
+
+// ParseRequest reads the pagination and sorting parameters of a list request.
+// Invalid parameters are reported as a 400 *apperror.Error.
+func ParseRequest(c *fiber.Ctx, opts Options) (Request, error) {
+	req := Request{Limit: c.QueryInt("limit", DefaultLimit), Page: c.QueryInt("page", 1)}
+	if req.Limit < 1 {
+		req.Limit = DefaultLimit
+	}
+	if req.Limit > MaxLimit {
+		req.Limit = MaxLimit
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	sorts, err := parseSort(c.Query("sort", opts.DefaultSort), opts.SortFields)
+	if err != nil {
+		return Request{}, err
+	}
+	req.Sorts = sorts
+
+	if c.Context().QueryArgs().Has("cursor") {
+		req.CursorMode = true
+		if len(sorts) > 1 {
+			return Request{}, apperror.BadRequest(apperror.CodeInvalidSort, "Cursor pagination supports a single sort field")
+		}
+		if raw := c.Query("cursor"); raw != "" {
+			decoded, err := decodeCursor(raw)
+			if err != nil {
+				return Request{}, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+			}
+			req.cursor = decoded
+		}
+	}
+	return req, nil
+}
+
+// NewPage builds the response envelope for one page of data, including the
+// links to the neighbouring pages.
+func NewPage(c *fiber.Ctx, req Request, data interface{}, result Result) *Page {
+	page := &Page{Data: data, Total: result.Total, Limit: req.Limit}
+	if req.CursorMode {
+		if result.HasNext {
+			page.Links.Next = link(c, "cursor", result.NextCursor)
+		}
+		if result.HasPrev {
+			page.Links.Prev = link(c, "cursor", result.PrevCursor)
+		}
+		return page
+	}
+
+	page.Page = req.Page
+	if result.HasNext {
+		page.Links.Next = link(c, "page", strconv.Itoa(req.Page+1))
+	}
+	if result.HasPrev {
+		page.Links.Prev = link(c, "page", strconv.Itoa(req.Page-1))
+	}
+	return page
+}
+
+// parseSort validates the ?sort= parameter against the whitelist.
+func parseSort(raw string, allowed map[string]string) ([]Sort, error) {
+	sorts := []Sort{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		column, ok := allowed[strings.TrimPrefix(name, "-")]
+		if !ok {
+			return nil, apperror.BadRequest(apperror.CodeInvalidSort, "Unknown sort field: "+strings.TrimPrefix(name, "-"))
+		}
+		sorts = append(sorts, Sort{Column: column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// cursorSort returns the single sort field used by cursor pagination (the primary key by default).
+func (r Request) cursorSort() Sort {
+	if len(r.Sorts) == 1 {
+		return r.Sorts[0]
+	}
+	return Sort{Column: "id"}
+}
+
+// backwards reports whether a cursor-paginated request pages towards the start of the list.
+func (r Request) backwards() bool {
+	return r.cursor != nil && r.cursor.Prev
+}
+
+// offsetResult fills in the neighbours of an offset-paginated page.
+func offsetResult(req Request, total int64) Result {
+	return Result{
+		Total:   total,
+		HasNext: int64(req.Page*req.Limit) < total,
+		HasPrev: req.Page > 1,
+	}
+}
+
+// cursorResult fills in the neighbours of a cursor-paginated page.
+// first and last are the (sort value, ID) pairs of the first and last item on the page;
+// hasMore reports whether a row beyond the page was found in the direction of travel.
+func cursorResult(req Request, total int64, count int, hasMore bool, first, last [2]interface{}) Result {
+	result := Result{Total: total}
+	if count == 0 {
+		return result
+	}
+	backwards := req.backwards()
+	if (!backwards && hasMore) || backwards {
+		result.HasNext = true
+		result.NextCursor = encodeCursor(last[0], last[1], false)
+	}
+	if (!backwards && req.cursor != nil) || (backwards && hasMore) {
+		result.HasPrev = true
+		result.PrevCursor = encodeCursor(first[0], first[1], true)
+	}
+	return result
+}
+
+// encodeCursor serializes a position as URL-safe base64 JSON.
+func encodeCursor(value, id interface{}, prev bool) string {
+	v, _ := json.Marshal(value)
+	i, _ := json.Marshal(id)
+	b, _ := json.Marshal(cursor{Value: v, ID: i, Prev: prev})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor is the inverse of encodeCursor.
+func decodeCursor(raw string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var decoded cursor
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// decodeValue converts a JSON cursor value back into the column's Go type
+// (e.g. time.Time), so it is compared correctly by every database driver.
+func decodeValue(raw json.RawMessage, fieldType reflect.Type) (interface{}, error) {
+	value := reflect.New(fieldType)
+	if err := json.Unmarshal(raw, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
+
+// link returns the current request URL with one query parameter replaced.
+func link(c *fiber.Ctx, key, value string) string {
+	values := url.Values{}
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		values.Add(string(k), string(v))
+	})
+	if key == "cursor" {
+		values.Del("page") // The two pagination styles don't mix
+	}
+	values.Set(key, value)
+	return c.BaseURL() + c.Path() + "?" + values.Encode()
+}
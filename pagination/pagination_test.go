@@ -0,0 +1,216 @@
+package pagination_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/pagination"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type item struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+var items = []item{{1, "delta"}, {2, "alpha"}, {3, "echo"}, {4, "bravo"}, {5, "charlie"}}
+
+var options = pagination.Options{SortFields: map[string]string{"id": "id", "name": "name"}, DefaultSort: "id"}
+
+func itemField(i item, column string) interface{} {
+	if column == "name" {
+		return i.Name
+	}
+	return i.ID
+}
+
+type page struct {
+	Data  []item           `json:"data"`
+	Links pagination.Links `json:"links"`
+	Code  string           `json:"code"` // Set on errors
+}
+
+// list runs a list request against items the way a handler would.
+func list(t *testing.T, query string) (int, page) {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	app.Get("/items", func(c *fiber.Ctx) error {
+		req, err := pagination.ParseRequest(c, options)
+		if err != nil {
+			return err
+		}
+		data, result, err := pagination.Slice(items, req, itemField)
+		if err != nil {
+			return err
+		}
+		return c.JSON(pagination.NewPage(c, req, data, result))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/items?"+query, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body page
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func ids(items []item) []uint {
+	result := []uint{}
+	for _, i := range items {
+		result = append(result, i.ID)
+	}
+	return result
+}
+
+// follow returns the query string of a next or prev link.
+func follow(t *testing.T, link string) string {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.RawQuery
+}
+
+func TestSortWhitelist(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		wantIDs  []uint
+		wantCode string
+	}{
+		{"", []uint{1, 2, 3, 4, 5}, ""},
+		{"sort=name", []uint{2, 4, 5, 1, 3}, ""},
+		{"sort=-name", []uint{3, 1, 5, 4, 2}, ""},
+		{"sort=-id", []uint{5, 4, 3, 2, 1}, ""},
+		{"sort=password", nil, apperror.CodeInvalidSort},
+		{"sort=name;DROP%20TABLE%20items", nil, apperror.CodeInvalidSort},
+		{"sort=name,secret", nil, apperror.CodeInvalidSort},
+		{"sort=name,id&cursor=", nil, apperror.CodeInvalidSort}, // Cursors take a single field
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			status, body := list(t, tc.query)
+			if tc.wantCode != "" {
+				if status != fiber.StatusBadRequest || body.Code != tc.wantCode {
+					t.Errorf("status %d, code %q; want 400 %s", status, body.Code, tc.wantCode)
+				}
+				return
+			}
+			if status != fiber.StatusOK || !reflect.DeepEqual(ids(body.Data), tc.wantIDs) {
+				t.Errorf("status %d, IDs %v; want 200 %v", status, ids(body.Data), tc.wantIDs)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	query := "sort=name&limit=2&cursor="
+	forward := []uint{}
+	var last page
+	for query != "" {
+		status, body := list(t, query)
+		if status != fiber.StatusOK {
+			t.Fatalf("%s: status %d", query, status)
+		}
+		forward = append(forward, ids(body.Data)...)
+		last, query = body, ""
+		if body.Links.Next != "" {
+			query = follow(t, body.Links.Next)
+		}
+	}
+	if want := []uint{2, 4, 5, 1, 3}; !reflect.DeepEqual(forward, want) {
+		t.Errorf("forward = %v, want %v", forward, want)
+	}
+
+	backward := []uint{}
+	for query = follow(t, last.Links.Prev); query != ""; {
+		_, body := list(t, query)
+		backward = append(ids(body.Data), backward...)
+		query = ""
+		if body.Links.Prev != "" {
+			query = follow(t, body.Links.Prev)
+		}
+	}
+	if want := []uint{2, 4, 5, 1}; !reflect.DeepEqual(backward, want) {
+		t.Errorf("backward = %v, want %v", backward, want)
+	}
+}
+
+func TestTamperedCursorIsRejected(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	for name, cursor := range map[string]string{
+		"not base64":          "%%%",
+		"padded base64":       base64.URLEncoding.EncodeToString([]byte(`{"v":1,"id":1}`)),
+		"not JSON":            encode("not json"),
+		"not an object":       encode(`[1,2]`),
+		"value of wrong type": encode(`{"v":1,"id":1}`), // Sorted by name
+		"ID of wrong type":    encode(`{"v":"alpha","id":"1 OR 1=1"}`),
+		"negative ID":         encode(`{"v":"alpha","id":-1}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			status, body := list(t, "sort=name&cursor="+url.QueryEscape(cursor))
+			if status != fiber.StatusBadRequest || body.Code != apperror.CodeInvalidCursor {
+				t.Errorf("status %d, code %q; want 400 %s", status, body.Code, apperror.CodeInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestLikePatternsMatchLiterally(t *testing.T) {
+	for _, tc := range []struct{ input, prefix, contains string }{
+		{"abc", "abc%", "%abc%"},
+		{"50%", "50!%%", "%50!%%"},
+		{"a_b", "a!_b%", "%a!_b%"},
+		{"wow!", "wow!!%", "%wow!!%"},
+		{`back\slash`, `back\slash%`, `%back\slash%`},
+	} {
+		if got := pagination.Prefix(tc.input); got != tc.prefix {
+			t.Errorf("Prefix(%q) = %q, want %q", tc.input, got, tc.prefix)
+		}
+		if got := pagination.Contains(tc.input); got != tc.contains {
+			t.Errorf("Contains(%q) = %q, want %q", tc.input, got, tc.contains)
+		}
+	}
+
+	// The patterns only mean anything together with the ESCAPE clause, so check them in SQL too.
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE names (name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO names VALUES ('50%'), ('500'), ('a_b'), ('axb'), ('wow!'), ('wow!!')").Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		pattern string
+		want    []string
+	}{
+		{pagination.Prefix("50%"), []string{"50%"}},
+		{pagination.Contains("_"), []string{"a_b"}},
+		{pagination.Prefix("wow!"), []string{"wow!", "wow!!"}},
+		{pagination.Contains("!!"), []string{"wow!!"}},
+	} {
+		var got []string
+		if err := db.Raw("SELECT name FROM names WHERE name LIKE ? ESCAPE '"+pagination.LikeEscape+"' ORDER BY name", tc.pattern).Scan(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("LIKE %q matched %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+}
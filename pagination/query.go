@@ -0,0 +1,123 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"gorm.io/gorm"
+)
+
+// Query runs the list query in db (with any filters already applied) and
+// fills dest, which must be a pointer to a slice of models.
+func Query(db *gorm.DB, dest interface{}, req Request) (Result, error) {
+	// Count before pagination conditions are applied, so the total covers every page.
+	var total int64
+	if err := db.Session(&gorm.Session{}).Model(dest).Count(&total).Error; err != nil {
+		return Result{}, apperror.Internal(err)
+	}
+
+	if req.CursorMode {
+		return queryByCursor(db, dest, req, total)
+	}
+
+	if err := db.Order(order(req.Sorts, false)).Offset((req.Page - 1) * req.Limit).Limit(req.Limit).Find(dest).Error; err != nil {
+		return Result{}, apperror.Internal(err)
+	}
+	return offsetResult(req, total), nil
+}
+
+// order builds the ORDER BY clause. The primary key is always appended as a
+// tie-breaker so that pages are deterministic even when sort values repeat.
+// With reverse set, every direction is flipped (used when paging backwards).
+func order(sorts []Sort, reverse bool) string {
+	parts := []string{}
+	hasID, tieDesc := false, false
+	for _, s := range sorts {
+		parts = append(parts, s.Column+direction(s.Desc != reverse))
+		hasID = hasID || s.Column == "id"
+		tieDesc = s.Desc
+	}
+	if !hasID {
+		parts = append(parts, "id"+direction(tieDesc != reverse))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// queryByCursor implements keyset pagination on a single sort field plus the primary key.
+func queryByCursor(db *gorm.DB, dest interface{}, req Request, total int64) (Result, error) {
+	sort := req.cursorSort()
+
+	// Parse the model so the sort column's Go type is known for decoding the cursor.
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dest); err != nil {
+		return Result{}, apperror.Internal(err)
+	}
+	sortSchemaField := stmt.Schema.LookUpField(sort.Column)
+	idSchemaField := stmt.Schema.LookUpField("id")
+	if sortSchemaField == nil || idSchemaField == nil {
+		return Result{}, apperror.Internal(errors.New("unknown sort column: " + sort.Column))
+	}
+
+	query := db
+	if req.cursor != nil {
+		value, err := decodeValue(req.cursor.Value, sortSchemaField.FieldType)
+		if err != nil {
+			return Result{}, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+		}
+		id, err := decodeValue(req.cursor.ID, idSchemaField.FieldType)
+		if err != nil {
+			return Result{}, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+		}
+
+		// Rows strictly after (or before, when paging back) the cursor position in sort order.
+		op := ">"
+		if sort.Desc != req.cursor.Prev {
+			op = "<"
+		}
+		if sort.Column == "id" {
+			query = query.Where("id "+op+" ?", id)
+		} else {
+			query = query.Where(sort.Column+" "+op+" ? OR ("+sort.Column+" = ? AND id "+op+" ?)", value, value, id)
+		}
+	}
+	backwards := req.backwards()
+
+	// Fetch one extra row to find out whether there is another page.
+	if err := query.Order(order([]Sort{sort}, backwards)).Limit(req.Limit + 1).Find(dest).Error; err != nil {
+		return Result{}, apperror.Internal(err)
+	}
+
+	items := reflect.ValueOf(dest).Elem()
+	hasMore := items.Len() > req.Limit
+	if hasMore {
+		items.Set(items.Slice(0, req.Limit))
+	}
+	if backwards {
+		// Rows were fetched in reverse order; flip them back to the requested order.
+		swap := reflect.Swapper(items.Interface())
+		for i, j := 0, items.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	if items.Len() == 0 {
+		return Result{Total: total}, nil
+	}
+
+	ctx := db.Statement.Context
+	position := func(item reflect.Value) [2]interface{} {
+		value, _ := sortSchemaField.ValueOf(ctx, item)
+		id, _ := idSchemaField.ValueOf(ctx, item)
+		return [2]interface{}{value, id}
+	}
+	return cursorResult(req, total, items.Len(), hasMore, position(items.Index(0)), position(items.Index(items.Len()-1))), nil
+}
@@ -0,0 +1,139 @@
+package pagination
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+)
+
+// Field returns the value of a column for an in-memory item, e.g. field(product, "product_name").
+// It must support "id" and every column in the resource's Options.SortFields.
+type Field[T any] func(item T, column string) interface{}
+
+// Slice applies a list request to items held in memory, the way Query does in the database.
+// It is used by the in-memory repositories, so that list handlers behave the same in tests.
+// Values are compared as strings, integers or times; strings compare byte-wise.
+func Slice[T any](items []T, req Request, field Field[T]) ([]T, Result, error) {
+	total := int64(len(items))
+	sorted := append([]T(nil), items...)
+
+	if !req.CursorMode {
+		sortItems(sorted, req.Sorts, field, false)
+		start := (req.Page - 1) * req.Limit
+		if start > len(sorted) {
+			start = len(sorted)
+		}
+		end := start + req.Limit
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		return sorted[start:end], offsetResult(req, total), nil
+	}
+
+	column := req.cursorSort()
+	backwards := req.backwards()
+	sortItems(sorted, []Sort{column}, field, backwards)
+
+	if req.cursor != nil && len(sorted) > 0 {
+		value, err := decodeValue(req.cursor.Value, reflect.TypeOf(field(sorted[0], column.Column)))
+		if err != nil {
+			return nil, Result{}, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+		}
+		id, err := decodeValue(req.cursor.ID, reflect.TypeOf(field(sorted[0], "id")))
+		if err != nil {
+			return nil, Result{}, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+		}
+
+		// Keep the rows strictly after the cursor position in the direction of travel.
+		desc := column.Desc != backwards
+		remaining := []T{}
+		for _, item := range sorted {
+			cmp := compare(field(item, column.Column), value)
+			if cmp == 0 {
+				cmp = compare(field(item, "id"), id)
+			}
+			if (!desc && cmp > 0) || (desc && cmp < 0) {
+				remaining = append(remaining, item)
+			}
+		}
+		sorted = remaining
+	}
+
+	hasMore := len(sorted) > req.Limit
+	if hasMore {
+		sorted = sorted[:req.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+	if len(sorted) == 0 {
+		return sorted, Result{Total: total}, nil
+	}
+
+	position := func(item T) [2]interface{} {
+		return [2]interface{}{field(item, column.Column), field(item, "id")}
+	}
+	return sorted, cursorResult(req, total, len(sorted), hasMore, position(sorted[0]), position(sorted[len(sorted)-1])), nil
+}
+
+// sortItems orders items like the ORDER BY clause built by order.
+func sortItems[T any](items []T, sorts []Sort, field Field[T], reverse bool) {
+	hasID, tieDesc := false, false
+	for _, s := range sorts {
+		hasID = hasID || s.Column == "id"
+		tieDesc = s.Desc
+	}
+	if !hasID {
+		sorts = append(append([]Sort(nil), sorts...), Sort{Column: "id", Desc: tieDesc})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, s := range sorts {
+			cmp := compare(field(items[i], s.Column), field(items[j], s.Column))
+			if cmp == 0 {
+				continue
+			}
+			if s.Desc != reverse {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// compare orders two column values of the same type.
+func compare(a, b interface{}) int {
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch av.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(av.Int(), bv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(av.Uint(), bv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(av.Float(), bv.Float())
+	}
+	return 0
+}
+
+func compareOrdered[N int64 | uint64 | float64](a, b N) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
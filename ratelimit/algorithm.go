@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// The functions below are the algorithms as run by MemoryStore. RedisStore runs the same
+// arithmetic in Lua (see redis.go); keep the two in step.
+
+// windowState is the state of a sliding window: the request counts of the current fixed
+// window, which started at start, and of the one before it.
+type windowState struct {
+	start             time.Time
+	current, previous int
+}
+
+// slidingWindow counts a request at now if the weighted count leaves room for it.
+func slidingWindow(s *windowState, rate config.Rate, now time.Time) Result {
+	window := rate.Window
+	now = now.Round(0) // Strip the monotonic reading, so windows compare by wall clock
+	elapsed := time.Duration(now.UnixNano() % int64(window))
+	start := now.Add(-elapsed)
+	switch {
+	case s.start.Equal(start):
+	case s.start.Add(window).Equal(start): // The current window has just become the previous one
+		s.previous, s.current = s.current, 0
+	default: // Idle for a whole window or more
+		s.previous, s.current = 0, 0
+	}
+	s.start = start
+
+	limit := float64(rate.Limit)
+	count := float64(s.previous)*(1-float64(elapsed)/float64(window)) + float64(s.current)
+	if count+1 > limit {
+		return Result{RetryAfter: slidingRetryAfter(s, rate, elapsed)}
+	}
+	s.current++
+	return Result{Allowed: true, Remaining: int(limit - count - 1)}
+}
+
+// slidingRetryAfter returns how long until the weighted count of s leaves room for one
+// more request, assuming none is counted in the meantime.
+func slidingRetryAfter(s *windowState, rate config.Rate, elapsed time.Duration) time.Duration {
+	window := float64(rate.Window)
+	room := float64(rate.Limit - 1 - s.current) // Room left once the previous window has faded out
+	if room >= 0 && s.previous > 0 {
+		// The previous window's weight drops enough before this window ends.
+		return time.Duration(window*(1-room/float64(s.previous))) - elapsed
+	}
+	// Wait for the next window, where the current count becomes the fading previous one.
+	return rate.Window - elapsed + time.Duration(window*(1-float64(rate.Limit-1)/float64(s.current)))
+}
+
+// bucketState is the state of a token bucket: the tokens left at last.
+type bucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBucket refills the bucket for the time elapsed since the last request and
+// takes a token for this one, if there is one.
+func tokenBucket(s *bucketState, rate config.Rate, now time.Time) Result {
+	limit := float64(rate.Limit)
+	perSecond := limit / rate.Window.Seconds()
+	if s.last.IsZero() {
+		s.tokens = limit // A new bucket starts full
+	} else {
+		s.tokens = math.Min(limit, s.tokens+now.Sub(s.last).Seconds()*perSecond)
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return Result{RetryAfter: time.Duration((1 - s.tokens) / perSecond * float64(time.Second))}
+	}
+	s.tokens--
+	return Result{Allowed: true, Remaining: int(s.tokens)}
+}
+
+// lockoutState is the state of a lockout: the failures in a row and when the lock ends.
+type lockoutState struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+// fail records a failure at now and locks the key once policy.Threshold is reached:
+// for policy.Duration, doubled for every failure beyond it, up to policy.MaxDuration.
+func fail(s *lockoutState, policy config.Lockout, now time.Time) time.Duration {
+	s.failures++
+	if s.failures < policy.Threshold {
+		return 0
+	}
+	lock := policy.Duration
+	for i := policy.Threshold; i < s.failures && lock < policy.MaxDuration; i++ {
+		lock *= 2
+	}
+	lock = min(lock, policy.MaxDuration)
+	s.lockedUntil = now.Add(lock)
+	return lock
+}
+
+// lockoutExpiry returns when a lockout state can be forgotten: policy.ResetAfter after the
+// last failure or, when locked, after the lock ends.
+func lockoutExpiry(s *lockoutState, policy config.Lockout, now time.Time) time.Time {
+	if s.lockedUntil.After(now) {
+		return s.lockedUntil.Add(policy.ResetAfter)
+	}
+	return now.Add(policy.ResetAfter)
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// Lockout locks usernames after repeated login failures (see config.Lockout).
+//
+// It is keyed by username, whether or not such an account exists, so the answer to a
+// locked username doesn't reveal which accounts exist. Callers pass the account's own
+// username when the login resolved to one, and usernames are compared after
+// NormalizeUsername, so "bob", "Bob" and " BOB" share one count: the database may match
+// them all to the same account. A nil Lockout, or one with a threshold of 0, never locks anything.
+type Lockout struct {
+	store  Store
+	policy config.Lockout
+}
+
+// NewLockout creates a Lockout counting in store.
+func NewLockout(store Store, policy config.Lockout) *Lockout {
+	return &Lockout{store: store, policy: policy}
+}
+
+// enabled reports whether the lockout does anything.
+func (l *Lockout) enabled() bool {
+	return l != nil && l.policy.Threshold > 0
+}
+
+// LockedFor returns how long username remains locked (0 if it isn't).
+func (l *Lockout) LockedFor(ctx context.Context, username string) (time.Duration, error) {
+	if !l.enabled() {
+		return 0, nil
+	}
+	return l.store.LockedFor(ctx, key(username))
+}
+
+// Fail records a failed login for username and returns how long it is now locked for.
+func (l *Lockout) Fail(ctx context.Context, username string) (time.Duration, error) {
+	if !l.enabled() {
+		return 0, nil
+	}
+	return l.store.Fail(ctx, key(username), l.policy)
+}
+
+// Reset forgets the failed logins of username, after a successful one.
+func (l *Lockout) Reset(ctx context.Context, username string) error {
+	if !l.enabled() {
+		return nil
+	}
+	return l.store.Reset(ctx, key(username))
+}
+
+// key is the store key of a username's lockout.
+func key(username string) string {
+	return "lockout:" + NormalizeUsername(username)
+}
+
+// NormalizeUsername is the form of a username the login controls count by: trimmed and
+// lower-cased, as a case-insensitive collation (MySQL's default) compares it.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// sweepInterval is how often MemoryStore drops the state of idle keys.
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store keeping its state in the process. Each instance of the API
+// then enforces the limits on its own, so behind a load balancer a client gets the
+// limit once per instance; use RedisStore to share them.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time // Replaced in tests
+}
+
+// memoryEntry is the state of one key: a *windowState, *bucketState or *lockoutState.
+type memoryEntry struct {
+	state   interface{}
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}, now: time.Now}
+}
+
+// Allow implements Store.
+func (s *MemoryStore) Allow(ctx context.Context, algorithm, key string, rate config.Rate) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	switch algorithm {
+	case SlidingWindow:
+		state, ok := s.state(key, now).(*windowState)
+		if !ok {
+			state = &windowState{}
+		}
+		result := slidingWindow(state, rate, now)
+		s.entries[key] = &memoryEntry{state: state, expires: state.start.Add(2 * rate.Window)}
+		return result, nil
+	case TokenBucket:
+		state, ok := s.state(key, now).(*bucketState)
+		if !ok {
+			state = &bucketState{}
+		}
+		result := tokenBucket(state, rate, now)
+		s.entries[key] = &memoryEntry{state: state, expires: now.Add(rate.Window)} // Full again by then
+		return result, nil
+	}
+	return Result{}, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+}
+
+// Fail implements Store.
+func (s *MemoryStore) Fail(ctx context.Context, key string, policy config.Lockout) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	state, ok := s.state(key, now).(*lockoutState)
+	if !ok {
+		state = &lockoutState{}
+	}
+	lock := fail(state, policy, now)
+	s.entries[key] = &memoryEntry{state: state, expires: lockoutExpiry(state, policy, now)}
+	return lock, nil
+}
+
+// LockedFor implements Store.
+func (s *MemoryStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	state, ok := s.state(key, now).(*lockoutState)
+	if !ok || !state.lockedUntil.After(now) {
+		return 0, nil
+	}
+	return state.lockedUntil.Sub(now), nil
+}
+
+// Reset implements Store.
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Close implements Store. There is nothing to release.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// state returns the unexpired state of key, or nil.
+func (s *MemoryStore) state(key string, now time.Time) interface{} {
+	entry, ok := s.entries[key]
+	if !ok || !entry.expires.After(now) {
+		return nil
+	}
+	return entry.state
+}
+
+// sweep drops expired entries, at most once per sweepInterval, so keys that are never
+// seen again (e.g. one-off client IPs) don't accumulate.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if !entry.expires.After(now) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package ratelimit throttles requests and locks accounts under password guessing.
+//
+// A Limiter allows a Rate of requests per key (client IP, user ID or username, see
+// Middleware), with either of two algorithms:
+//   - SlidingWindow counts the requests of the current and the previous fixed window,
+//     weighting the previous one by how much of it still overlaps the sliding window.
+//     It spreads the limit evenly and never lets more than Rate.Limit through in any window.
+//   - TokenBucket refills a bucket of Rate.Limit tokens at Rate.Limit per Rate.Window and
+//     takes one per request, so clients can burst up to the limit after being idle.
+//
+// A Lockout locks an account after repeated login failures, for longer every time.
+//
+// The counters live in a Store: MemoryStore keeps them in the process, RedisStore in a
+// Redis-compatible server, so that every instance of the API shares the same limits.
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+)
+
+// Supported algorithms (RATE_LIMIT_ALGORITHM).
+const (
+	SlidingWindow = "sliding_window"
+	TokenBucket   = "token_bucket"
+)
+
+// Result is the outcome of counting one request.
+type Result struct {
+	Allowed    bool
+	Remaining  int           // Further requests allowed right now
+	RetryAfter time.Duration // When not allowed, how long until a request would be
+}
+
+// Store keeps the state of the limiters and lockouts. Each method is a single atomic
+// operation, so concurrent requests, and instances sharing the store, can't race each other.
+// Keys are namespaced by the caller.
+type Store interface {
+	// Allow counts a request against key with the given algorithm and rate.
+	// Rejected requests are not counted.
+	Allow(ctx context.Context, algorithm, key string, rate config.Rate) (Result, error)
+
+	// Fail records a failed attempt against key and returns how long key is now locked
+	// for (0 if it isn't), according to policy.
+	Fail(ctx context.Context, key string, policy config.Lockout) (time.Duration, error)
+
+	// LockedFor returns how long key remains locked (0 if it isn't).
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+
+	// Reset forgets the failed attempts of key.
+	Reset(ctx context.Context, key string) error
+
+	// Close releases the store's resources, e.g. its connections.
+	Close() error
+}
+
+// NewStore creates the store selected by cfg.Store. For redis, it checks that the
+// server can be reached.
+func NewStore(ctx context.Context, cfg config.RateLimit) (Store, error) {
+	if cfg.Store == "redis" {
+		return NewRedisStore(ctx, cfg.RedisURL)
+	}
+	return NewMemoryStore(), nil
+}
+
+// Limits are the limits applied by routes.SetupRoutes. A nil field disables that limit,
+// so the zero Limits doesn't limit anything.
+type Limits struct {
+	AuthPerIP        *Limiter // Every /auth/* request, by client IP
+	LoginPerUsername *Limiter // Login attempts, by the username in the request body
+	APIPerUser       *Limiter // Authenticated requests, by user ID
+	Lockout          *Lockout // Locks usernames after repeated login failures
+}
+
+// New creates the limits described by the configuration, all counting in store.
+func New(store Store, cfg config.RateLimit, lockout config.Lockout) *Limits {
+	return &Limits{
+		AuthPerIP:        NewLimiter("auth_ip", store, cfg.Algorithm, cfg.AuthPerIP),
+		LoginPerUsername: NewLimiter("login_username", store, cfg.Algorithm, cfg.LoginPerUsername),
+		APIPerUser:       NewLimiter("api_user", store, cfg.Algorithm, cfg.APIPerUser),
+		Lockout:          NewLockout(store, lockout),
+	}
+}
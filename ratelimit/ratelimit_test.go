@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// stores returns a MemoryStore and a RedisStore backed by an in-process miniredis,
+// which runs the Lua scripts, so the two implementations are held to the same tests.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redisStore, err := NewRedisStore(context.Background(), "redis://"+server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { redisStore.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "redis": redisStore}
+}
+
+func TestAllow(t *testing.T) {
+	ctx := context.Background()
+	rate := config.Rate{Limit: 3, Window: time.Hour}
+	for name, store := range stores(t) {
+		for _, algorithm := range []string{SlidingWindow, TokenBucket} {
+			t.Run(name+"/"+algorithm, func(t *testing.T) {
+				for i := 0; i < rate.Limit; i++ {
+					result, err := store.Allow(ctx, algorithm, "a", rate)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !result.Allowed || result.Remaining != rate.Limit-1-i {
+						t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, rate.Limit-1-i)
+					}
+				}
+
+				result, err := store.Allow(ctx, algorithm, "a", rate)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// A sliding window may have to wait for the next window to fade enough.
+				if result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > 2*rate.Window {
+					t.Fatalf("request over the limit = %+v, want rejected with a retry after within 2 windows", result)
+				}
+
+				if result, _ := store.Allow(ctx, algorithm, "b", rate); !result.Allowed {
+					t.Fatalf("other key = %+v, want allowed", result)
+				}
+			})
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	policy := config.Lockout{Threshold: 2, Duration: time.Minute, MaxDuration: 3 * time.Minute, ResetAfter: time.Hour}
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Locked from the threshold on, doubling every failure up to the maximum.
+			for i, want := range []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+				lock, err := store.Fail(ctx, "bob", policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if lock != want {
+					t.Fatalf("failure %d: locked for %v, want %v", i+1, lock, want)
+				}
+			}
+
+			locked, err := store.LockedFor(ctx, "bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locked <= 2*time.Minute || locked > 3*time.Minute {
+				t.Fatalf("LockedFor = %v, want about 3m", locked)
+			}
+
+			if err := store.Reset(ctx, "bob"); err != nil {
+				t.Fatal(err)
+			}
+			if locked, _ := store.LockedFor(ctx, "bob"); locked != 0 {
+				t.Fatalf("LockedFor after Reset = %v, want 0", locked)
+			}
+		})
+	}
+}
+
+// TestSlidingWindow checks the weighting of the previous window and the retry after,
+// on a MemoryStore with a fake clock.
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_000_000_020, 0) // On a whole minute
+	store.now = func() time.Time { return now }
+	rate := config.Rate{Limit: 4, Window: time.Minute}
+
+	for i := 0; i < 4; i++ {
+		store.Allow(ctx, SlidingWindow, "a", rate)
+	}
+	if result, _ := store.Allow(ctx, SlidingWindow, "a", rate); result.Allowed || result.RetryAfter != 75*time.Second {
+		// The 4 requests weigh 3 (too many) until a quarter into the next window.
+		t.Fatalf("over the limit = %+v, want rejected for 75s", result)
+	}
+
+	// Halfway into the next window, the previous one weighs 2: 2 more requests fit.
+	now = now.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Allow(ctx, SlidingWindow, "a", rate); !result.Allowed {
+			t.Fatalf("request %d in the next window = %+v, want allowed", i+1, result)
+		}
+	}
+	if result, _ := store.Allow(ctx, SlidingWindow, "a", rate); result.Allowed || result.RetryAfter != 15*time.Second {
+		// The previous window must fade to 1 (at three quarters) for one more request.
+		t.Fatalf("over the limit = %+v, want rejected for 15s", result)
+	}
+}
+
+// TestTokenBucket checks the refill, on a MemoryStore with a fake clock.
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_000_000_000, 0)
+	store.now = func() time.Time { return now }
+	rate := config.Rate{Limit: 4, Window: 4 * time.Second} // A token every second
+
+	for i := 0; i < 4; i++ {
+		store.Allow(ctx, TokenBucket, "a", rate)
+	}
+	if result, _ := store.Allow(ctx, TokenBucket, "a", rate); result.Allowed || result.RetryAfter != time.Second {
+		t.Fatalf("empty bucket = %+v, want rejected for 1s", result)
+	}
+
+	now = now.Add(2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Allow(ctx, TokenBucket, "a", rate); !result.Allowed {
+			t.Fatalf("refilled request %d = %+v, want allowed", i+1, result)
+		}
+	}
+	if result, _ := store.Allow(ctx, TokenBucket, "a", rate); result.Allowed {
+		t.Fatalf("empty bucket = %+v, want rejected", result)
+	}
+}
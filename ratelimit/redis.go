@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces every key written by RedisStore, so the server can be shared.
+const keyPrefix = "ratelimit:"
+
+// RedisStore is a Store keeping its state in a Redis-compatible server (Redis, Valkey,
+// KeyDB, Dragonfly...), shared by every instance of the API. Each operation is a Lua
+// script, which the server runs atomically.
+//
+// Times are sent by the instance (in milliseconds), so the instances' clocks should be
+// kept in sync, e.g. with NTP.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the server at url (redis://[[user]:password@]host[:port][/db],
+// or rediss:// for TLS) and checks that it answers.
+func NewRedisStore(ctx context.Context, url string) (*RedisStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting to %s: %w", options.Addr, err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Close implements Store, closing the connections to the server.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// slidingWindowScript is slidingWindow (see algorithm.go) on a hash holding the start
+// of the current window and the counts of the current and previous windows.
+// ARGV: now, window (ms), limit. Returns {allowed, remaining, retry after (ms)}.
+var slidingWindowScript = redis.NewScript(`
+local now, window, limit = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local elapsed = now % window
+local start = now - elapsed
+local state = redis.call('HMGET', KEYS[1], 'start', 'current', 'previous')
+local last, current, previous = tonumber(state[1]), tonumber(state[2]) or 0, tonumber(state[3]) or 0
+if last ~= start then
+	if last == start - window then
+		previous, current = current, 0
+	else
+		previous, current = 0, 0
+	end
+end
+
+local count = previous * (1 - elapsed / window) + current
+local allowed, remaining, retry = 0, 0, 0
+if count + 1 > limit then
+	local room = limit - 1 - current
+	if room >= 0 and previous > 0 then
+		retry = window * (1 - room / previous) - elapsed
+	else
+		retry = window - elapsed + window * (1 - (limit - 1) / current)
+	end
+else
+	current = current + 1
+	allowed, remaining = 1, math.floor(limit - count - 1)
+end
+
+redis.call('HSET', KEYS[1], 'start', start, 'current', current, 'previous', previous)
+redis.call('PEXPIRE', KEYS[1], start + 2 * window - now)
+return {allowed, remaining, math.ceil(retry)}
+`)
+
+// tokenBucketScript is tokenBucket (see algorithm.go) on a hash holding the tokens left
+// and the time of the last request.
+// ARGV: now, window (ms), limit. Returns {allowed, remaining, retry after (ms)}.
+var tokenBucketScript = redis.NewScript(`
+local now, window, limit = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local perMs = limit / window
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens, last = tonumber(state[1]), tonumber(state[2])
+if tokens == nil then
+	tokens = limit
+else
+	tokens = math.min(limit, tokens + (now - last) * perMs)
+end
+
+local allowed, retry = 0, 0
+if tokens < 1 then
+	retry = math.ceil((1 - tokens) / perMs)
+else
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// failScript is fail and lockoutExpiry (see algorithm.go) on a hash holding the number
+// of failures and when the lock ends.
+// ARGV: now, threshold, duration, max duration, reset after (ms). Returns the lock (ms).
+var failScript = redis.NewScript(`
+local now, threshold, duration, max, reset = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4]), tonumber(ARGV[5])
+local failures = redis.call('HINCRBY', KEYS[1], 'failures', 1)
+local lockedUntil = tonumber(redis.call('HGET', KEYS[1], 'locked_until')) or 0
+
+local lock = 0
+if failures >= threshold then
+	lock = duration
+	local i = threshold
+	while i < failures and lock < max do
+		lock = lock * 2
+		i = i + 1
+	end
+	lock = math.min(lock, max)
+	lockedUntil = now + lock
+	redis.call('HSET', KEYS[1], 'locked_until', lockedUntil)
+end
+
+local expires = now + reset
+if lockedUntil > now then
+	expires = lockedUntil + reset
+end
+redis.call('PEXPIRE', KEYS[1], expires - now)
+return lock
+`)
+
+// Allow implements Store.
+func (s *RedisStore) Allow(ctx context.Context, algorithm, key string, rate config.Rate) (Result, error) {
+	var script *redis.Script
+	switch algorithm {
+	case SlidingWindow:
+		script = slidingWindowScript
+	case TokenBucket:
+		script = tokenBucketScript
+	default:
+		return Result{}, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+	}
+
+	reply, err := script.Run(ctx, s.client, []string{keyPrefix + key}, time.Now().UnixMilli(), rate.Window.Milliseconds(), rate.Limit).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    reply[0] == 1,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+	}, nil
+}
+
+// Fail implements Store.
+func (s *RedisStore) Fail(ctx context.Context, key string, policy config.Lockout) (time.Duration, error) {
+	lock, err := failScript.Run(ctx, s.client, []string{keyPrefix + key},
+		time.Now().UnixMilli(), policy.Threshold, policy.Duration.Milliseconds(), policy.MaxDuration.Milliseconds(), policy.ResetAfter.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(lock) * time.Millisecond, nil
+}
+
+// LockedFor implements Store.
+func (s *RedisStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	lockedUntil, err := s.client.HGet(ctx, keyPrefix+key, "locked_until").Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return max(0, time.Until(time.UnixMilli(lockedUntil))), nil
+}
+
+// Reset implements Store.
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, keyPrefix+key).Err()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models"
+
+	"gorm.io/gorm"
+)
+
+// AccountTokenRepository records the single-use links emailed to users
+// (email verification and password reset).
+type AccountTokenRepository interface {
+	Create(ctx context.Context, token *models.AccountToken) error
+	// Use marks the token with the given ID as used. It returns false if the token is
+	// unknown, expired or already used, so a link works once even when followed twice at the same time.
+	Use(ctx context.Context, tokenID string) (bool, error)
+	// Supersede marks every unused token of a user for purpose as used, so older links stop working.
+	Supersede(ctx context.Context, userID uint, purpose string) error
+}
+
+type gormAccountTokens struct {
+	db *gorm.DB
+}
+
+func (r *gormAccountTokens) Create(ctx context.Context, token *models.AccountToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormAccountTokens) Use(ctx context.Context, tokenID string) (bool, error) {
+	// As for refresh tokens, the "used_at IS NULL" condition lets only one of two
+	// concurrent requests update the row.
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.AccountToken{}).
+		Where("token_id = ? AND used_at IS NULL AND expires_at > ?", tokenID, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormAccountTokens) Supersede(ctx context.Context, userID uint, purpose string) error {
+	return r.db.WithContext(ctx).Model(&models.AccountToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
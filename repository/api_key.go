@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository stores the API keys of users.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	// ListByUser returns every key of a user, revoked and expired ones included, newest first.
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	FindByID(ctx context.Context, id uint) (*models.APIKey, error)
+	// FindByHash returns the key with the given hash, with its user loaded.
+	// The user is left empty (ID 0) if the account has been deleted.
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id uint) error
+	// RevokeUserKeys revokes every key of a user, e.g. when the account is deleted.
+	RevokeUserKeys(ctx context.Context, userID uint) error
+	// MarkUsed records that a key was used at the given time, unless that was already
+	// recorded less than interval before, to save a write on most requests.
+	MarkUsed(ctx context.Context, id uint, at time.Time, interval time.Duration) error
+}
+
+type gormAPIKeys struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeys) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Omit("User").Create(key).Error
+}
+
+func (r *gormAPIKeys) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormAPIKeys) FindByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeys) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Preload("User").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeys) Revoke(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormAPIKeys) RevokeUserKeys(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormAPIKeys) MarkUsed(ctx context.Context, id uint, at time.Time, interval time.Duration) error {
+	// UpdateColumn leaves updated_at alone: using a key doesn't change it.
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-interval)).
+		UpdateColumn("last_used_at", at).Error
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/anpsniper/test3-bayu-be/migrations"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository reports whether the database can serve requests.
+type HealthRepository interface {
+	// Ping checks that a connection to the database can be used.
+	Ping(ctx context.Context) error
+	// PendingMigrations returns how many embedded migrations have not been applied yet.
+	// It only reads, and fails if the schema_migrations table does not exist.
+	PendingMigrations(ctx context.Context) (int, error)
+}
+
+type gormHealth struct {
+	db *gorm.DB
+}
+
+func (r *gormHealth) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (r *gormHealth) PendingMigrations(ctx context.Context) (int, error) {
+	migrator, err := migrations.New(r.db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return migrator.CountPending()
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+
+	"gorm.io/gorm"
+)
+
+// IdentityRepository stores the links between users and their accounts at OpenID Connect providers.
+type IdentityRepository interface {
+	// FindBySubject returns the identity with the given provider and subject, with its user loaded.
+	// The user is left empty (ID 0) if the account has been deleted.
+	FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	// RecordLogin stores the email address the provider reported at a login, and when it happened.
+	RecordLogin(ctx context.Context, id uint, email string, at time.Time) error
+	// Delete removes an identity for good, so its subject can be linked again.
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormIdentities struct {
+	db *gorm.DB
+}
+
+func (r *gormIdentities) FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Omit("User").Create(identity).Error
+}
+
+func (r *gormIdentities) RecordLogin(ctx context.Context, id uint, email string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
+
+func (r *gormIdentities) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.UserIdentity{}, id).Error
+}
@@ -0,0 +1,913 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NewMemory returns repositories that keep everything in memory, for tests.
+// They behave like the GORM ones where the handlers can tell: IDs are assigned
+// in insertion order, timestamps and product UUIDs are filled in, deletes are soft,
+// usernames and emails are unique, and LIKE filters ignore ASCII case as on MySQL and SQLite.
+// Transactions are not isolated and nothing is rolled back.
+func NewMemory() *Repositories {
+	store := &memoryStore{
+		products:      map[uint]models.Product{},
+		owners:        map[uint]models.Owner{},
+		users:         map[uint]models.User{},
+		sessions:      map[uint]models.Session{},
+		refreshTokens: map[uint]models.RefreshToken{},
+		accountTokens: map[uint]models.AccountToken{},
+		recoveryCodes: map[uint]models.RecoveryCode{},
+		apiKeys:       map[uint]models.APIKey{},
+		identities:    map[uint]models.UserIdentity{},
+		links:         map[[2]uint]bool{},
+		lastID:        map[string]uint{},
+	}
+	return &Repositories{
+		Products:      &memoryProducts{store},
+		Owners:        &memoryOwners{store},
+		Users:         &memoryUsers{store},
+		Sessions:      &memorySessions{store},
+		Tokens:        &memoryAccountTokens{store},
+		RecoveryCodes: &memoryRecoveryCodes{store},
+		APIKeys:       &memoryAPIKeys{store},
+		Identities:    &memoryIdentities{store},
+		Health:        memoryHealth{},
+	}
+}
+
+// memoryStore holds the tables shared by the in-memory repositories.
+// Records are stored by value without their associations, so callers can't change them behind the store's back.
+type memoryStore struct {
+	mu            sync.Mutex
+	products      map[uint]models.Product
+	owners        map[uint]models.Owner
+	users         map[uint]models.User
+	sessions      map[uint]models.Session
+	refreshTokens map[uint]models.RefreshToken
+	accountTokens map[uint]models.AccountToken
+	recoveryCodes map[uint]models.RecoveryCode
+	apiKeys       map[uint]models.APIKey
+	identities    map[uint]models.UserIdentity
+	links         map[[2]uint]bool // products_owners rows as (product ID, owner ID)
+	lastID        map[string]uint  // Last ID assigned per table
+}
+
+// create fills in the gorm.Model fields of a new record.
+func (s *memoryStore) create(table string, model *gorm.Model) {
+	s.lastID[table]++
+	now := time.Now()
+	model.ID = s.lastID[table]
+	model.CreatedAt, model.UpdatedAt = now, now
+}
+
+// softDelete sets DeletedAt like GORM's Delete does for models with a DeletedAt field.
+func softDelete(model *gorm.Model) {
+	model.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+// sortedValues returns the records that are not soft deleted, ordered by ID.
+func sortedValues[T any](table map[uint]T, model func(T) gorm.Model) []T {
+	values := []T{}
+	for _, value := range table {
+		if !model(value).DeletedAt.Valid {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return model(values[i]).ID < model(values[j]).ID })
+	return values
+}
+
+// hasPrefix and contains emulate the case-insensitive LIKE filters.
+func hasPrefix(value, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix))
+}
+
+func contains(value, part string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(part))
+}
+
+func productModel(p models.Product) gorm.Model { return p.Model }
+func ownerModel(o models.Owner) gorm.Model     { return o.Model }
+func userModel(u models.User) gorm.Model       { return u.Model }
+
+// ownersOf returns the owners linked to a product. The caller must hold the lock.
+func (s *memoryStore) ownersOf(productID uint) []models.Owner {
+	owners := []models.Owner{}
+	for _, owner := range sortedValues(s.owners, ownerModel) {
+		if s.links[[2]uint{productID, owner.ID}] {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// productsOf returns the products linked to an owner. The caller must hold the lock.
+func (s *memoryStore) productsOf(ownerID uint) []models.Product {
+	products := []models.Product{}
+	for _, product := range sortedValues(s.products, productModel) {
+		if s.links[[2]uint{product.ID, ownerID}] {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
+// --- Products ---
+
+type memoryProducts struct {
+	*memoryStore
+}
+
+func (r *memoryProducts) List(ctx context.Context, filter ProductFilter, req pagination.Request) ([]models.Product, pagination.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matching := []models.Product{}
+	for _, p := range sortedValues(r.products, productModel) {
+		if (filter.Brand != "" && p.ProductBrand != filter.Brand) ||
+			(filter.NamePrefix != "" && !hasPrefix(p.ProductName, filter.NamePrefix)) ||
+			(filter.NameContains != "" && !contains(p.ProductName, filter.NameContains)) ||
+			(filter.CreatedFrom != nil && p.CreatedDate.Before(*filter.CreatedFrom)) ||
+			(filter.CreatedTo != nil && !p.CreatedDate.Before(*filter.CreatedTo)) {
+			continue
+		}
+		matching = append(matching, p)
+	}
+
+	page, result, err := pagination.Slice(matching, req, func(p models.Product, column string) interface{} {
+		switch column {
+		case "product_name":
+			return p.ProductName
+		case "product_brand":
+			return p.ProductBrand
+		case "created_date":
+			return p.CreatedDate
+		}
+		return p.ID
+	})
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	if filter.IncludeOwners {
+		for i := range page {
+			page[i].Owners = r.ownersOf(page[i].ID)
+		}
+	}
+	return page, result, nil
+}
+
+func (r *memoryProducts) FindByUUID(ctx context.Context, publicID string, withOwners bool) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range sortedValues(r.products, productModel) {
+		if p.UUID == publicID {
+			if withOwners {
+				p.Owners = r.ownersOf(p.ID)
+			}
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProducts) Create(ctx context.Context, product *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.create("products", &product.Model)
+	if product.UUID == "" {
+		product.UUID = uuid.NewString()
+	}
+	if product.CreatedDate.IsZero() {
+		product.CreatedDate = product.CreatedAt
+	}
+	stored := *product
+	stored.Owners = nil
+	r.products[product.ID] = stored
+	return nil
+}
+
+func (r *memoryProducts) Update(ctx context.Context, product *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product.UpdatedAt = time.Now()
+	stored := *product
+	stored.Owners = nil
+	r.products[product.ID] = stored
+	return nil
+}
+
+func (r *memoryProducts) Delete(ctx context.Context, product *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[product.ID]
+	if !ok {
+		return nil
+	}
+	softDelete(&stored.Model)
+	r.products[product.ID] = stored
+	product.DeletedAt = stored.DeletedAt
+	return nil
+}
+
+func (r *memoryProducts) AddOwner(ctx context.Context, product *models.Product, owner *models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.links[[2]uint{product.ID, owner.ID}] = true
+	return nil
+}
+
+func (r *memoryProducts) RemoveOwner(ctx context.Context, product *models.Product, owner *models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.links, [2]uint{product.ID, owner.ID})
+	return nil
+}
+
+func (r *memoryProducts) ReplaceOwners(ctx context.Context, product *models.Product, owners []models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for link := range r.links {
+		if link[0] == product.ID {
+			delete(r.links, link)
+		}
+	}
+	for _, owner := range owners {
+		r.links[[2]uint{product.ID, owner.ID}] = true
+	}
+	return nil
+}
+
+// --- Owners ---
+
+type memoryOwners struct {
+	*memoryStore
+}
+
+func (r *memoryOwners) List(ctx context.Context, filter OwnerFilter, req pagination.Request) ([]models.Owner, pagination.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matching := []models.Owner{}
+	for _, o := range sortedValues(r.owners, ownerModel) {
+		if (filter.NamePrefix != "" && !hasPrefix(o.OwnerName, filter.NamePrefix)) ||
+			(filter.NameContains != "" && !contains(o.OwnerName, filter.NameContains)) {
+			continue
+		}
+		matching = append(matching, o)
+	}
+
+	return pagination.Slice(matching, req, func(o models.Owner, column string) interface{} {
+		switch column {
+		case "owner_name":
+			return o.OwnerName
+		case "created_at":
+			return o.CreatedAt
+		}
+		return o.ID
+	})
+}
+
+func (r *memoryOwners) FindByID(ctx context.Context, id uint, withProducts bool) (*models.Owner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner, ok := r.owners[id]
+	if !ok || owner.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	if withProducts {
+		owner.Products = r.productsOf(id)
+	}
+	return &owner, nil
+}
+
+func (r *memoryOwners) FindByIDs(ctx context.Context, ids []uint) ([]models.Owner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	owners := []models.Owner{}
+	for _, owner := range sortedValues(r.owners, ownerModel) {
+		if wanted[owner.ID] {
+			owners = append(owners, owner)
+		}
+	}
+	return owners, nil
+}
+
+func (r *memoryOwners) Create(ctx context.Context, owner *models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.create("owners", &owner.Model)
+	stored := *owner
+	stored.Products = nil
+	r.owners[owner.ID] = stored
+	return nil
+}
+
+func (r *memoryOwners) Update(ctx context.Context, owner *models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner.UpdatedAt = time.Now()
+	stored := *owner
+	stored.Products = nil
+	r.owners[owner.ID] = stored
+	return nil
+}
+
+func (r *memoryOwners) Delete(ctx context.Context, owner *models.Owner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.owners[owner.ID]
+	if !ok {
+		return nil
+	}
+	softDelete(&stored.Model)
+	r.owners[owner.ID] = stored
+	owner.DeletedAt = stored.DeletedAt
+	return nil
+}
+
+// --- Users ---
+
+type memoryUsers struct {
+	*memoryStore
+}
+
+func (r *memoryUsers) List(ctx context.Context, filter UserFilter, req pagination.Request) ([]models.User, pagination.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matching := []models.User{}
+	for _, u := range sortedValues(r.users, userModel) {
+		if (filter.UsernamePrefix != "" && !hasPrefix(u.Username, filter.UsernamePrefix)) ||
+			(filter.Role != "" && u.Role != filter.Role) {
+			continue
+		}
+		matching = append(matching, u)
+	}
+
+	return pagination.Slice(matching, req, func(u models.User, column string) interface{} {
+		switch column {
+		case "username":
+			return u.Username
+		case "email":
+			return u.Email
+		case "created_at":
+			return u.CreatedAt
+		}
+		return u.ID
+	})
+}
+
+func (r *memoryUsers) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUsers) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range sortedValues(r.users, userModel) {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range sortedValues(r.users, userModel) {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) ExistsByUsername(ctx context.Context, username string, excludeID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users { // Including soft-deleted ones, as the unique index does
+		if user.Username == username && user.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUsers) ExistsByEmail(ctx context.Context, email string, excludeID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email && user.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkUnique mimics the unique indexes on users.username and users.email,
+// which also cover soft-deleted rows. The caller must hold the lock.
+func (r *memoryUsers) checkUnique(user *models.User) error {
+	for _, other := range r.users {
+		if other.ID == user.ID {
+			continue
+		}
+		if other.Username == user.Username {
+			return fmt.Errorf("duplicate username %q", user.Username)
+		}
+		if other.Email == user.Email {
+			return fmt.Errorf("duplicate email %q", user.Email)
+		}
+	}
+	return nil
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insert(user)
+}
+
+func (r *memoryUsers) CreateFirstAsAdmin(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.users) == 0 { // Including soft-deleted ones
+		user.Role = models.RoleAdmin
+	}
+	return r.insert(user)
+}
+
+// insert stores a new user. The caller must hold the lock.
+func (r *memoryUsers) insert(user *models.User) error {
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	r.create("users", &user.Model)
+	if user.Role == "" {
+		user.Role = models.RoleUser // Column default
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUsers) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUsers) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	r.users[userID] = user
+	return true, nil
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	softDelete(&stored.Model)
+	r.users[user.ID] = stored
+	user.DeletedAt = stored.DeletedAt
+	return nil
+}
+
+// --- Sessions ---
+
+type memorySessions struct {
+	*memoryStore
+}
+
+func (r *memorySessions) CreateSession(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.create("sessions", &session.Model)
+	stored := *session
+	stored.User, stored.RefreshTokens = models.User{}, nil
+	r.sessions[session.ID] = stored
+	return nil
+}
+
+func (r *memorySessions) FindSession(ctx context.Context, id uint) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessions) RevokeSession(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	r.sessions[id] = session
+	return nil
+}
+
+func (r *memorySessions) RevokeUserSessions(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			r.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (r *memorySessions) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			return fmt.Errorf("duplicate refresh token hash")
+		}
+	}
+	r.create("refresh_tokens", &token.Model)
+	stored := *token
+	stored.Session = models.Session{}
+	r.refreshTokens[token.ID] = stored
+	return nil
+}
+
+func (r *memorySessions) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refreshTokens {
+		if token.TokenHash != tokenHash || token.DeletedAt.Valid {
+			continue
+		}
+		token.Session = r.sessions[token.SessionID]
+		if user, ok := r.users[token.Session.UserID]; ok && !user.DeletedAt.Valid {
+			token.Session.User = user
+		}
+		return &token, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memorySessions) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.refreshTokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	r.refreshTokens[id] = token
+	return true, nil
+}
+
+func (r *memorySessions) Transaction(ctx context.Context, fn func(sessions SessionRepository) error) error {
+	return fn(r)
+}
+
+// --- Account tokens ---
+
+type memoryAccountTokens struct {
+	*memoryStore
+}
+
+func (r *memoryAccountTokens) Create(ctx context.Context, token *models.AccountToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.accountTokens {
+		if other.TokenID == token.TokenID {
+			return fmt.Errorf("duplicate account token ID %q", token.TokenID)
+		}
+	}
+	r.create("account_tokens", &token.Model)
+	stored := *token
+	stored.User = models.User{}
+	r.accountTokens[token.ID] = stored
+	return nil
+}
+
+func (r *memoryAccountTokens) Use(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.accountTokens {
+		if token.TokenID != tokenID || token.DeletedAt.Valid || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+			continue
+		}
+		token.UsedAt = &now
+		r.accountTokens[id] = token
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryAccountTokens) Supersede(ctx context.Context, userID uint, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.accountTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+			r.accountTokens[id] = token
+		}
+	}
+	return nil
+}
+
+// --- Recovery codes ---
+
+type memoryRecoveryCodes struct {
+	*memoryStore
+}
+
+func (r *memoryRecoveryCodes) Replace(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, code := range r.recoveryCodes {
+		if code.UserID == userID {
+			delete(r.recoveryCodes, id)
+		}
+	}
+	for i := range codes {
+		codes[i].UserID = userID
+		r.create("recovery_codes", &codes[i].Model)
+		stored := codes[i]
+		stored.User = models.User{}
+		r.recoveryCodes[stored.ID] = stored
+	}
+	return nil
+}
+
+func (r *memoryRecoveryCodes) Use(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, code := range r.recoveryCodes {
+		if code.UserID != userID || code.CodeHash != codeHash || code.UsedAt != nil {
+			continue
+		}
+		now := time.Now()
+		code.UsedAt = &now
+		r.recoveryCodes[id] = code
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryRecoveryCodes) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, code := range r.recoveryCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// --- API keys ---
+
+type memoryAPIKeys struct {
+	*memoryStore
+}
+
+func (r *memoryAPIKeys) Create(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.apiKeys {
+		if other.KeyHash == key.KeyHash {
+			return fmt.Errorf("duplicate API key hash")
+		}
+	}
+	r.create("api_keys", &key.Model)
+	stored := *key
+	stored.User = models.User{}
+	stored.Scopes = append([]string(nil), key.Scopes...)
+	r.apiKeys[key.ID] = stored
+	return nil
+}
+
+func (r *memoryAPIKeys) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := []models.APIKey{}
+	for _, key := range sortedValues(r.apiKeys, func(k models.APIKey) gorm.Model { return k.Model }) {
+		if key.UserID == userID {
+			keys = append([]models.APIKey{key}, keys...) // Newest first
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) FindByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok || key.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeys) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.apiKeys {
+		if key.KeyHash != keyHash || key.DeletedAt.Valid {
+			continue
+		}
+		if user, ok := r.users[key.UserID]; ok && !user.DeletedAt.Valid {
+			key.User = user
+		}
+		return &key, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAPIKeys) Revoke(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	r.apiKeys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeys) RevokeUserKeys(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, key := range r.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &now
+			r.apiKeys[id] = key
+		}
+	}
+	return nil
+}
+
+func (r *memoryAPIKeys) MarkUsed(ctx context.Context, id uint, at time.Time, interval time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok || (key.LastUsedAt != nil && !key.LastUsedAt.Before(at.Add(-interval))) {
+		return nil
+	}
+	key.LastUsedAt = &at
+	r.apiKeys[id] = key
+	return nil
+}
+
+// --- Identities ---
+
+type memoryIdentities struct {
+	*memoryStore
+}
+
+func (r *memoryIdentities) FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider != provider || identity.Subject != subject || identity.DeletedAt.Valid {
+			continue
+		}
+		if user, ok := r.users[identity.UserID]; ok && !user.DeletedAt.Valid {
+			identity.User = user
+		}
+		return &identity, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.identities {
+		if other.Provider == identity.Provider && other.Subject == identity.Subject {
+			return fmt.Errorf("duplicate identity %s/%s", identity.Provider, identity.Subject)
+		}
+	}
+	r.create("user_identities", &identity.Model)
+	stored := *identity
+	stored.User = models.User{}
+	r.identities[identity.ID] = stored
+	return nil
+}
+
+func (r *memoryIdentities) RecordLogin(ctx context.Context, id uint, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if identity, ok := r.identities[id]; ok {
+		identity.Email = email
+		identity.LastLoginAt = &at
+		r.identities[id] = identity
+	}
+	return nil
+}
+
+func (r *memoryIdentities) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.identities, id)
+	return nil
+}
+
+// memoryHealth is always healthy: there is no connection to lose and no schema to migrate.
+type memoryHealth struct{}
+
+func (memoryHealth) Ping(ctx context.Context) error { return nil }
+
+func (memoryHealth) PendingMigrations(ctx context.Context) (int, error) { return 0, nil }
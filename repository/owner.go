@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination"
+
+	"gorm.io/gorm"
+)
+
+// OwnerFilter narrows down an owner listing. Zero values mean "no filter".
+type OwnerFilter struct {
+	NamePrefix   string // Owner name starts with the value
+	NameContains string // Owner name contains the value
+}
+
+// OwnerRepository stores owners.
+type OwnerRepository interface {
+	List(ctx context.Context, filter OwnerFilter, req pagination.Request) ([]models.Owner, pagination.Result, error)
+	// FindByID looks an owner up by ID, optionally with the products linked to it.
+	FindByID(ctx context.Context, id uint, withProducts bool) (*models.Owner, error)
+	// FindByIDs returns the owners with the given IDs; missing IDs are skipped.
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Owner, error)
+	Create(ctx context.Context, owner *models.Owner) error
+	Update(ctx context.Context, owner *models.Owner) error
+	Delete(ctx context.Context, owner *models.Owner) error
+}
+
+type gormOwners struct {
+	db *gorm.DB
+}
+
+func (r *gormOwners) List(ctx context.Context, filter OwnerFilter, req pagination.Request) ([]models.Owner, pagination.Result, error) {
+	query := r.db.WithContext(ctx)
+	if filter.NamePrefix != "" {
+		query = query.Where("owner_name LIKE ? ESCAPE '"+pagination.LikeEscape+"'", pagination.Prefix(filter.NamePrefix))
+	}
+	if filter.NameContains != "" {
+		query = query.Where("owner_name LIKE ? ESCAPE '"+pagination.LikeEscape+"'", pagination.Contains(filter.NameContains))
+	}
+
+	owners := []models.Owner{}
+	result, err := pagination.Query(query, &owners, req)
+	return owners, result, err
+}
+
+func (r *gormOwners) FindByID(ctx context.Context, id uint, withProducts bool) (*models.Owner, error) {
+	query := r.db.WithContext(ctx)
+	if withProducts {
+		query = query.Preload("Products")
+	}
+	var owner models.Owner
+	if err := query.First(&owner, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &owner, nil
+}
+
+func (r *gormOwners) FindByIDs(ctx context.Context, ids []uint) ([]models.Owner, error) {
+	owners := []models.Owner{}
+	if len(ids) == 0 {
+		return owners, nil
+	}
+	err := r.db.WithContext(ctx).Find(&owners, ids).Error
+	return owners, err
+}
+
+func (r *gormOwners) Create(ctx context.Context, owner *models.Owner) error {
+	return r.db.WithContext(ctx).Create(owner).Error
+}
+
+func (r *gormOwners) Update(ctx context.Context, owner *models.Owner) error {
+	return r.db.WithContext(ctx).Save(owner).Error
+}
+
+func (r *gormOwners) Delete(ctx context.Context, owner *models.Owner) error {
+	return r.db.WithContext(ctx).Delete(owner).Error
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination"
+
+	"gorm.io/gorm"
+)
+
+// ProductFilter narrows down a product listing. Zero values mean "no filter".
+type ProductFilter struct {
+	Brand         string     // Exact brand match
+	NamePrefix    string     // Product name starts with the value
+	NameContains  string     // Product name contains the value
+	CreatedFrom   *time.Time // created_date on or after
+	CreatedTo     *time.Time // created_date before
+	IncludeOwners bool       // Load each product's owners
+}
+
+// ProductRepository stores products and their links to owners (the products_owners table).
+type ProductRepository interface {
+	List(ctx context.Context, filter ProductFilter, req pagination.Request) ([]models.Product, pagination.Result, error)
+	// FindByUUID looks a product up by its public UUID, optionally with its owners.
+	FindByUUID(ctx context.Context, publicID string, withOwners bool) (*models.Product, error)
+	Create(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, product *models.Product) error
+	Delete(ctx context.Context, product *models.Product) error
+
+	// AddOwner links an owner to a product; linking an owner twice is a no-op.
+	AddOwner(ctx context.Context, product *models.Product, owner *models.Owner) error
+	// RemoveOwner unlinks an owner from a product, keeping both records.
+	RemoveOwner(ctx context.Context, product *models.Product, owner *models.Owner) error
+	// ReplaceOwners makes owners the complete set of owners of a product, atomically.
+	ReplaceOwners(ctx context.Context, product *models.Product, owners []models.Owner) error
+}
+
+type gormProducts struct {
+	db *gorm.DB
+}
+
+func (r *gormProducts) List(ctx context.Context, filter ProductFilter, req pagination.Request) ([]models.Product, pagination.Result, error) {
+	query := r.db.WithContext(ctx)
+	if filter.IncludeOwners {
+		query = query.Preload("Owners")
+	}
+	if filter.Brand != "" {
+		query = query.Where("product_brand = ?", filter.Brand)
+	}
+	if filter.NamePrefix != "" {
+		query = query.Where("product_name LIKE ? ESCAPE '"+pagination.LikeEscape+"'", pagination.Prefix(filter.NamePrefix))
+	}
+	if filter.NameContains != "" {
+		query = query.Where("product_name LIKE ? ESCAPE '"+pagination.LikeEscape+"'", pagination.Contains(filter.NameContains))
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_date >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_date < ?", *filter.CreatedTo)
+	}
+
+	products := []models.Product{}
+	result, err := pagination.Query(query, &products, req)
+	return products, result, err
+}
+
+func (r *gormProducts) FindByUUID(ctx context.Context, publicID string, withOwners bool) (*models.Product, error) {
+	query := r.db.WithContext(ctx)
+	if withOwners {
+		query = query.Preload("Owners")
+	}
+	var product models.Product
+	if err := query.Where("uuid = ?", publicID).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+func (r *gormProducts) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Create(product).Error
+}
+
+func (r *gormProducts) Update(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Save(product).Error
+}
+
+func (r *gormProducts) Delete(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Delete(product).Error
+}
+
+func (r *gormProducts) AddOwner(ctx context.Context, product *models.Product, owner *models.Owner) error {
+	return r.db.WithContext(ctx).Model(product).Association("Owners").Append(owner)
+}
+
+func (r *gormProducts) RemoveOwner(ctx context.Context, product *models.Product, owner *models.Owner) error {
+	return r.db.WithContext(ctx).Model(product).Association("Owners").Delete(owner)
+}
+
+func (r *gormProducts) ReplaceOwners(ctx context.Context, product *models.Product, owners []models.Owner) error {
+	// Replace inserts the new links and deletes the old ones in separate statements.
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(product).Association("Owners").Replace(owners)
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository stores the hashed two-factor recovery codes of users.
+type RecoveryCodeRepository interface {
+	// Replace deletes every recovery code of a user and stores codes instead (none disables them all).
+	Replace(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+	// Use marks the user's unused code with the given hash as used. It returns false if
+	// there is no such code, so a code works once even when sent twice at the same time.
+	Use(ctx context.Context, userID uint, codeHash string) (bool, error)
+	// CountUnused counts the codes of a user that have not been used yet.
+	CountUnused(ctx context.Context, userID uint) (int64, error)
+}
+
+type gormRecoveryCodes struct {
+	db *gorm.DB
+}
+
+func (r *gormRecoveryCodes) Replace(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Deleted for good rather than soft deleted: an old code must never match again.
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		for i := range codes {
+			codes[i].UserID = userID
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *gormRecoveryCodes) Use(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormRecoveryCodes) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,52 @@
+// Package repository hides the database behind one interface per resource,
+// so the services and handlers can run against GORM in production and against
+// the in-memory fakes from NewMemory in tests.
+//
+// Repositories return ErrNotFound for missing records and plain errors otherwise;
+// turning them into API errors is up to the services package.
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a looked-up record does not exist (or has been soft deleted).
+var ErrNotFound = errors.New("record not found")
+
+// Repositories bundles every repository the application needs.
+type Repositories struct {
+	Products      ProductRepository
+	Owners        OwnerRepository
+	Users         UserRepository
+	Sessions      SessionRepository
+	Tokens        AccountTokenRepository
+	RecoveryCodes RecoveryCodeRepository
+	APIKeys       APIKeyRepository
+	Identities    IdentityRepository
+	Health        HealthRepository
+}
+
+// NewGorm returns repositories backed by the given database connection (usually database.DB).
+func NewGorm(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Products:      &gormProducts{db: db},
+		Owners:        &gormOwners{db: db},
+		Users:         &gormUsers{db: db},
+		Sessions:      &gormSessions{db: db},
+		Tokens:        &gormAccountTokens{db: db},
+		RecoveryCodes: &gormRecoveryCodes{db: db},
+		APIKeys:       &gormAPIKeys{db: db},
+		Identities:    &gormIdentities{db: db},
+		Health:        &gormHealth{db: db},
+	}
+}
+
+// notFound converts GORM's ErrRecordNotFound into ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository stores login sessions and their refresh tokens.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	FindSession(ctx context.Context, id uint) (*models.Session, error)
+	// RevokeSession marks a session as revoked; revoking it again keeps the first timestamp.
+	RevokeSession(ctx context.Context, id uint) error
+	// RevokeUserSessions revokes every session of a user, e.g. after a password reset.
+	RevokeUserSessions(ctx context.Context, userID uint) error
+
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// FindRefreshToken looks a refresh token up by its hash, with Session.User loaded.
+	// Session.User has a zero ID if the account has been deleted since.
+	FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// MarkRefreshTokenUsed records that a refresh token has been rotated.
+	// It returns false if the token had already been used, which means it was presented twice.
+	MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error)
+
+	// Transaction runs fn with a repository whose changes are committed only if fn returns nil.
+	Transaction(ctx context.Context, fn func(sessions SessionRepository) error) error
+}
+
+type gormSessions struct {
+	db *gorm.DB
+}
+
+func (r *gormSessions) CreateSession(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormSessions) FindSession(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessions) RevokeSession(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormSessions) RevokeUserSessions(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormSessions) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormSessions) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).Preload("Session.User").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+func (r *gormSessions) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	// The "used_at IS NULL" condition makes this safe against two concurrent
+	// requests presenting the same token: only one of them updates the row.
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormSessions) Transaction(ctx context.Context, fn func(sessions SessionRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormSessions{db: tx})
+	})
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"github.com/anpsniper/test3-bayu-be/models" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserFilter narrows down a user listing. Zero values mean "no filter".
+type UserFilter struct {
+	UsernamePrefix string // Username starts with the value
+	Role           string // Exact role match
+}
+
+// UserRepository stores user accounts.
+type UserRepository interface {
+	List(ctx context.Context, filter UserFilter, req pagination.Request) ([]models.User, pagination.Result, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// ExistsByUsername reports whether an account other than excludeID uses the username.
+	// Pass 0 as excludeID to check every account. Soft-deleted accounts count: the unique
+	// indexes on username and email cover them too.
+	ExistsByUsername(ctx context.Context, username string, excludeID uint) (bool, error)
+	// ExistsByEmail is the email counterpart of ExistsByUsername.
+	ExistsByEmail(ctx context.Context, email string, excludeID uint) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	// CreateFirstAsAdmin is Create, except that user becomes an admin if no account, not even
+	// a soft-deleted one, has ever existed. The check and the insert are atomic, so two
+	// concurrent calls on an empty table can't both create an admin.
+	CreateFirstAsAdmin(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	// UseTOTPStep records step as the time step of the last TOTP code accepted for a user.
+	// It returns false if a code of that step or a later one was already accepted, so a code
+	// works only once even when sent twice at the same time.
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	Delete(ctx context.Context, user *models.User) error
+}
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) List(ctx context.Context, filter UserFilter, req pagination.Request) ([]models.User, pagination.Result, error) {
+	query := r.db.WithContext(ctx)
+	if filter.UsernamePrefix != "" {
+		query = query.Where("username LIKE ? ESCAPE '"+pagination.LikeEscape+"'", pagination.Prefix(filter.UsernamePrefix))
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	users := []models.User{}
+	result, err := pagination.Query(query, &users, req)
+	return users, result, err
+}
+
+func (r *gormUsers) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) ExistsByUsername(ctx context.Context, username string, excludeID uint) (bool, error) {
+	return r.exists(ctx, "username", username, excludeID)
+}
+
+func (r *gormUsers) ExistsByEmail(ctx context.Context, email string, excludeID uint) (bool, error) {
+	return r.exists(ctx, "email", email, excludeID)
+}
+
+// exists counts the accounts other than excludeID whose column equals value.
+// column is always a constant from this file, never user input.
+func (r *gormUsers) exists(ctx context.Context, column, value string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where(column+" = ? AND id <> ?", value, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUsers) CreateFirstAsAdmin(ctx context.Context, user *models.User) error {
+	// Accounts are never hard-deleted, so once one exists there is nothing left to race for
+	// and the common case needs no lock.
+	if exists, err := anyUser(r.db.WithContext(ctx)); err != nil {
+		return err
+	} else if exists {
+		return r.Create(ctx, user)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx
+		switch tx.Dialector.Name() {
+		case "postgres":
+			// Row locks can't cover rows that don't exist yet. This mode conflicts with itself
+			// and with inserts but not with plain reads.
+			if err := tx.Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		case "mysql":
+			// On an empty table InnoDB locks the gap instead; a concurrent transaction doing the
+			// same deadlocks on its insert and is rolled back.
+			query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		// SQLite needs neither: it allows a single writer, and the second transaction to write
+		// after both have read fails with SQLITE_BUSY.
+		exists, err := anyUser(query)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			user.Role = models.RoleAdmin
+		}
+		return tx.Create(user).Error
+	})
+}
+
+// anyUser reports whether at least one account, soft-deleted or not, exists.
+func anyUser(query *gorm.DB) (bool, error) {
+	var ids []uint
+	err := query.Unscoped().Model(&models.User{}).Limit(1).Pluck("id", &ids).Error
+	return len(ids) > 0, err
+}
+
+func (r *gormUsers) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *gormUsers) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUsers) Delete(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Delete(user).Error
+}
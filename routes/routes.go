@@ -3,39 +3,142 @@ package routes
 import (
 	// IMPORTANT: Replace "github.com/anpsniper/test3-bayu-be" with your actual Go module name
 	"github.com/anpsniper/test3-bayu-be/controllers" // Import your controllers package
+	"github.com/anpsniper/test3-bayu-be/logging"     // Import your logging package
+	"github.com/anpsniper/test3-bayu-be/metrics"     // Import your metrics package
 	"github.com/anpsniper/test3-bayu-be/middlewares" // Import your middlewares package
+	"github.com/anpsniper/test3-bayu-be/models"      // Import your models package (for permission names)
+	"github.com/anpsniper/test3-bayu-be/ratelimit"   // Import your ratelimit package
+	"github.com/anpsniper/test3-bayu-be/repository"  // Import your repository package
+	"github.com/anpsniper/test3-bayu-be/services"    // Import your services package
+	"github.com/anpsniper/test3-bayu-be/tracing"     // Import your tracing package
 
 	"github.com/gofiber/fiber/v2" // Import the Fiber framework
 )
 
 // SetupRoutes configures all the API endpoints for the Fiber application.
-// It takes a *fiber.App instance as an argument to register the routes.
-func SetupRoutes(app *fiber.App) {
+// It builds the services and handlers on top of the given repositories
+// (repository.NewGorm in production, repository.NewMemory in tests) and registers them on app.
+// health is passed in rather than built here because main also uses it, to fail the
+// readiness probe while the server shuts down. limits throttle the auth endpoints and
+// authenticated users (&ratelimit.Limits{} disables them). accounts sends the email
+// verification and password reset links, through the mailer configured in main,
+// twoFactor holds the TOTP settings from the configuration and oidc the identity providers.
+func SetupRoutes(app *fiber.App, repos *repository.Repositories, health *services.HealthService, limits *ratelimit.Limits, accounts *services.AccountService, twoFactor *services.TwoFactorService, oidc *services.OIDCService) {
+	// Give every request an ID (X-Request-ID, generated if the client didn't send a valid one).
+	// It is echoed in error responses and logged with every record of the request, so a
+	// failure can be matched to the server logs.
+	app.Use(middlewares.RequestID())
+
+	// Count every request and time it, labelled with the route pattern (see metrics.Middleware).
+	app.Use(metrics.Middleware())
+
+	// Start a span for every request, continuing the caller's trace if it sent a traceparent
+	// header. Handlers pass its context down, so the SQL statements become child spans.
+	app.Use(tracing.Middleware())
+
+	// Log every request once it has been handled, with its trace ID (see logging.AccessLog).
+	app.Use(logging.AccessLog())
+
+	// Handlers get their dependencies injected instead of using a global database connection.
+	authService := services.NewAuthService(repos.Users, repos.Sessions, limits.Lockout, accounts, twoFactor)
+	authHandler := controllers.NewAuthHandler(authService)
+	oidcHandler := controllers.NewOIDCHandler(oidc, authService)
+	accountHandler := controllers.NewAccountHandler(accounts)
+	twoFactorHandler := controllers.NewTwoFactorHandler(twoFactor)
+	productHandler := controllers.NewProductHandler(services.NewProductService(repos.Products, repos.Owners))
+	ownerHandler := controllers.NewOwnerHandler(services.NewOwnerService(repos.Owners))
+	userHandler := controllers.NewUserHandler(services.NewUserService(repos.Users, repos.Sessions, repos.APIKeys))
+	healthHandler := controllers.NewHealthHandler(health)
+	apiKeys := services.NewAPIKeyService(repos.Users, repos.APIKeys)
+	apiKeyHandler := controllers.NewAPIKeyHandler(apiKeys)
+	authRequired := middlewares.JWTAuthRequired(repos.Sessions, apiKeys) // Also accepts "ApiKey <key>"
+	sessionRequired := middlewares.SessionRequired()                     // Runs after authRequired; rejects API keys
+	perUser := limits.APIPerUser.Middleware(ratelimit.ByUserID)          // Runs after authRequired, which sets the user ID
+
+	// --- Probes and build information ---
+	// Public so load balancers and orchestrators can call them without a token.
+	app.Get("/healthz", healthHandler.Healthz) // Liveness: the process is up
+	app.Get("/readyz", healthHandler.Readyz)   // Readiness: database reachable, schema up to date, not shutting down
+	app.Get("/version", healthHandler.Version) // Version, git commit, build time and Go version
+	app.Get("/metrics", metrics.Handler())     // Prometheus metrics (keep it reachable from the scraper only)
+
 	// --- Public Routes (Authentication) ---
-	// These routes do not require any authentication middleware.
-	authGroup := app.Group("/auth")                   // Create a group for authentication-related routes
-	authGroup.Post("/register", controllers.Register) // Route for user registration
-	authGroup.Post("/login", controllers.Login)       // Route for user login
+	// These routes do not require any authentication middleware, but are throttled per client IP
+	// so they can't be used to guess passwords or mass-create accounts. Login is also throttled
+	// per username, whatever the IP, and AuthService locks usernames after repeated failures.
+	authGroup := app.Group("/auth", limits.AuthPerIP.Middleware(ratelimit.ByIP))                          // Create a group for authentication-related routes
+	authGroup.Post("/register", authHandler.Register)                                                     // Route for user registration
+	authGroup.Post("/login", limits.LoginPerUsername.Middleware(ratelimit.ByUsername), authHandler.Login) // Route for user login
+	authGroup.Post("/refresh", authHandler.Refresh)                                                       // Route for rotating a refresh token
+	authGroup.Post("/logout", authHandler.Logout)                                                         // Route for revoking a session
+	authGroup.Get("/verify", accountHandler.VerifyEmail)                                                  // Route followed from an email verification link
+	authGroup.Post("/verify", accountHandler.VerifyEmail)                                                 // Same, with the token in the body
+	authGroup.Post("/forgot-password", accountHandler.ForgotPassword)                                     // Route for emailing a password reset link
+	authGroup.Post("/reset-password", accountHandler.ResetPassword)                                       // Route for choosing a new password with that link
+	authGroup.Post("/2fa/verify", authHandler.VerifyTwoFactor)                                            // Route for the second step of a login with two-factor authentication
+	authGroup.Get("/oidc", oidcHandler.Providers)                                                         // Route listing the identity providers users can log in with
+	authGroup.Get("/oidc/:provider/login", oidcHandler.Login)                                             // Route redirecting to an identity provider to log in
+	authGroup.Get("/oidc/:provider/callback", oidcHandler.Callback)                                       // Route the identity provider redirects back to
 
-	// --- Protected Routes (Require JWT authentication) ---
-	// All routes within these groups will first pass through the JWTAuthRequired middleware.
+	// Two-factor authentication settings of the authenticated user. The middlewares are set
+	// per route, since a group would also apply them to the public /auth/2fa/verify.
+	authGroup.Get("/2fa", authRequired, perUser, sessionRequired, twoFactorHandler.Status)                                  // Whether it is enabled, and the recovery codes left
+	authGroup.Post("/2fa/enroll", authRequired, perUser, sessionRequired, twoFactorHandler.Enroll)                          // New TOTP secret, as an otpauth:// URI and a QR code
+	authGroup.Post("/2fa/confirm", authRequired, perUser, sessionRequired, twoFactorHandler.Confirm)                        // Enable it with a first code; returns the recovery codes
+	authGroup.Post("/2fa/recovery-codes", authRequired, perUser, sessionRequired, twoFactorHandler.RegenerateRecoveryCodes) // Replace the recovery codes
+	authGroup.Delete("/2fa", authRequired, perUser, sessionRequired, twoFactorHandler.Disable)                              // Disable it with a code
+
+	// Public keys for verifying access tokens (empty when using an HS256 shared secret)
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+
+	// --- Protected Routes (Require JWT authentication or an API key) ---
+	// All routes within these groups will first pass through the JWTAuthRequired middleware,
+	// then through the per-user rate limit, then through RequirePermission, which checks
+	// the permissions carried by the token (or the scopes of the API key).
+	can := middlewares.RequirePermission // Short alias to keep the route table readable
 
 	// Product routes group
 	productGroup := app.Group("/products")
-	productGroup.Use(middlewares.JWTAuthRequired)          // Apply JWT authentication to all product routes
-	productGroup.Post("/", controllers.CreateProduct)      // Create a new product
-	productGroup.Get("/", controllers.GetProducts)         // Get all products
-	productGroup.Get("/:id", controllers.GetProductByID)   // Get a single product by ID
-	productGroup.Put("/:id", controllers.UpdateProduct)    // Update an existing product by ID
-	productGroup.Delete("/:id", controllers.DeleteProduct) // Delete a product by ID
+	productGroup.Use(authRequired, perUser)                                                     // Apply JWT authentication to all product routes
+	productGroup.Post("/", can(models.PermProductsWrite), productHandler.CreateProduct)         // Create a new product
+	productGroup.Get("/", can(models.PermProductsRead), productHandler.GetProducts)             // Get all products
+	productGroup.Get("/:uuid", can(models.PermProductsRead), productHandler.GetProductByID)     // Get a single product by UUID
+	productGroup.Put("/:uuid", can(models.PermProductsWrite), productHandler.UpdateProduct)     // Update an existing product by UUID
+	productGroup.Delete("/:uuid", can(models.PermProductsDelete), productHandler.DeleteProduct) // Delete a product by UUID
+
+	// Product-owner associations (products_owners join table)
+	productGroup.Put("/:uuid/owners", can(models.PermProductsWrite), productHandler.ReplaceProductOwners)           // Replace all owners of a product
+	productGroup.Post("/:uuid/owners/:ownerId", can(models.PermProductsWrite), productHandler.AddProductOwner)      // Link an owner to a product
+	productGroup.Delete("/:uuid/owners/:ownerId", can(models.PermProductsWrite), productHandler.RemoveProductOwner) // Unlink an owner from a product
+
+	// Owner routes group
+	ownerGroup := app.Group("/owners")
+	ownerGroup.Use(authRequired, perUser)                                                      // Apply JWT authentication to all owner routes
+	ownerGroup.Post("/", can(models.PermOwnersWrite), ownerHandler.CreateOwner)                // Create a new owner
+	ownerGroup.Get("/", can(models.PermOwnersRead), ownerHandler.GetOwners)                    // Get all owners
+	ownerGroup.Get("/:id", can(models.PermOwnersRead), ownerHandler.GetOwnerByID)              // Get a single owner by ID
+	ownerGroup.Get("/:id/products", can(models.PermOwnersRead), ownerHandler.GetOwnerProducts) // Get the products linked to an owner
+	ownerGroup.Put("/:id", can(models.PermOwnersWrite), ownerHandler.UpdateOwner)              // Update an existing owner by ID
+	ownerGroup.Delete("/:id", can(models.PermOwnersDelete), ownerHandler.DeleteOwner)          // Delete an owner by ID
 
 	// User routes group (excluding the public register/login routes)
+	// Update and delete are not guarded by a permission because users may always manage
+	// their own account; the controllers require PermUsersManage for other accounts.
 	userGroup := app.Group("/users")
-	userGroup.Use(middlewares.JWTAuthRequired)       // Apply JWT authentication to all user routes
-	userGroup.Get("/", controllers.GetUsers)         // Get all users
-	userGroup.Get("/:id", controllers.GetUserByID)   // Get a single user by ID
-	userGroup.Put("/:id", controllers.UpdateUser)    // Update an existing user by ID
-	userGroup.Delete("/:id", controllers.DeleteUser) // Delete a user by ID
+	userGroup.Use(authRequired, perUser)                                               // Apply JWT authentication to all user routes
+	userGroup.Get("/", can(models.PermUsersRead), userHandler.GetUsers)                // Get all users
+	userGroup.Get("/:id", can(models.PermUsersRead), userHandler.GetUserByID)          // Get a single user by ID
+	userGroup.Put("/:id", sessionRequired, userHandler.UpdateUser)                     // Update an existing user by ID
+	userGroup.Put("/:id/role", can(models.PermUsersRoles), userHandler.UpdateUserRole) // Change a user's role
+	userGroup.Delete("/:id", sessionRequired, userHandler.DeleteUser)                  // Delete a user by ID
+
+	// Personal API keys of the authenticated user. Managing them requires a login session,
+	// so a key can't be used to create more keys or to outlive its own revocation.
+	apiKeyGroup := app.Group("/api-keys")
+	apiKeyGroup.Use(authRequired, perUser, sessionRequired) // Apply JWT authentication to all API key routes
+	apiKeyGroup.Post("/", apiKeyHandler.CreateAPIKey)       // Create a key; the response is the only time it is shown
+	apiKeyGroup.Get("/", apiKeyHandler.GetAPIKeys)          // List the keys, with their scopes, expiry and last use
+	apiKeyGroup.Delete("/:id", apiKeyHandler.RevokeAPIKey)  // Revoke a key by ID
 
 	// --- Basic Root Route ---
 	// This is a simple public route to confirm the API is running.
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// serve runs the HTTP server until it fails to start or the process receives SIGINT
+// (Ctrl+C) or SIGTERM (sent by Docker, Kubernetes and systemd when stopping a service).
+//
+// On a signal the readiness probe starts failing and, after cfg.ShutdownDelay (time for
+// the load balancer to notice and stop routing new requests here), the server stops
+// accepting new connections and waits up to cfg.ShutdownTimeout for in-flight requests
+// to complete, so a deploy doesn't cut them off. serve returns once the server is stopped;
+// the caller then releases whatever the handlers were using (e.g. the database connection pool).
+func serve(app *fiber.App, cfg config.App, health *services.HealthService) {
+	addr := ":" + strconv.Itoa(cfg.Port)
+
+	// Listen in the background so this goroutine can wait for a signal.
+	// Listen/ListenTLS return nil once the server has been shut down.
+	listenErr := make(chan error, 1)
+	go func() {
+		if cfg.TLSCertFile != "" {
+			slog.Info("Server is starting", "port", cfg.Port, "tls", true)
+			listenErr <- app.ListenTLS(addr, cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+		slog.Info("Server is starting", "port", cfg.Port, "tls", false)
+		listenErr <- app.Listen(addr)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-listenErr:
+		// The server never started (e.g. port already in use or unreadable certificate).
+		fatal("Failed to start server", "error", err)
+	case sig := <-signals:
+		slog.Info("Shutting down, waiting for in-flight requests", "signal", sig.String(), "timeout", cfg.ShutdownTimeout.String())
+	}
+
+	health.StartDraining()
+	if cfg.ShutdownDelay > 0 {
+		slog.Info("Readiness probe is failing, waiting before closing the listener", "delay", cfg.ShutdownDelay.String())
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	// A timeout of 0 waits for every request, however long it takes.
+	var err error
+	if cfg.ShutdownTimeout > 0 {
+		err = app.ShutdownWithTimeout(cfg.ShutdownTimeout)
+	} else {
+		err = app.Shutdown()
+	}
+	if err != nil {
+		slog.Warn("Graceful shutdown did not complete", "error", err)
+	}
+	<-listenErr
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"
+	"github.com/anpsniper/test3-bayu-be/config"
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"
+	"github.com/anpsniper/test3-bayu-be/mail"
+	"github.com/anpsniper/test3-bayu-be/models"
+	"github.com/anpsniper/test3-bayu-be/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Bodies of the emails sent by AccountService: the username, the link and how long it works.
+const (
+	verifyEmailText = `Hello %s,
+
+Please confirm that this is your email address by opening the link below:
+
+%s
+
+The link works once and expires in %s. If you did not create an account, you can ignore this email.
+`
+	resetPasswordText = `Hello %s,
+
+Someone, hopefully you, asked to reset the password of your account. To choose a new one, open the link below:
+
+%s
+
+The link works once and expires in %s. If you did not ask for it, you can ignore this email: your password has not been changed.
+`
+)
+
+// AccountService emails the email verification and password reset links of accounts,
+// and acts on them when they are followed.
+//
+// A link carries a token signed with the JWT keys (see jwtkeys), stating its purpose, the
+// account and its address, an expiry and an ID recorded in the account_tokens table.
+// The signature means a token can't be forged or altered; the record makes it work once.
+// Sending a new link of the same purpose disables the previous ones.
+type AccountService struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   repository.AccountTokenRepository
+	mailer   mail.Mailer
+	cfg      config.Account
+}
+
+// NewAccountService creates an AccountService sending its emails through mailer.
+func NewAccountService(users repository.UserRepository, sessions repository.SessionRepository, tokens repository.AccountTokenRepository, mailer mail.Mailer, cfg config.Account) *AccountService {
+	return &AccountService{users: users, sessions: sessions, tokens: tokens, mailer: mailer, cfg: cfg}
+}
+
+// VerificationRequired reports whether accounts can only log in once their email address is verified.
+func (s *AccountService) VerificationRequired() bool {
+	return s.cfg.RequireVerifiedEmail
+}
+
+// SendVerification emails user a link to verify their address.
+func (s *AccountService) SendVerification(ctx context.Context, user *models.User) error {
+	link, err := s.link(ctx, user, models.PurposeVerifyEmail, s.cfg.VerifyEmailURL, s.cfg.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return s.send(ctx, user, "Verify your email address", fmt.Sprintf(verifyEmailText, user.Username, link, humanDuration(s.cfg.VerifyEmailTTL)))
+}
+
+// VerifyEmail marks the address of a verification link as verified.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	user, err := s.use(ctx, token, models.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.users.Update(ctx, user); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// ForgotPassword emails a password reset link to the account using email, if there is one.
+// The outcome is the same whether or not there is, so that the endpoint can't be used to
+// find out which addresses have an account: failures to send are only logged, and the
+// server's mailer (a mail.Queue) sends in the background, so the response doesn't wait
+// for the mail server either.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	link, err := s.link(ctx, user, models.PurposeResetPassword, s.cfg.ResetPasswordURL, s.cfg.ResetPasswordTTL)
+	if err == nil {
+		err = s.send(ctx, user, "Reset your password", fmt.Sprintf(resetPasswordText, user.Username, link, humanDuration(s.cfg.ResetPasswordTTL)))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send a password reset link", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for the account of a reset link.
+// Every session of the account is revoked, in case the old password was known to someone else.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	user, err := s.use(ctx, token, models.PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	user.Password = hashedPassword
+	// The link was sent to the account's current address, so following it proves the address too.
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.users.Update(ctx, user); err != nil {
+		return apperror.Internal(err)
+	}
+
+	if err := s.tokens.Supersede(ctx, user.ID, models.PurposeResetPassword); err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.sessions.RevokeUserSessions(ctx, user.ID); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// link issues a token for user and returns the URL base with the token appended.
+func (s *AccountService) link(ctx context.Context, user *models.User, purpose, base string, ttl time.Duration) (string, error) {
+	if jwtkeys.Keys == nil {
+		return "", apperror.Internal(errors.New("JWT signing keys not loaded"))
+	}
+	if err := s.tokens.Supersede(ctx, user.ID, purpose); err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	record := models.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenID:   uuid.NewString(),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokens.Create(ctx, &record); err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	// The address is part of the token so that a link stops working if the address changes.
+	// Access tokens have no "purpose" claim, and these tokens no "sid" claim, so neither
+	// can be used as the other.
+	token, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+		"purpose": purpose,
+		"user_id": user.ID,
+		"email":   user.Email,
+		"jti":     record.TokenID,
+		"exp":     record.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// use checks a token for purpose and marks it as used, returning its account.
+func (s *AccountService) use(ctx context.Context, token, purpose string) (*models.User, error) {
+	if jwtkeys.Keys == nil {
+		return nil, apperror.Internal(errors.New("JWT verification keys not loaded"))
+	}
+	invalid := apperror.BadRequest(apperror.CodeAccountTokenInvalid, "Invalid link; please request a new one")
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, jwtkeys.Keys.Keyfunc, jwt.WithExpirationRequired())
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, apperror.BadRequest(apperror.CodeAccountTokenExpired, "This link has expired; please request a new one")
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	tokenPurpose, _ := claims["purpose"].(string)
+	userID, _ := claims["user_id"].(float64)
+	email, _ := claims["email"].(string)
+	tokenID, _ := claims["jti"].(string)
+	if tokenPurpose != purpose || tokenID == "" {
+		return nil, invalid
+	}
+
+	user, err := s.users.FindByID(ctx, uint(userID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, invalid // The account has been deleted since
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if user.Email != email {
+		return nil, invalid // The address has changed since
+	}
+
+	used, err := s.tokens.Use(ctx, tokenID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if !used {
+		return nil, apperror.BadRequest(apperror.CodeAccountTokenInvalid, "This link has already been used or replaced by a newer one")
+	}
+	return user, nil
+}
+
+// send emails text to user.
+func (s *AccountService) send(ctx context.Context, user *models.User, subject, text string) error {
+	return s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: subject, Text: text})
+}
+
+// humanDuration formats a link lifetime for an email, e.g. "48 hours" or "30 minutes".
+func humanDuration(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d == time.Minute:
+		return "1 minute"
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/validation" // Adjust import path to your module name
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognise (for instance by
+// secret scanners) and JWTAuthRequired can tell them from access tokens.
+const apiKeyPrefix = "ak_"
+
+// apiKeyUseInterval is how often the last use of a key is recorded at most.
+const apiKeyUseInterval = time.Minute
+
+// APIKeyService manages the personal API keys with which scripts and other machine
+// clients call the API as their user, with an "Authorization: ApiKey <key>" header.
+//
+// A key carries a subset of the permissions of its user's role (its scopes) and may
+// expire. Only a hash of the key is stored, so the key is returned once, by Create.
+type APIKeyService struct {
+	users repository.UserRepository
+	keys  repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates an APIKeyService.
+func NewAPIKeyService(users repository.UserRepository, keys repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{users: users, keys: keys}
+}
+
+// Create issues a new key for a user and returns it with the key itself.
+// Every scope must be a permission the user's role grants.
+func (s *APIKeyService) Create(ctx context.Context, userID uint, req *dto.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, "", apperror.Unauthorized(apperror.CodeUnauthorized, "Authenticated user not found")
+	}
+	if err != nil {
+		return nil, "", apperror.Internal(err)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", apperror.Validation([]validation.FieldError{{Field: "expires_at", Rule: "future", Message: "must be in the future"}})
+	}
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !models.ValidPermission(scope) {
+			return nil, "", apperror.Validation([]validation.FieldError{{Field: "scopes", Rule: "permission", Message: "unknown permission " + scope}})
+		}
+		if !models.RoleHasPermission(user.Role, scope) {
+			return nil, "", apperror.Forbidden(apperror.CodePermissionDenied, "Your role does not grant the permission "+scope)
+		}
+		if !contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", apperror.Internal(err)
+	}
+	plaintext := apiKeyPrefix + token
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:   hashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.keys.Create(ctx, key); err != nil {
+		return nil, "", apperror.Internal(err)
+	}
+	return key, plaintext, nil
+}
+
+// List returns the keys of a user, newest first. Revoked and expired keys are included.
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	keys, err := s.keys.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return keys, nil
+}
+
+// Revoke stops a key of the user from working. Revoking a revoked key does nothing.
+func (s *APIKeyService) Revoke(ctx context.Context, userID uint, id string) error {
+	numericID, err := parseID(id, "API key")
+	if err != nil {
+		return err
+	}
+
+	key, err := s.keys.FindByID(ctx, numericID)
+	// Keys of other users are reported as missing, so their IDs can't be probed.
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && key.UserID != userID) {
+		return apperror.NotFound(apperror.CodeAPIKeyNotFound, "API key not found")
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.keys.Revoke(ctx, key.ID); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// Authenticate returns the key matching the given plaintext, with its user loaded,
+// if the key is usable, and records that it was used.
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (*models.APIKey, error) {
+	key, err := s.keys.FindByHash(ctx, hashToken(plaintext))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperror.Unauthorized(apperror.CodeAPIKeyInvalid, "Invalid API key")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	// The key of a deleted account is as good as unknown.
+	if key.User.ID == 0 {
+		return nil, apperror.Unauthorized(apperror.CodeAPIKeyInvalid, "Invalid API key")
+	}
+	if key.Revoked() {
+		return nil, apperror.Unauthorized(apperror.CodeAPIKeyRevoked, "API key has been revoked")
+	}
+	now := time.Now()
+	if key.Expired(now) {
+		return nil, apperror.Unauthorized(apperror.CodeAPIKeyExpired, "API key has expired")
+	}
+
+	// Failing to record the use is no reason to reject the request.
+	if err := s.keys.MarkUsed(ctx, key.ID, now, apiKeyUseInterval); err != nil {
+		slog.ErrorContext(ctx, "Failed to record the use of an API key", "api_key_id", key.ID, "error", err)
+	}
+	return key, nil
+}
+
+// contains reports whether values contains value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/metrics"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/ratelimit"  // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+)
+
+// AuthService registers accounts and manages their login sessions.
+type AuthService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	lockout   *ratelimit.Lockout
+	accounts  *AccountService
+	twoFactor *TwoFactorService
+}
+
+// NewAuthService creates an AuthService. lockout locks usernames after repeated
+// failed logins (or second factors); nil disables it. accounts sends the email
+// verification links, and twoFactor checks the second factor of the accounts that enabled it.
+func NewAuthService(users repository.UserRepository, sessions repository.SessionRepository, lockout *ratelimit.Lockout, accounts *AccountService, twoFactor *TwoFactorService) *AuthService {
+	return &AuthService{users: users, sessions: sessions, lockout: lockout, accounts: accounts, twoFactor: twoFactor}
+}
+
+// Register creates a new account, emails it a verification link, starts a session for it
+// and returns both. When a verified email address is required to log in, no session is
+// started and the returned TokenPair is nil.
+// The very first account becomes an admin; every later one starts as a regular user.
+func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*models.User, *TokenPair, error) {
+	user := req.ToModel()
+
+	// Check if a user with the same username or email already exists to prevent duplicates.
+	// The two cases get different error codes so clients can point at the offending field.
+	if taken, err := s.users.ExistsByUsername(ctx, user.Username, 0); err != nil {
+		return nil, nil, apperror.Internal(err)
+	} else if taken {
+		return nil, nil, apperror.Conflict(apperror.CodeUsernameTaken, "Username already exists")
+	}
+	if taken, err := s.users.ExistsByEmail(ctx, user.Email, 0); err != nil {
+		return nil, nil, apperror.Internal(err)
+	} else if taken {
+		return nil, nil, apperror.Conflict(apperror.CodeEmailTaken, "Email already exists")
+	}
+
+	// Hash the plaintext password before storing it in the database for security.
+	hashedPassword, err := HashPassword(user.Password)
+	if err != nil {
+		return nil, nil, apperror.Internal(err)
+	}
+	user.Password = hashedPassword
+
+	// Never trust a role sent by the client; new accounts start as regular users.
+	// The very first account becomes an admin so a fresh installation can be managed
+	// without touching the database (see also the "promote-admin" command in main.go).
+	// Soft-deleted accounts count too, so deleting every user doesn't reopen this path.
+	user.Role = models.RoleUser
+	if err := s.users.CreateFirstAsAdmin(ctx, user); err != nil {
+		return nil, nil, apperror.Internal(err)
+	}
+
+	// A failure to send is only logged: the account exists either way, and logging in
+	// with an unverified address sends a new link.
+	if err := s.accounts.SendVerification(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "Failed to send an email verification link", "user_id", user.ID, "error", err)
+	}
+	if s.accounts.VerificationRequired() {
+		return user, nil, nil
+	}
+
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// Login checks a username and password and starts a new session.
+// For an account with two-factor authentication enabled, no session is started yet:
+// Login returns a challenge instead, for VerifyTwoFactor, and a nil TokenPair.
+func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*TokenPair, *TwoFactorChallenge, error) {
+	user, lookupErr := s.users.FindByUsername(ctx, req.Username)
+	// Failures count against the account the database matched, however its name was
+	// typed (collations may ignore case); unknown names count under the name typed.
+	lockName := req.Username
+	if lookupErr == nil {
+		lockName = user.Username
+	}
+
+	// A locked username is refused before its password is even checked, so guessing
+	// can't go on during the lock. Lockout errors (e.g. Redis down) don't block logins.
+	locked, err := s.lockout.LockedFor(ctx, lockName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check the login lockout", "error", err)
+	}
+	if locked > 0 {
+		metrics.Logins.WithLabelValues("locked").Inc()
+		return nil, nil, apperror.TooManyRequests(apperror.CodeAccountLocked, "Too many failed login attempts, please retry later", locked)
+	}
+
+	if lookupErr != nil {
+		// Whether the user is missing or the lookup failed, the answer is the same.
+		// Using a generic "Invalid credentials" message is better for security
+		// as it doesn't reveal whether the username or password was incorrect.
+		return nil, nil, s.loginFailed(ctx, lockName)
+	}
+
+	if !CheckPasswordHash(req.Password, user.Password) {
+		return nil, nil, s.loginFailed(ctx, lockName)
+	}
+
+	if s.accounts.VerificationRequired() && user.EmailVerifiedAt == nil {
+		// The password is right, so this is the account's owner: send them a new link,
+		// in case the first one got lost or expired.
+		if err := s.accounts.SendVerification(ctx, user); err != nil {
+			slog.ErrorContext(ctx, "Failed to send an email verification link", "user_id", user.ID, "error", err)
+		}
+		metrics.Logins.WithLabelValues("unverified").Inc()
+		return nil, nil, apperror.Forbidden(apperror.CodeEmailNotVerified, "Email address not verified; a new verification link has been sent")
+	}
+
+	if user.TwoFactorEnabled() {
+		// The lockout is only reset once the second factor is right too, so knowing
+		// the password doesn't give unlimited guesses at the code.
+		return s.challenge(user)
+	}
+
+	tokens, err := s.loginSucceeded(ctx, user)
+	return tokens, nil, err
+}
+
+// FederatedLogin starts a new session for a user authenticated by an identity provider
+// (see OIDCService), which stands in for the password. As with Login, an account with
+// two-factor authentication enabled gets a challenge for VerifyTwoFactor instead.
+func (s *AuthService) FederatedLogin(ctx context.Context, user *models.User) (*TokenPair, *TwoFactorChallenge, error) {
+	if user.TwoFactorEnabled() {
+		return s.challenge(user)
+	}
+	tokens, err := s.loginSucceeded(ctx, user)
+	return tokens, nil, err
+}
+
+// challenge returns the two-factor challenge of a user who passed the first factor.
+func (s *AuthService) challenge(user *models.User) (*TokenPair, *TwoFactorChallenge, error) {
+	challenge, err := s.twoFactor.Challenge(user)
+	if err != nil {
+		metrics.Logins.WithLabelValues("error").Inc()
+		return nil, nil, err
+	}
+	metrics.Logins.WithLabelValues("two_factor_required").Inc()
+	return nil, challenge, nil
+}
+
+// VerifyTwoFactor completes a login that returned a challenge: given the challenge token
+// and a TOTP or recovery code, it starts a new session. Wrong codes count towards the
+// lockout of the username, like wrong passwords.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, req *dto.TwoFactorVerifyRequest) (*TokenPair, error) {
+	user, err := s.twoFactor.ChallengeUser(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := s.lockout.LockedFor(ctx, user.Username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check the login lockout", "error", err)
+	}
+	if locked > 0 {
+		metrics.Logins.WithLabelValues("locked").Inc()
+		return nil, apperror.TooManyRequests(apperror.CodeAccountLocked, "Too many failed login attempts, please retry later", locked)
+	}
+
+	ok, err := s.twoFactor.Authenticate(ctx, user, req.Code)
+	if err != nil {
+		metrics.Logins.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	if !ok {
+		s.recordFailure(ctx, user.Username, "invalid_two_factor")
+		return nil, apperror.Unauthorized(apperror.CodeTwoFactorCodeInvalid, "Invalid authentication or recovery code")
+	}
+	return s.loginSucceeded(ctx, user)
+}
+
+// loginSucceeded resets the lockout of a user who got their credentials right and starts a session.
+func (s *AuthService) loginSucceeded(ctx context.Context, user *models.User) (*TokenPair, error) {
+	if err := s.lockout.Reset(ctx, user.Username); err != nil {
+		slog.ErrorContext(ctx, "Failed to reset the login lockout", "error", err)
+	}
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		metrics.Logins.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.Logins.WithLabelValues("success").Inc()
+	return tokens, nil
+}
+
+// loginFailed counts a failed login towards the lockout of username and returns the
+// error for it. The failure that triggers a lock still gets the usual 401; the lock
+// applies from the next attempt on.
+func (s *AuthService) loginFailed(ctx context.Context, username string) error {
+	s.recordFailure(ctx, username, "invalid_credentials")
+	return apperror.Unauthorized(apperror.CodeInvalidCredentials, "Invalid credentials")
+}
+
+// recordFailure counts a failed login in the metrics, under result, and towards the lockout of username.
+func (s *AuthService) recordFailure(ctx context.Context, username, result string) {
+	metrics.Logins.WithLabelValues(result).Inc()
+	if _, err := s.lockout.Fail(ctx, username); err != nil {
+		slog.ErrorContext(ctx, "Failed to record a failed login", "error", err)
+	}
+}
+
+// startSession creates a new session (token family) for the user and issues its first token pair.
+func (s *AuthService) startSession(ctx context.Context, user *models.User) (*TokenPair, error) {
+	var tokens *TokenPair
+	err := s.sessions.Transaction(ctx, func(sessions repository.SessionRepository) error {
+		session := models.Session{UserID: user.ID}
+		if err := sessions.CreateSession(ctx, &session); err != nil {
+			return apperror.Internal(err)
+		}
+
+		var err error
+		tokens, err = issueTokenPair(ctx, sessions, user, session.ID)
+		return err
+	})
+	return tokens, err
+}
+
+// Refresh exchanges a refresh token for a new token pair.
+// Each refresh token can be used exactly once: it is rotated on every call.
+// If an already-rotated token is presented again, the token was most likely
+// stolen, so the whole session is revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	var tokens *TokenPair
+	reuseDetected := false
+
+	err := s.sessions.Transaction(ctx, func(sessions repository.SessionRepository) error {
+		record, err := sessions.FindRefreshToken(ctx, hashToken(refreshToken))
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.Unauthorized(apperror.CodeRefreshTokenInvalid, "Invalid refresh token")
+		}
+		if err != nil {
+			return apperror.Internal(err)
+		}
+
+		if record.Session.Revoked() {
+			return apperror.Unauthorized(apperror.CodeSessionRevoked, "Session has been revoked")
+		}
+		if record.Session.User.ID == 0 {
+			// The account was deleted after the session was started.
+			return apperror.Unauthorized(apperror.CodeSessionRevoked, "User no longer exists")
+		}
+		if time.Now().After(record.ExpiresAt) {
+			return apperror.Unauthorized(apperror.CodeRefreshTokenExpired, "Refresh token has expired")
+		}
+
+		marked, err := sessions.MarkRefreshTokenUsed(ctx, record.ID)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+		if !marked {
+			reuseDetected = true
+			if err := sessions.RevokeSession(ctx, record.SessionID); err != nil {
+				return apperror.Internal(err)
+			}
+			// Returning nil commits the revocation; the 401 is returned below.
+			return nil
+		}
+
+		// The new access token picks up the user's current role, so role changes apply on the next refresh.
+		tokens, err = issueTokenPair(ctx, sessions, &record.Session.User, record.SessionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reuseDetected {
+		return nil, apperror.Unauthorized(apperror.CodeRefreshTokenReused, "Refresh token reuse detected; session revoked")
+	}
+	return tokens, nil
+}
+
+// Logout revokes the session (token family) that a refresh token belongs to.
+// Unknown tokens are ignored so that logging out is idempotent.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	record, err := s.sessions.FindRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	if err := s.sessions.RevokeSession(ctx, record.SessionID); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/repository"
+)
+
+// pingTimeout bounds the database check of a readiness probe, so a hung connection
+// makes the probe fail instead of piling up requests.
+const pingTimeout = 2 * time.Second
+
+// Readiness is the outcome of a readiness check.
+// Checks maps each check ("shutdown", "database", "migrations") to "ok" or the reason it failed.
+// The endpoint is public, so reasons are fixed texts; the underlying errors are only logged.
+type Readiness struct {
+	Ready  bool              `json:"-"`
+	Status string            `json:"status"` // "ready" or "unavailable"
+	Checks map[string]string `json:"checks"`
+}
+
+// HealthService tells load balancers and orchestrators whether the process is able to
+// serve traffic. It is shared with main, which marks it as draining on shutdown.
+type HealthService struct {
+	health   repository.HealthRepository
+	draining atomic.Bool
+}
+
+// NewHealthService creates a HealthService.
+func NewHealthService(health repository.HealthRepository) *HealthService {
+	return &HealthService{health: health}
+}
+
+// StartDraining makes every following readiness check fail, so the load balancer stops
+// sending new requests while the in-flight ones finish. It cannot be undone.
+func (s *HealthService) StartDraining() {
+	s.draining.Store(true)
+}
+
+// Ready checks that the server is not shutting down, that the database answers and that
+// its schema is up to date. Every check runs, so the report shows all failures at once.
+func (s *HealthService) Ready(ctx context.Context) Readiness {
+	r := Readiness{Ready: true, Status: "ready", Checks: map[string]string{}}
+	fail := func(check, reason string) {
+		r.Ready, r.Status = false, "unavailable"
+		r.Checks[check] = reason
+	}
+
+	r.Checks["shutdown"] = "ok"
+	if s.draining.Load() {
+		fail("shutdown", "server is shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	r.Checks["database"] = "ok"
+	if err := s.health.Ping(ctx); err != nil {
+		slog.ErrorContext(ctx, "Readiness check: database unreachable", "error", err)
+		fail("database", "unreachable")
+	}
+
+	r.Checks["migrations"] = "ok"
+	if pending, err := s.health.PendingMigrations(ctx); err != nil {
+		slog.ErrorContext(ctx, "Readiness check: failed to check migrations", "error", err)
+		fail("migrations", "check failed")
+	} else if pending > 0 {
+		fail("migrations", fmt.Sprintf("%d pending migration(s)", pending))
+	}
+	return r
+}
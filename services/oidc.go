@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/config"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/metrics"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// purposeOIDC is the "purpose" claim of the state tokens that carry a login through an
+// identity provider from OIDCService.Start to OIDCService.Finish.
+const purposeOIDC = "oidc_login"
+
+// oidcTimeout bounds every request to an identity provider (discovery, keys, code exchange).
+const oidcTimeout = 10 * time.Second
+
+// usernameUnwanted matches what usernames may not contain (see dto.RegisterRequest).
+var usernameUnwanted = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// OIDCLogin is the start of a login through an identity provider: the user is sent to URL,
+// and State, which holds what Finish checks, must come back with them to the callback.
+type OIDCLogin struct {
+	URL       string
+	State     string
+	ExpiresIn time.Duration
+}
+
+// OIDCService logs users in through OpenID Connect identity providers, with the
+// authorization code flow and PKCE.
+//
+// Each provider's endpoints and signing keys are discovered from its issuer on first use.
+// The ID token returned for the code is checked (signature, issuer, audience, expiry and
+// nonce) and its subject is looked up among the identities linked to users. An unknown
+// subject is linked to the user with the same email address, provided the provider has
+// verified it, or to a new account if provisioning is enabled.
+type OIDCService struct {
+	users      repository.UserRepository
+	identities repository.IdentityRepository
+	cfg        config.OIDC
+	client     *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*oidcProvider // Discovered so far, by name
+}
+
+// oidcProvider is a discovered identity provider.
+type oidcProvider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcState is what the state token of a login holds.
+type oidcState struct {
+	provider string
+	state    string // Sent to the provider, which sends it back to the callback
+	nonce    string // Sent to the provider, which puts it in the ID token
+	verifier string // PKCE code verifier; the provider only got its hash
+}
+
+// oidcClaims are the claims of an ID token used besides the subject.
+type oidcClaims struct {
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // A boolean, or "true"/"false" for some providers
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// NewOIDCService creates an OIDCService for the providers in cfg.
+// Nothing is requested from the providers until a user logs in with them.
+func NewOIDCService(users repository.UserRepository, identities repository.IdentityRepository, cfg config.OIDC) *OIDCService {
+	return &OIDCService{
+		users:      users,
+		identities: identities,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: oidcTimeout},
+		providers:  map[string]*oidcProvider{},
+	}
+}
+
+// Providers returns the names of the configured providers, in configuration order.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.cfg.Providers))
+	for _, p := range s.cfg.Providers {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// SecureCookies reports whether the cookies of the login flow must only be sent over HTTPS,
+// which is the case whenever the API is reached over HTTPS.
+func (s *OIDCService) SecureCookies() bool {
+	return strings.HasPrefix(s.cfg.RedirectBaseURL, "https://")
+}
+
+// Start begins a login through the named provider.
+func (s *OIDCService) Start(ctx context.Context, name string) (*OIDCLogin, error) {
+	p, err := s.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if jwtkeys.Keys == nil {
+		return nil, apperror.Internal(errors.New("JWT signing keys not loaded"))
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	// The state token is signed, not encrypted: it only travels in a cookie of the browser
+	// that started the login, which is also the only party entitled to the PKCE verifier.
+	// Access tokens have no "purpose" claim, so neither can be used as the other.
+	token, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+		"purpose":  purposeOIDC,
+		"provider": name,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(s.cfg.StateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &OIDCLogin{
+		URL:       p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:     token,
+		ExpiresIn: s.cfg.StateTTL,
+	}, nil
+}
+
+// Finish completes a login through the named provider, given the state token returned
+// by Start and the query of the callback, and returns the user it logs in.
+// Refused logins are counted in metrics.Logins as "federation_failed".
+func (s *OIDCService) Finish(ctx context.Context, name, stateToken string, query *dto.OIDCCallbackQuery) (*models.User, error) {
+	user, err := s.finish(ctx, name, stateToken, query)
+	if err != nil {
+		metrics.Logins.WithLabelValues("federation_failed").Inc()
+	}
+	return user, err
+}
+
+// finish implements Finish.
+func (s *OIDCService) finish(ctx context.Context, name, stateToken string, query *dto.OIDCCallbackQuery) (*models.User, error) {
+	p, err := s.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The state must match the one in the cookie, so a callback can't be forged
+	// to log the user in to someone else's account.
+	flow, err := parseOIDCState(stateToken)
+	if err != nil {
+		return nil, err
+	}
+	if flow.provider != name || subtle.ConstantTimeCompare([]byte(query.State), []byte(flow.state)) != 1 {
+		return nil, apperror.BadRequest(apperror.CodeOIDCStateInvalid, "Login state does not match; please start the login again")
+	}
+
+	if query.Error != "" {
+		detail := "The identity provider refused the login: " + query.Error
+		if query.ErrorDescription != "" {
+			detail += " (" + query.ErrorDescription + ")"
+		}
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, detail)
+	}
+	if query.Code == "" {
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "The identity provider returned no authorization code")
+	}
+
+	ctx = oidc.ClientContext(ctx, s.client)
+	token, err := p.oauth2.Exchange(ctx, query.Code, oauth2.VerifierOption(flow.verifier))
+	if err != nil {
+		slog.WarnContext(ctx, "Identity provider rejected an authorization code", "provider", name, "error", err)
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "The identity provider rejected the authorization code")
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "The identity provider returned no ID token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid ID token from an identity provider", "provider", name, "error", err)
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "Invalid ID token")
+	}
+	// The nonce ties the ID token to this login, so a token issued for another one can't be replayed.
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(flow.nonce)) != 1 {
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "Invalid ID token")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, apperror.Unauthorized(apperror.CodeOIDCLoginFailed, "Invalid ID token")
+	}
+	return s.resolveUser(ctx, name, idToken.Subject, claims)
+}
+
+// resolveUser returns the user linked to the subject at a provider, linking or creating one
+// on the first login.
+func (s *OIDCService) resolveUser(ctx context.Context, provider, subject string, claims oidcClaims) (*models.User, error) {
+	now := time.Now()
+	identity, err := s.identities.FindBySubject(ctx, provider, subject)
+	switch {
+	case err == nil && identity.User.ID != 0:
+		if err := s.identities.RecordLogin(ctx, identity.ID, claims.Email, now); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		return &identity.User, nil
+	case err == nil:
+		// The linked account has been deleted since; the identity is linked anew below.
+		if err := s.identities.Delete(ctx, identity.ID); err != nil {
+			return nil, apperror.Internal(err)
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, apperror.Internal(err)
+	}
+
+	// Only an address the provider has verified may be matched with an account,
+	// or anyone could claim somebody else's account at a careless provider.
+	if claims.Email == "" || !claims.emailVerified() {
+		return nil, apperror.Forbidden(apperror.CodeIdentityEmailUnverified, "The identity provider did not supply a verified email address")
+	}
+
+	user, err := s.users.FindByEmail(ctx, claims.Email)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		if !s.cfg.AutoProvision {
+			return nil, apperror.Forbidden(apperror.CodeIdentityNotLinked, "No account uses the email address of this identity")
+		}
+		if user, err = s.provision(ctx, claims, now); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, apperror.Internal(err)
+	case user.EmailVerifiedAt == nil:
+		// Anyone can register with an address they don't own; linking such an account
+		// would let whoever registered it into the account of the address's real owner.
+		return nil, apperror.Forbidden(apperror.CodeEmailNotVerified, "An account with this email address exists but the address is not verified; verify it, or log in with the password, first")
+	}
+
+	identity = &models.UserIdentity{UserID: user.ID, Provider: provider, Subject: subject, Email: claims.Email, LastLoginAt: &now}
+	if err := s.identities.Create(ctx, identity); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+// provision creates the account of a user logging in through a provider for the first time.
+// The account has no password, which no password matches: it logs in through the provider
+// until its owner chooses a password with a reset link.
+func (s *OIDCService) provision(ctx context.Context, claims oidcClaims, now time.Time) (*models.User, error) {
+	// FindByEmail found no account, but a deleted one may still hold the address.
+	taken, err := s.users.ExistsByEmail(ctx, claims.Email, 0)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if taken {
+		return nil, apperror.Conflict(apperror.CodeEmailTaken, "The email address of this identity belongs to a deleted account")
+	}
+
+	username, err := s.freeUsername(ctx, claims.PreferredUsername, strings.SplitN(claims.Email, "@", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+	// Unlike Register, the first account doesn't become an admin: the provider, not
+	// whoever installed the API, decides who gets here (see the "promote-admin" command).
+	user := &models.User{
+		Username:        username,
+		Email:           claims.Email,
+		Role:            models.RoleUser,
+		EmailVerifiedAt: &now, // The provider has verified it
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+// freeUsername returns an unused username made from the first usable candidate,
+// with a number appended if needed.
+func (s *OIDCService) freeUsername(ctx context.Context, candidates ...string) (string, error) {
+	base := "user"
+	for _, candidate := range candidates {
+		if candidate = usernameUnwanted.ReplaceAllString(candidate, ""); len(candidate) >= 3 {
+			base = candidate
+			break
+		}
+	}
+	if len(base) > 40 {
+		base = base[:40] // Room for the number, within the 50 characters allowed
+	}
+
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s%d", base, i)
+		}
+		taken, err := s.users.ExistsByUsername(ctx, username, 0)
+		if err != nil {
+			return "", apperror.Internal(err)
+		}
+		if !taken {
+			return username, nil
+		}
+	}
+	// A hundred namesakes: fall back to a random suffix.
+	random, err := generateOpaqueToken()
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+	return base + hashToken(random)[:8], nil
+}
+
+// provider returns the named provider, discovering it on first use.
+func (s *OIDCService) provider(ctx context.Context, name string) (*oidcProvider, error) {
+	var cfg *config.OIDCProvider
+	for i := range s.cfg.Providers {
+		if s.cfg.Providers[i].Name == name {
+			cfg = &s.cfg.Providers[i]
+		}
+	}
+	if cfg == nil {
+		return nil, apperror.NotFound(apperror.CodeProviderNotFound, "Unknown identity provider")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.providers[name]; ok {
+		return p, nil
+	}
+
+	// The provider keeps this context to fetch new signing keys later on,
+	// so it must not be the request's, which is canceled once the request is served.
+	discovered, err := oidc.NewProvider(oidc.ClientContext(context.Background(), s.client), cfg.Issuer)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to discover an identity provider", "provider", name, "issuer", cfg.Issuer, "error", err)
+		unavailable := apperror.New(http.StatusBadGateway, apperror.CodeProviderUnavailable, "The identity provider is unavailable, please retry later")
+		unavailable.Cause = err
+		return nil, unavailable
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+	p := &oidcProvider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     discovered.Endpoint(),
+			RedirectURL:  strings.TrimSuffix(s.cfg.RedirectBaseURL, "/") + "/auth/oidc/" + name + "/callback",
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	s.providers[name] = p
+	return p, nil
+}
+
+// parseOIDCState checks a state token signed by Start and returns what it holds.
+func parseOIDCState(token string) (*oidcState, error) {
+	if jwtkeys.Keys == nil {
+		return nil, apperror.Internal(errors.New("JWT verification keys not loaded"))
+	}
+	invalid := apperror.BadRequest(apperror.CodeOIDCStateInvalid, "Login state missing or expired; please start the login again")
+	if token == "" {
+		return nil, invalid
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, jwtkeys.Keys.Keyfunc, jwt.WithExpirationRequired()); err != nil {
+		return nil, invalid
+	}
+	flow := &oidcState{}
+	purpose, _ := claims["purpose"].(string)
+	flow.provider, _ = claims["provider"].(string)
+	flow.state, _ = claims["state"].(string)
+	flow.nonce, _ = claims["nonce"].(string)
+	flow.verifier, _ = claims["verifier"].(string)
+	if purpose != purposeOIDC || flow.state == "" || flow.nonce == "" || flow.verifier == "" {
+		return nil, invalid
+	}
+	return flow, nil
+}
+
+// emailVerified reports whether the provider has verified the email address.
+func (c oidcClaims) emailVerified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+)
+
+// OwnerService manages owners.
+type OwnerService struct {
+	owners repository.OwnerRepository
+}
+
+// NewOwnerService creates an OwnerService.
+func NewOwnerService(owners repository.OwnerRepository) *OwnerService {
+	return &OwnerService{owners: owners}
+}
+
+// List returns one page of owners matching the filter.
+func (s *OwnerService) List(ctx context.Context, filter repository.OwnerFilter, req pagination.Request) ([]models.Owner, pagination.Result, error) {
+	owners, result, err := s.owners.List(ctx, filter, req)
+	if err != nil {
+		return nil, pagination.Result{}, internal(err)
+	}
+	return owners, result, nil
+}
+
+// Get returns the owner with the given ID, optionally with the products linked to it.
+func (s *OwnerService) Get(ctx context.Context, id string, withProducts bool) (*models.Owner, error) {
+	return findOwner(ctx, s.owners, id, withProducts)
+}
+
+// Create stores a new owner.
+func (s *OwnerService) Create(ctx context.Context, req *dto.CreateOwnerRequest) (*models.Owner, error) {
+	owner := req.ToModel()
+	if err := s.owners.Create(ctx, owner); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return owner, nil
+}
+
+// Update changes the fields provided in req.
+func (s *OwnerService) Update(ctx context.Context, id string, req *dto.UpdateOwnerRequest) (*models.Owner, error) {
+	owner, err := findOwner(ctx, s.owners, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Apply(owner)
+	if err := s.owners.Update(ctx, owner); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return owner, nil
+}
+
+// Delete soft deletes the owner with the given ID.
+func (s *OwnerService) Delete(ctx context.Context, id string) error {
+	owner, err := findOwner(ctx, s.owners, id, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.owners.Delete(ctx, owner); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+)
+
+// ProductService manages products and their links to owners.
+type ProductService struct {
+	products repository.ProductRepository
+	owners   repository.OwnerRepository
+}
+
+// NewProductService creates a ProductService.
+func NewProductService(products repository.ProductRepository, owners repository.OwnerRepository) *ProductService {
+	return &ProductService{products: products, owners: owners}
+}
+
+// List returns one page of products matching the filter.
+func (s *ProductService) List(ctx context.Context, filter repository.ProductFilter, req pagination.Request) ([]models.Product, pagination.Result, error) {
+	products, result, err := s.products.List(ctx, filter, req)
+	if err != nil {
+		return nil, pagination.Result{}, internal(err)
+	}
+	return products, result, nil
+}
+
+// Get returns the product with the given UUID, optionally with its owners.
+func (s *ProductService) Get(ctx context.Context, publicID string, withOwners bool) (*models.Product, error) {
+	return findProduct(ctx, s.products, publicID, withOwners)
+}
+
+// Create stores a new product.
+func (s *ProductService) Create(ctx context.Context, req *dto.CreateProductRequest) (*models.Product, error) {
+	product := req.ToModel()
+	if err := s.products.Create(ctx, product); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return product, nil
+}
+
+// Update changes the fields provided in req. The ID and UUID never change.
+func (s *ProductService) Update(ctx context.Context, publicID string, req *dto.UpdateProductRequest) (*models.Product, error) {
+	product, err := findProduct(ctx, s.products, publicID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Apply(product)
+	if err := s.products.Update(ctx, product); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return product, nil
+}
+
+// Delete soft deletes the product with the given UUID.
+func (s *ProductService) Delete(ctx context.Context, publicID string) error {
+	product, err := findProduct(ctx, s.products, publicID, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.products.Delete(ctx, product); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// AddOwner links an owner to a product and returns the product with its owners.
+// Linking an owner that is already attached is a no-op.
+func (s *ProductService) AddOwner(ctx context.Context, publicID, ownerID string) (*models.Product, error) {
+	product, err := findProduct(ctx, s.products, publicID, false)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := findOwner(ctx, s.owners, ownerID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.products.AddOwner(ctx, product, owner); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return findProduct(ctx, s.products, publicID, true)
+}
+
+// RemoveOwner unlinks an owner from a product. Only the link is removed; the owner itself is kept.
+func (s *ProductService) RemoveOwner(ctx context.Context, publicID, ownerID string) error {
+	product, err := findProduct(ctx, s.products, publicID, false)
+	if err != nil {
+		return err
+	}
+	owner, err := findOwner(ctx, s.owners, ownerID, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.products.RemoveOwner(ctx, product, owner); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// ReplaceOwners makes ownerIDs the complete set of owners of a product and returns the product
+// with its owners. An empty list removes every owner. If any of the owners does not exist,
+// nothing is changed and a 404 apperror is returned.
+func (s *ProductService) ReplaceOwners(ctx context.Context, publicID string, ownerIDs []uint) (*models.Product, error) {
+	product, err := findProduct(ctx, s.products, publicID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	owners, err := s.owners.FindByIDs(ctx, ownerIDs)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	// Compare against the distinct requested IDs so duplicates in the request don't cause a false 404.
+	requested := make(map[uint]struct{}, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		requested[ownerID] = struct{}{}
+	}
+	if len(owners) != len(requested) {
+		return nil, apperror.NotFound(apperror.CodeOwnerNotFound, "One or more owners not found")
+	}
+
+	if err := s.products.ReplaceOwners(ctx, product, owners); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return findProduct(ctx, s.products, publicID, true)
+}
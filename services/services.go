@@ -0,0 +1,93 @@
+// Package services holds the business rules of the API: who may change what,
+// which values must be unique, how sessions and tokens are issued.
+// Services work on the repository interfaces, so they never touch the database
+// directly, and report failures as *apperror.Error values that the handlers
+// can return unchanged.
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+
+	"github.com/google/uuid"
+)
+
+// internal passes API errors (e.g. a bad cursor reported by the pagination package)
+// through unchanged and wraps everything else as a 500.
+func internal(err error) error {
+	var apiErr *apperror.Error
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	return apperror.Internal(err)
+}
+
+// findProduct loads a product by its public UUID, optionally with its owners.
+// A malformed UUID is reported as a 400 apperror, a missing product as a 404.
+func findProduct(ctx context.Context, products repository.ProductRepository, publicID string, withOwners bool) (*models.Product, error) {
+	parsed, err := uuid.Parse(publicID)
+	if err != nil {
+		return nil, apperror.BadRequest(apperror.CodeInvalidID, "Product ID must be a UUID")
+	}
+
+	product, err := products.FindByUUID(ctx, parsed.String(), withOwners)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperror.NotFound(apperror.CodeProductNotFound, "Product not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return product, nil
+}
+
+// findOwner loads an owner by the ID given in the URL, optionally with its products.
+// A malformed ID is reported as a 400 apperror, a missing owner as a 404.
+func findOwner(ctx context.Context, owners repository.OwnerRepository, id string, withProducts bool) (*models.Owner, error) {
+	numericID, err := parseID(id, "Owner")
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := owners.FindByID(ctx, numericID, withProducts)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperror.NotFound(apperror.CodeOwnerNotFound, "Owner not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return owner, nil
+}
+
+// findUser loads a user by the ID given in the URL.
+// A malformed ID is reported as a 400 apperror, a missing user as a 404.
+func findUser(ctx context.Context, users repository.UserRepository, id string) (*models.User, error) {
+	numericID, err := parseID(id, "User")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := users.FindByID(ctx, numericID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperror.NotFound(apperror.CodeUserNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+// parseID converts a numeric ID from the URL, reporting anything but a positive integer
+// as a 400 apperror. The ID must be parsed rather than passed to GORM's First as a string:
+// GORM treats a non-numeric string condition as raw SQL.
+func parseID(id, resource string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return 0, apperror.BadRequest(apperror.CodeInvalidID, resource+" ID must be a positive integer")
+	}
+	return uint(n), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+
+	"github.com/golang-jwt/jwt/v5" // JWT library for token creation
+	"golang.org/x/crypto/bcrypt"   // For secure password hashing
+)
+
+// Token lifetimes. Access tokens are short-lived; clients use the refresh token to get new ones.
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenPair is the set of tokens handed to a client after login, registration or refresh.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// HashPassword hashes a given plaintext password using bcrypt.
+// Bcrypt is a strong, adaptive hashing algorithm, making it suitable for password storage.
+func HashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(bytes), err
+}
+
+// CheckPasswordHash compares a plaintext password with its bcrypt hash.
+// It returns true if they match, false otherwise.
+func CheckPasswordHash(password, hash string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return err == nil
+}
+
+// GenerateJWTToken creates a new short-lived JSON Web Token for a given user.
+// The token includes the user ID, the user's role and permissions, the ID of the
+// session it belongs to and an expiration time.
+func GenerateJWTToken(user *models.User, sessionID uint) (string, error) {
+	// The signing keys are loaded once at startup (see jwtkeys.LoadKeys in main.go).
+	if jwtkeys.Keys == nil {
+		// If no keys are loaded, it's a critical server configuration error.
+		return "", apperror.Internal(errors.New("JWT signing keys not loaded"))
+	}
+
+	// Define the token's claims (payload).
+	// "user_id": The ID of the user for whom the token is generated.
+	// "role": The user's role, e.g. "user" or "admin".
+	// "permissions": The permissions granted by that role, checked by middlewares.RequirePermission.
+	// "sid": The session the token belongs to, so it can be revoked on logout.
+	// "exp": The expiration time of the token (AccessTokenTTL from now, in Unix timestamp).
+	claims := jwt.MapClaims{
+		"user_id":     user.ID,
+		"role":        user.Role,
+		"permissions": models.RolePermissions[user.Role],
+		"sid":         sessionID,
+		"exp":         time.Now().Add(AccessTokenTTL).Unix(),
+	}
+
+	// Sign the token with the active key (HS256, RS256 or EdDSA); its ID goes into the 'kid' header.
+	tokenString, err := jwtkeys.Keys.Sign(claims)
+	if err != nil {
+		// If signing fails, return an internal server error.
+		return "", apperror.Internal(err)
+	}
+
+	return tokenString, nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of an opaque token.
+// Refresh tokens are high-entropy random values, so a fast hash is sufficient (unlike passwords).
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateOpaqueToken returns a random, URL-safe token with 256 bits of entropy.
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// issueTokenPair stores a new refresh token for the session and signs a matching access token.
+func issueTokenPair(ctx context.Context, sessions repository.SessionRepository, user *models.User, sessionID uint) (*TokenPair, error) {
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	record := models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}
+	if err := sessions.CreateRefreshToken(ctx, &record); err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	accessToken, err := GenerateJWTToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/config"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/jwtkeys"    // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// purposeTwoFactor is the "purpose" claim of the challenge tokens returned by a login
+// that still needs a second factor.
+const purposeTwoFactor = "two_factor"
+
+// totpOptions are the TOTP parameters every common authenticator app understands:
+// 6 digits, a new code every 30 seconds, HMAC-SHA1. The code of the previous and the next
+// step are accepted too, for clocks that are a little off.
+var totpOptions = totp.ValidateOpts{Period: 30, Skew: 1, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// recoveryCodeEncoding spells recovery codes in lower case letters and digits.
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// TwoFactorEnrollment is what a user needs to add their account to an authenticator app.
+type TwoFactorEnrollment struct {
+	Secret string // Base32, for typing into the app by hand
+	URI    string // The otpauth:// URI encoded in QRCode
+	QRCode []byte // PNG image
+}
+
+// TwoFactorChallenge is returned by a login with the right password when the account
+// has two-factor authentication enabled: the token is exchanged for a session, together
+// with a TOTP or recovery code, at /auth/2fa/verify.
+type TwoFactorChallenge struct {
+	Token     string
+	ExpiresIn time.Duration
+}
+
+// TwoFactorService manages the TOTP authenticator and the recovery codes of accounts,
+// and the challenge between the password and the code when logging in.
+//
+// Enrolling stores a new secret; two-factor authentication is only enabled once a code
+// from the app confirms the secret was added, which also generates the recovery codes.
+// Each TOTP code and each recovery code works once.
+type TwoFactorService struct {
+	users repository.UserRepository
+	codes repository.RecoveryCodeRepository
+	cfg   config.TwoFactor
+}
+
+// NewTwoFactorService creates a TwoFactorService.
+func NewTwoFactorService(users repository.UserRepository, codes repository.RecoveryCodeRepository, cfg config.TwoFactor) *TwoFactorService {
+	return &TwoFactorService{users: users, codes: codes, cfg: cfg}
+}
+
+// Status reports whether two-factor authentication is enabled for a user,
+// and how many of their recovery codes are left.
+func (s *TwoFactorService) Status(ctx context.Context, userID uint) (bool, int64, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return false, 0, err
+	}
+	if !user.TwoFactorEnabled() {
+		return false, 0, nil
+	}
+	left, err := s.codes.CountUnused(ctx, userID)
+	if err != nil {
+		return false, 0, apperror.Internal(err)
+	}
+	return true, left, nil
+}
+
+// Enroll generates a new TOTP secret for a user who hasn't enabled two-factor
+// authentication yet, replacing any unconfirmed one.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uint) (*TwoFactorEnrollment, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperror.Conflict(apperror.CodeTwoFactorEnabled, "Two-factor authentication is already enabled; disable it first to enroll a new authenticator")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.cfg.Issuer,
+		AccountName: user.Username,
+		Period:      totpOptions.Period,
+		Digits:      totpOptions.Digits,
+		Algorithm:   totpOptions.Algorithm,
+	})
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, image); err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	user.TOTPSecret = key.Secret()
+	user.TOTPLastStep = 0
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &TwoFactorEnrollment{Secret: key.Secret(), URI: key.URL(), QRCode: qrCode.Bytes()}, nil
+}
+
+// Confirm enables two-factor authentication with the secret of the last enrollment, given
+// a code from the authenticator app, and returns the new recovery codes. They are only
+// ever shown here: just their hashes are stored.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperror.Conflict(apperror.CodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, apperror.Conflict(apperror.CodeTwoFactorNotEnrolled, "No authenticator enrolled; enroll one first")
+	}
+
+	if ok, err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, apperror.Forbidden(apperror.CodeTwoFactorCodeInvalid, "Invalid authentication code")
+	}
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return s.newRecoveryCodes(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, given a code from their
+// authenticator app, and returns the new ones.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.enabledUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Only a TOTP code will do: a recovery code suggests the app is lost, and that calls for
+	// disabling two-factor authentication and enrolling a new app instead.
+	if ok, err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, apperror.Forbidden(apperror.CodeTwoFactorCodeInvalid, "Invalid authentication code")
+	}
+	return s.newRecoveryCodes(ctx, user.ID)
+}
+
+// Disable turns two-factor authentication off, given a TOTP or recovery code,
+// and deletes the secret and the recovery codes.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.enabledUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, err := s.Authenticate(ctx, user, code); err != nil {
+		return err
+	} else if !ok {
+		return apperror.Forbidden(apperror.CodeTwoFactorCodeInvalid, "Invalid authentication or recovery code")
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := s.users.Update(ctx, user); err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.codes.Replace(ctx, user.ID, nil); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// Challenge issues the token a login returns instead of a session when user has
+// two-factor authentication enabled. It proves the password was right, for ChallengeTTL.
+func (s *TwoFactorService) Challenge(user *models.User) (*TwoFactorChallenge, error) {
+	if jwtkeys.Keys == nil {
+		return nil, apperror.Internal(errors.New("JWT signing keys not loaded"))
+	}
+	// Access tokens have no "purpose" claim, and challenge tokens no "sid" claim,
+	// so neither can be used as the other.
+	token, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+		"purpose": purposeTwoFactor,
+		"user_id": user.ID,
+		"exp":     time.Now().Add(s.cfg.ChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &TwoFactorChallenge{Token: token, ExpiresIn: s.cfg.ChallengeTTL}, nil
+}
+
+// ChallengeUser checks a challenge token and returns its account.
+func (s *TwoFactorService) ChallengeUser(ctx context.Context, token string) (*models.User, error) {
+	if jwtkeys.Keys == nil {
+		return nil, apperror.Internal(errors.New("JWT verification keys not loaded"))
+	}
+	invalid := apperror.Unauthorized(apperror.CodeTwoFactorChallengeInvalid, "Invalid challenge token; please log in again")
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, jwtkeys.Keys.Keyfunc, jwt.WithExpirationRequired())
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, apperror.Unauthorized(apperror.CodeTwoFactorChallengeExpired, "Challenge token has expired; please log in again")
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	purpose, _ := claims["purpose"].(string)
+	userID, _ := claims["user_id"].(float64)
+	if purpose != purposeTwoFactor {
+		return nil, invalid
+	}
+
+	user, err := s.users.FindByID(ctx, uint(userID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, invalid // The account has been deleted since
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, invalid // Disabled since; the password alone now logs in
+	}
+	return user, nil
+}
+
+// Authenticate checks a second factor for user: a code from their authenticator app,
+// or one of their recovery codes, which is used up.
+func (s *TwoFactorService) Authenticate(ctx context.Context, user *models.User, code string) (bool, error) {
+	code = normalizeCode(code)
+	if len(code) == int(totpOptions.Digits) && strings.Trim(code, "0123456789") == "" {
+		return s.checkTOTP(ctx, user, code)
+	}
+	used, err := s.codes.Use(ctx, user.ID, hashToken(code))
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+	return used, nil
+}
+
+// checkTOTP checks a code from user's authenticator app and records its time step,
+// so that the same code can't be used again.
+func (s *TwoFactorService) checkTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	code = normalizeCode(code)
+	period := int64(totpOptions.Period)
+	current := time.Now().Unix() / period
+	for offset := -int64(totpOptions.Skew); offset <= int64(totpOptions.Skew); offset++ {
+		step := current + offset
+		expected, err := totp.GenerateCodeCustom(user.TOTPSecret, time.Unix(step*period, 0), totpOptions)
+		if err != nil {
+			return false, apperror.Internal(err)
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		fresh, err := s.users.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return false, apperror.Internal(err)
+		}
+		if fresh {
+			user.TOTPLastStep = step // So that a later Update doesn't write the old step back
+		}
+		return fresh, nil
+	}
+	return false, nil
+}
+
+// newRecoveryCodes replaces the recovery codes of a user with new ones and returns them,
+// e.g. "k3d9x-q2mfa": 50 random bits each, plenty for a code that works once.
+func (s *TwoFactorService) newRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, s.cfg.RecoveryCodes)
+	records := make([]models.RecoveryCode, s.cfg.RecoveryCodes)
+	for i := range codes {
+		random := make([]byte, 7)
+		if _, err := rand.Read(random); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(random)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		records[i] = models.RecoveryCode{CodeHash: hashToken(code)}
+	}
+	if err := s.codes.Replace(ctx, userID, records); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return codes, nil
+}
+
+// findUser loads the account of the authenticated user.
+func (s *TwoFactorService) findUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperror.NotFound(apperror.CodeUserNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+// enabledUser loads the account of the authenticated user, which must have two-factor authentication enabled.
+func (s *TwoFactorService) enabledUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, apperror.Conflict(apperror.CodeTwoFactorNotEnabled, "Two-factor authentication is not enabled")
+	}
+	return user, nil
+}
+
+// normalizeCode drops the spaces and dashes people type in codes, and ignores case,
+// so "123 456" and "K3D9X-Q2MFA" are read as "123456" and "k3d9xq2mfa".
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code))
+}
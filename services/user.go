@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anpsniper/test3-bayu-be/apperror"   // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/dto"        // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/models"     // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/pagination" // Adjust import path to your module name
+	"github.com/anpsniper/test3-bayu-be/repository" // Adjust import path to your module name
+)
+
+// UserService manages user accounts.
+type UserService struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	apiKeys  repository.APIKeyRepository
+}
+
+// NewUserService creates a UserService.
+func NewUserService(users repository.UserRepository, sessions repository.SessionRepository, apiKeys repository.APIKeyRepository) *UserService {
+	return &UserService{users: users, sessions: sessions, apiKeys: apiKeys}
+}
+
+// authorize checks whether the authenticated user (actorID) may manage the account
+// targetID. Users may only manage their own account unless their role grants the
+// PermUsersManage permission. It returns the authenticated user so callers can
+// apply further role-based rules.
+func (s *UserService) authorize(ctx context.Context, actorID, targetID uint) (*models.User, error) {
+	actor, err := s.users.FindByID(ctx, actorID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The account behind the token no longer exists (e.g. it was deleted).
+		return nil, apperror.Unauthorized(apperror.CodeUnauthorized, "Authenticated user not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	if actor.ID != targetID && !models.RoleHasPermission(actor.Role, models.PermUsersManage) {
+		return nil, apperror.Forbidden(apperror.CodePermissionDenied, "You are not allowed to manage this user")
+	}
+	return actor, nil
+}
+
+// List returns one page of users matching the filter.
+func (s *UserService) List(ctx context.Context, filter repository.UserFilter, req pagination.Request) ([]models.User, pagination.Result, error) {
+	users, result, err := s.users.List(ctx, filter, req)
+	if err != nil {
+		return nil, pagination.Result{}, internal(err)
+	}
+	return users, result, nil
+}
+
+// Get returns the user with the given ID.
+func (s *UserService) Get(ctx context.Context, id string) (*models.User, error) {
+	return findUser(ctx, s.users, id)
+}
+
+// Update changes the username, email and/or password of account id on behalf of actorID.
+// Only the fields provided in req are changed. Users without the PermUsersManage
+// permission must also supply their current password to change it.
+func (s *UserService) Update(ctx context.Context, actorID uint, id string, req *dto.UpdateUserRequest) (*models.User, error) {
+	user, err := findUser(ctx, s.users, id)
+	if err != nil {
+		return nil, err
+	}
+	actor, err := s.authorize(ctx, actorID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		// Make sure no other account already uses the new username.
+		taken, err := s.users.ExistsByUsername(ctx, *req.Username, user.ID)
+		if err != nil {
+			return nil, apperror.Internal(err)
+		}
+		if taken {
+			return nil, apperror.Conflict(apperror.CodeUsernameTaken, "Username already exists")
+		}
+		user.Username = *req.Username
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		// Make sure no other account already uses the new email.
+		taken, err := s.users.ExistsByEmail(ctx, *req.Email, user.ID)
+		if err != nil {
+			return nil, apperror.Internal(err)
+		}
+		if taken {
+			return nil, apperror.Conflict(apperror.CodeEmailTaken, "Email already exists")
+		}
+		user.Email = *req.Email
+		user.EmailVerifiedAt = nil // The new address has yet to be verified
+	}
+
+	if req.Password != nil {
+		// Admins may reset other users' passwords. Changing your own, admin or not, takes the
+		// current one, so a stolen admin token can't be turned into the admin's password.
+		resetsOther := models.RoleHasPermission(actor.Role, models.PermUsersManage) && actor.ID != user.ID
+		if !resetsOther && !CheckPasswordHash(req.CurrentPassword, user.Password) {
+			return nil, apperror.Forbidden(apperror.CodeWrongPassword, "Current password is incorrect")
+		}
+
+		hashedPassword, err := HashPassword(*req.Password)
+		if err != nil {
+			return nil, apperror.Internal(err)
+		}
+		user.Password = hashedPassword
+	}
+
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if req.Password != nil {
+		// Whoever knew the old password may be logged in: log every session out,
+		// the one making the change included, as a password reset does.
+		if err := s.sessions.RevokeUserSessions(ctx, user.ID); err != nil {
+			return nil, apperror.Internal(err)
+		}
+	}
+	return user, nil
+}
+
+// UpdateRole changes the role of account id. The new role takes effect when
+// the user's access token is next refreshed.
+func (s *UserService) UpdateRole(ctx context.Context, id, role string) (*models.User, error) {
+	user, err := findUser(ctx, s.users, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.ValidRole(role) {
+		return nil, apperror.BadRequest(apperror.CodeBadRequest, "Unknown role: "+role)
+	}
+
+	user.Role = role
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+// Delete soft deletes account id on behalf of actorID, after revoking its sessions
+// and API keys.
+func (s *UserService) Delete(ctx context.Context, actorID uint, id string) error {
+	user, err := findUser(ctx, s.users, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authorize(ctx, actorID, user.ID); err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeUserSessions(ctx, user.ID); err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.apiKeys.RevokeUserKeys(ctx, user.ID); err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.users.Delete(ctx, user); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
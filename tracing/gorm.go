@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey is where the before callbacks store the span of the running statement.
+const spanKey = "tracing:span"
+
+// GormPlugin is a GORM plugin recording a client span for every statement, as a child of
+// the span found in the statement's context. Repositories must therefore run their queries
+// with db.WithContext(ctx), ctx being the request's user context (see Middleware).
+//
+// The span carries the SQL with its placeholders, never the bound values, which may be
+// passwords or tokens.
+//
+// Register it once per connection pool with db.Use(tracing.NewGormPlugin()).
+type GormPlugin struct {
+	tracer trace.Tracer
+}
+
+// NewGormPlugin creates a GormPlugin using the global TracerProvider.
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{tracer: otel.Tracer(instrumentationName)}
+}
+
+// Name implements gorm.Plugin.
+func (p *GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin. It wraps every GORM operation with callbacks
+// starting and ending a span.
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("tracing:before_create", p.before("create")),
+		callbacks.Create().After("*").Register("tracing:after_create", after("create")),
+		callbacks.Query().Before("*").Register("tracing:before_query", p.before("query")),
+		callbacks.Query().After("*").Register("tracing:after_query", after("query")),
+		callbacks.Update().Before("*").Register("tracing:before_update", p.before("update")),
+		callbacks.Update().After("*").Register("tracing:after_update", after("update")),
+		callbacks.Delete().Before("*").Register("tracing:before_delete", p.before("delete")),
+		callbacks.Delete().After("*").Register("tracing:after_delete", after("delete")),
+		callbacks.Row().Before("*").Register("tracing:before_row", p.before("row")),
+		callbacks.Row().After("*").Register("tracing:after_row", after("row")),
+		callbacks.Raw().Before("*").Register("tracing:before_raw", p.before("raw")),
+		callbacks.Raw().After("*").Register("tracing:after_raw", after("raw")),
+	)
+}
+
+// before starts the statement's span, named after the operation (e.g. "db.query").
+// after appends the table, which is only known once GORM has parsed the model.
+func (p *GormPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := p.tracer.Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(dbSystem(db.Dialector.Name()), semconv.DBOperationName(operation)),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// after ends the statement's span, with the table, SQL, affected rows and error.
+func after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+		defer span.End()
+
+		if table := db.Statement.Table; table != "" { // Empty for db.Exec with raw SQL
+			span.SetName("db." + operation + " " + table)
+			span.SetAttributes(semconv.DBCollectionName(table))
+		}
+		span.SetAttributes(
+			semconv.DBQueryText(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		// A lookup that finds nothing is an ordinary outcome (a 404), not a failed statement.
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
+
+// dbSystem maps a GORM dialector name to the db.system attribute.
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	}
+	return semconv.DBSystemOtherSQL
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"github.com/anpsniper/test3-bayu-be/apperror"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, as a child of the caller's span when
+// the request carries a traceparent header. The span's context becomes the request's user
+// context (c.UserContext()), which the handlers pass down to the services and repositories,
+// so the GORM statements of the request show up as its children.
+//
+// Once the request has been handled, the span is named after the route pattern
+// (e.g. "GET /products/:uuid") and gets the status code and, for authenticated requests,
+// the user_id set by JWTAuthRequired.
+//
+// Errors are returned unchanged, for the app's ErrorHandler to turn into the response;
+// register Middleware after metrics.Middleware, which is where that happens.
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer(instrumentationName)
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+		// Spans are exported in the background, after fasthttp has reused the buffers
+		// behind c.Method(), c.Path()..., so every value taken from the request is copied.
+		method := utils.CopyString(c.Method())
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+				semconv.URLScheme(utils.CopyString(c.Protocol())),
+				semconv.ClientAddress(utils.CopyString(c.IP())),
+				semconv.UserAgentOriginal(utils.CopyString(c.Get(fiber.HeaderUserAgent))),
+				attribute.String("request_id", apperror.RequestID(c)),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = apperror.From(err).Status // What the ErrorHandler will send
+		}
+		// When no route matches, the span keeps the bare method as its name.
+		if !apperror.RouteNotMatched(err) {
+			route := c.Route().Path
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if userID, ok := c.Locals("userID").(uint); ok {
+			span.SetAttributes(attribute.Int64("user_id", int64(userID)))
+		}
+		// Client errors (4xx) are the client's problem, not a failure of the server span.
+		if status >= fiber.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, "")
+		}
+		return err
+	}
+}
+
+// headerCarrier exposes the request headers to the OpenTelemetry propagators.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+// Get implements propagation.TextMapCarrier. The value is copied because the
+// tracestate and baggage keep parts of it for as long as the span lives.
+func (h headerCarrier) Get(key string) string {
+	return utils.CopyString(h.c.Get(key))
+}
+
+// Set implements propagation.TextMapCarrier.
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+// Keys implements propagation.TextMapCarrier.
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,77 @@
+// Package tracing records OpenTelemetry spans for every HTTP request (see Middleware)
+// and every GORM statement (see GormPlugin), so the time spent by a slow request can be
+// split between the handlers and the database.
+//
+// The trace context is propagated with the W3C traceparent and tracestate headers, so
+// the request span joins a trace started by the caller. Spans are sent to the exporter
+// chosen in the configuration by the TracerProvider installed with Setup; until then,
+// the global OpenTelemetry provider is a no-op and so is everything in this package.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anpsniper/test3-bayu-be/buildinfo"
+	"github.com/anpsniper/test3-bayu-be/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// instrumentationName identifies the spans created by this package.
+const instrumentationName = "github.com/anpsniper/test3-bayu-be/tracing"
+
+// Setup installs the W3C trace context propagator and, unless the exporter is "none",
+// a global TracerProvider sending spans to the configured exporter.
+//
+// The returned function flushes the spans still buffered and stops the exporter;
+// call it once the server has stopped.
+func Setup(ctx context.Context, cfg config.Tracing) (shutdown func(context.Context) error, err error) {
+	// Extract incoming traceparent/tracestate (and baggage) headers even when tracing is
+	// disabled, so the IDs of the caller's trace still reach the handlers' context.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		// OTLP over HTTP/protobuf, e.g. to an OpenTelemetry Collector or Jaeger on port 4318.
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating the %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(buildinfo.Get().Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("describing the service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Keep the caller's sampling decision when there is one, so traces aren't cut in half.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
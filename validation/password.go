@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// PasswordPolicy describes the rules new passwords must follow.
+type PasswordPolicy struct {
+	MinLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+}
+
+// MaxPasswordBytes is the longest password accepted, in bytes: bcrypt refuses to hash
+// anything longer.
+const MaxPasswordBytes = 72
+
+// DefaultPasswordPolicy is used for any setting that is not configured.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	RequireLower: true,
+	RequireDigit: true,
+}
+
+// passwordPolicy is the policy checked by the `password` validation tag.
+var passwordPolicy = DefaultPasswordPolicy
+
+// SetPasswordPolicy replaces the policy checked by the `password` validation tag.
+// It is called once at startup with the configured policy (see config.Password),
+// before any request is served.
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// CurrentPasswordPolicy returns the policy set by SetPasswordPolicy (DefaultPasswordPolicy if none was).
+func CurrentPasswordPolicy() PasswordPolicy {
+	return passwordPolicy
+}
+
+// Check returns a list of the policy rules the password violates (empty if it is acceptable).
+func (p PasswordPolicy) Check(password string) []string {
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+
+	problems := []string{}
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, "must be at least "+strconv.Itoa(p.MinLength)+" characters long")
+	}
+	if len(password) > MaxPasswordBytes {
+		problems = append(problems, "must be at most "+strconv.Itoa(MaxPasswordBytes)+" bytes long")
+	}
+	if p.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an upper-case letter")
+	}
+	if p.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lower-case letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		problems = append(problems, "must contain a symbol or punctuation character")
+	}
+	return problems
+}
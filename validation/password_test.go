@@ -0,0 +1,38 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSpecial: true}
+
+	for _, tc := range []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		want     []string
+	}{
+		{"acceptable", DefaultPasswordPolicy, "password1", []string{}},
+		{"too short", DefaultPasswordPolicy, "pass1", []string{"must be at least 8 characters long"}},
+		{"length in characters", DefaultPasswordPolicy, "pässwörd1", []string{}},
+		{"72 bytes", DefaultPasswordPolicy, strings.Repeat("pass1234", 9), []string{}},
+		{"73 bytes", DefaultPasswordPolicy, strings.Repeat("pass1234", 9) + "x", []string{"must be at most 72 bytes long"}},
+		{"72 characters over 72 bytes", DefaultPasswordPolicy, "1" + strings.Repeat("é", 36), []string{"must be at most 72 bytes long"}},
+		{"every rule", strict, "short", []string{
+			"must be at least 10 characters long",
+			"must contain an upper-case letter",
+			"must contain a digit",
+			"must contain a symbol or punctuation character",
+		}},
+		{"every rule met", strict, "Password-123", []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.Check(tc.password); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Check(%q) = %q, want %q", tc.password, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package validation checks request DTOs against the `validate` struct tags
+// declared on them and turns failures into a list of field-level errors.
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field, e.g. "email"
+	Rule    string `json:"rule"`    // The failed rule, e.g. "required" or "email"
+	Message string `json:"message"` // Human-readable explanation
+}
+
+// Errors is returned by Validate when one or more fields are invalid.
+type Errors struct {
+	Fields []FieldError
+}
+
+func (e *Errors) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+var (
+	validate     *validator.Validate
+	validateOnce sync.Once
+)
+
+// instance lazily builds the shared validator with the custom rules registered.
+func instance() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+
+		// Report fields by their JSON name, which is what API clients know them by.
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" || name == "" {
+				return field.Name
+			}
+			return name
+		})
+
+		// `validate:"password"` checks the configured password policy (see SetPasswordPolicy).
+		validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+			return len(CurrentPasswordPolicy().Check(fl.Field().String())) == 0
+		})
+		// `validate:"notblank"` rejects strings that are empty after trimming whitespace.
+		validate.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+			return strings.TrimSpace(fl.Field().String()) != ""
+		})
+	})
+	return validate
+}
+
+// Validate checks s against its `validate` struct tags.
+// It returns nil if s is valid and *Errors otherwise.
+func Validate(s interface{}) error {
+	err := instance().Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	result := &Errors{}
+	for _, fe := range validationErrors {
+		result.Fields = append(result.Fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return result
+}
+
+// message turns a validator error into an explanation for API clients.
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return "must be at least " + fe.Param() + " characters long"
+	case "max":
+		return "must be at most " + fe.Param() + " characters long"
+	case "alphanum":
+		return "may only contain letters and digits"
+	case "password":
+		return strings.Join(CurrentPasswordPolicy().Check(fe.Value().(string)), "; ")
+	default:
+		return "failed the '" + fe.Tag() + "' rule"
+	}
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type request struct {
+	Username string  `json:"username" validate:"required,min=3,max=50,alphanum"`
+	Email    *string `json:"email" validate:"omitnil,email"`
+	Password string  `json:"password" validate:"password"`
+	Name     string  `json:"name" validate:"notblank"`
+	Internal string  `json:"-" validate:"max=3"`
+}
+
+func valid() request {
+	return request{Username: "alice", Password: "password1", Name: "Alice", Internal: "abc"}
+}
+
+func TestValidate(t *testing.T) {
+	email := "not-an-email"
+
+	for _, tc := range []struct {
+		name   string
+		change func(r *request)
+		want   []FieldError
+	}{
+		{"valid", func(r *request) {}, nil},
+		{"required", func(r *request) { r.Username = "" }, []FieldError{
+			{"username", "required", "is required"},
+		}},
+		{"only the first failed rule of a field", func(r *request) { r.Username = "a!" }, []FieldError{
+			{"username", "min", "must be at least 3 characters long"},
+		}},
+		{"max", func(r *request) { r.Username = strings.Repeat("a", 51) }, []FieldError{
+			{"username", "max", "must be at most 50 characters long"},
+		}},
+		{"alphanum", func(r *request) { r.Username = "alice!" }, []FieldError{
+			{"username", "alphanum", "may only contain letters and digits"},
+		}},
+		{"email", func(r *request) { r.Email = &email }, []FieldError{
+			{"email", "email", "must be a valid email address"},
+		}},
+		{"notblank", func(r *request) { r.Name = " \t\n" }, []FieldError{
+			{"name", "notblank", "is required"},
+		}},
+		{"password lists every broken rule", func(r *request) { r.Password = "short" }, []FieldError{
+			{"password", "password", "must be at least 8 characters long; must contain a digit"},
+		}},
+		{"password over 72 bytes", func(r *request) { r.Password = strings.Repeat("pass1", 15) }, []FieldError{
+			{"password", "password", "must be at most 72 bytes long"},
+		}},
+		{"field without a JSON name", func(r *request) { r.Internal = "abcd" }, []FieldError{
+			{"Internal", "max", "must be at most 3 characters long"},
+		}},
+		{"several fields", func(r *request) { r.Username, r.Name = "", "" }, []FieldError{
+			{"username", "required", "is required"},
+			{"name", "notblank", "is required"},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := valid()
+			tc.change(&r)
+			err := Validate(r)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var fields *Errors
+			if !errors.As(err, &fields) {
+				t.Fatalf("Validate() = %v, want *Errors", err)
+			}
+			if !reflect.DeepEqual(fields.Fields, tc.want) {
+				t.Errorf("fields = %+v, want %+v", fields.Fields, tc.want)
+			}
+		})
+	}
+}
+
+func TestPasswordTagFollowsPolicy(t *testing.T) {
+	defer SetPasswordPolicy(CurrentPasswordPolicy())
+	SetPasswordPolicy(PasswordPolicy{MinLength: 4, RequireUpper: true})
+
+	r := valid()
+	r.Password = "Pass"
+	if err := Validate(r); err != nil {
+		t.Errorf("Validate() = %v with a password meeting the policy", err)
+	}
+	r.Password = "password1"
+	var fields *Errors
+	if err := Validate(r); !errors.As(err, &fields) || fields.Fields[0].Message != "must contain an upper-case letter" {
+		t.Errorf("Validate() = %v, want the missing upper-case letter reported", err)
+	}
+}